@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,10 +13,16 @@ import (
 	"time"
 
 	"github.com/Svirex/microurl/internal/adapters/api"
+	"github.com/Svirex/microurl/internal/adapters/auth"
+	"github.com/Svirex/microurl/internal/adapters/domainpolicy"
 	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/grpcapi"
+	"github.com/Svirex/microurl/internal/adapters/metrics"
 	"github.com/Svirex/microurl/internal/adapters/repository"
-	repo "github.com/Svirex/microurl/internal/adapters/repository/postgres"
+	"github.com/Svirex/microurl/internal/adapters/tlscert"
+	"github.com/Svirex/microurl/internal/adapters/tracing"
 	"github.com/Svirex/microurl/internal/config"
+	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/Svirex/microurl/internal/core/ports"
 	"github.com/Svirex/microurl/internal/core/service"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -26,12 +33,24 @@ import (
 
 const shortURLLength uint = 8
 
+// deleterMaxBacklog - при большей очереди удаления сервис считается неготовым.
+const deleterMaxBacklog = 10000
+
+// domainPolicyInterval - период проверки изменения файла правил доступа к доменам.
+const domainPolicyInterval = 5 * time.Second
+
 func Example() {
 	cfg, err := config.Parse()
 	if err != nil {
 		log.Fatal(err)
 	}
-	config := zap.Config{
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	loggerConfig := zap.Config{
 		Level:            zap.NewAtomicLevelAt(zapcore.DebugLevel),
 		Development:      true,
 		Encoding:         "json",
@@ -40,22 +59,36 @@ func Example() {
 		ErrorOutputPaths: []string{"stderr"},
 	}
 
-	l, err := config.Build()
+	l, err := loggerConfig.Build()
 	if err != nil {
 		log.Panicln("couldn't init zap logger")
 	}
 	logger := ports.Logger(l.Sugar())
 	defer logger.Sync()
 
-	generator := generator.NewStringGenerator(time.Now().UnixNano())
-	logger.Info("Created generator...")
-
 	serverCtx, serverCancel := context.WithCancel(context.Background())
 
+	shutdownTracing, err := tracing.Setup(serverCtx, cfg.OTLPEndpoint, "microurl", "N/A")
+	if err != nil {
+		logger.Panicf("setup tracing: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Errorln("shutdown tracing: ", err)
+		}
+	}()
+
 	var db *pgxpool.Pool
 	if cfg.PostgresDSN != "" {
 		logger.Info("Try create DB connection...")
-		db, err = pgxpool.New(serverCtx, cfg.PostgresDSN)
+		poolConfig, err := pgxpool.ParseConfig(cfg.PostgresDSN)
+		if err != nil {
+			logger.Panicln("DB config error", "err", err)
+		}
+		poolConfig.ConnConfig.Tracer = tracing.NewPgxTracer()
+		db, err = pgxpool.NewWithConfig(serverCtx, poolConfig)
 		if err != nil {
 			logger.Panicln("DB connection error", "err", err)
 		}
@@ -69,41 +102,223 @@ func Example() {
 		defer closeDB()
 	}
 
-	repository, err := repository.NewRepository(serverCtx, cfg, db, logger)
+	shortenerRepo, err := repository.NewRepository(serverCtx, cfg, db, logger)
 	if err != nil {
 		logger.Panicf("create repository err: %w\n", err)
 	}
-	defer repository.Shutdown()
-	logger.Infoln("Created repository...", "type=", fmt.Sprintf("%T", repository))
+	defer shortenerRepo.Shutdown()
+	logger.Infoln("Created repository...", "type=", fmt.Sprintf("%T", shortenerRepo))
+
+	var shortIDGenerator ports.StringGenerator = generator.NewStringGenerator()
+	if cfg.ShortIDGenerator == config.GeneratorCounter {
+		counter, err := repository.NewCounter(cfg, db)
+		if err != nil {
+			logger.Panicf("create counter: %v", err)
+		}
+		key := cfg.ShortIDKey
+		if key == "" {
+			key = cfg.SecretKey
+		}
+		shortIDGenerator = generator.NewCounterGenerator(counter, key)
+	}
+	logger.Infoln("Created generator...", "type=", cfg.ShortIDGenerator)
 
-	shortenerService := service.NewShortenerService(generator, repository, shortURLLength, cfg.BaseURL)
+	if cfg.KeyPoolSize > 0 {
+		keyPoolRepo, err := repository.NewKeyPoolRepository(cfg, db, shortenerRepo)
+		if err != nil {
+			logger.Panicf("create key pool repository: %v", err)
+		}
+		keyPool := service.NewKeyPool(keyPoolRepo, shortIDGenerator, logger,
+			shortURLLength, cfg.KeyPoolSize, cfg.KeyPoolWatermark)
+		keyPool.Run()
+		defer keyPool.Shutdown()
+		shortIDGenerator = keyPool
+		logger.Info("Created key pool service...")
+	}
+
+	shortenerOptions := []service.ShortenerOption{
+		service.WithURLNormalizer(service.NewURLNormalizer(cfg.URLSchemes, domain.URLFragmentPolicy(cfg.URLFragment))),
+	}
+	var domainPolicy *domainpolicy.Policy
+	if cfg.DomainPolicyFile != "" {
+		domainPolicy, err = domainpolicy.NewPolicy(cfg.DomainPolicyFile, domainPolicyInterval, logger)
+		if err != nil {
+			logger.Panicf("load domain policy: %v", err)
+		}
+		domainPolicy.Run()
+		defer domainPolicy.Shutdown()
+		shortenerOptions = append(shortenerOptions, service.WithDomainPolicy(domainPolicy))
+		logger.Info("Loaded domain policy...")
+	}
+
+	shortenerService := service.NewShortenerService(
+		shortIDGenerator,
+		shortenerRepo,
+		shortURLLength,
+		cfg.ShortIDMaxAttempts,
+		cfg.BaseURL,
+		shortenerOptions...,
+	)
 	defer shortenerService.Shutdown()
 	logger.Info("Created shorten service...")
 
 	dbCheckService := service.NewDBCheck(db, cfg)
 	logger.Info("Created DB check service...", "type=", fmt.Sprintf("%T", dbCheckService))
 
-	deleterRepo := repo.NewDeleterRepository(db, logger)
+	deleterRepo, err := repository.NewDeleterRepository(cfg, db, shortenerRepo, logger)
+	if err != nil {
+		logger.Panicf("create deleter repository: %v", err)
+	}
 
-	deleter, err := service.NewDeleter(deleterRepo, logger, 10)
+	deleter, err := service.NewDeleter(deleterRepo, logger, 10,
+		service.WithRetries(cfg.DeleteMaxAttempts, cfg.DeleteRetryBackoff),
+		service.WithDeadLetters(repository.NewDeadLetterRepository(cfg, db)),
+	)
 	if err != nil {
 		logger.Panicf("create deleter service: %#v", err)
 	}
 	deleter.Run()
 	defer deleter.Shutdown()
 
-	serviceAPI := api.NewAPI(shortenerService, dbCheckService, logger, deleter, cfg.SecretKey)
+	reaper := service.NewReaper(shortenerRepo, logger, cfg.ExpiredPurgeInterval)
+	reaper.Run()
+	defer reaper.Shutdown()
+	logger.Info("Created reaper service...")
+
+	trash := service.NewTrashService(deleterRepo, logger, cfg.DeletedGracePeriod, cfg.DeletedPurgeInterval)
+	trash.Run()
+	defer trash.Shutdown()
+	logger.Info("Created trash service...")
+
+	clickRepo, err := repository.NewClickRepository(cfg, db, shortenerRepo, logger)
+	if err != nil {
+		logger.Panicf("create click repository: %v", err)
+	}
+	clickService := service.NewClickService(clickRepo, logger, 100, 10000)
+	clickService.Run()
+	defer clickService.Shutdown()
+	logger.Info("Created click service...")
+
+	trustedProxies, err := api.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		logger.Panicf("parse trusted proxies: %v", err)
+	}
+
+	serviceMetrics := metrics.New("N/A", "N/A")
+	serviceMetrics.RegisterShortener(shortenerService)
+	serviceMetrics.RegisterDeleter(deleter)
+	serviceMetrics.RegisterTrash(trash)
+	if db != nil {
+		serviceMetrics.RegisterDBPool(db)
+	}
+
+	keyRing, err := auth.ParseKeyRing(cfg.SecretKey, cfg.SecretKeys, cfg.SecretKeyID)
+	if err != nil {
+		logger.Panicf("parse jwt signing keys: %v", err)
+	}
+	tokens := auth.NewTokens(keyRing, cfg.JWTTTL, cfg.JWTRefresh)
+	sameSite := map[string]http.SameSite{
+		config.SameSiteLax:    http.SameSiteLaxMode,
+		config.SameSiteStrict: http.SameSiteStrictMode,
+		config.SameSiteNone:   http.SameSiteNoneMode,
+	}[cfg.CookieSameSite]
+
+	apiKeyRepo, err := repository.NewAPIKeyRepository(cfg, db)
+	if err != nil {
+		logger.Panicf("create api key repository: %v", err)
+	}
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo)
+
+	healthChecks := repository.NewHealthChecks(cfg, db)
+	if cfg.PostgresDSN != "" {
+		healthChecks = append(healthChecks, service.NewDBHealthCheck(dbCheckService))
+	}
+	healthChecks = append(healthChecks, service.NewDeleterHealthCheck(deleter, deleterMaxBacklog))
+	healthService := service.NewHealthService(5*time.Second, healthChecks...)
+
+	apiOptions := []api.Option{
+		api.WithHealth(healthService),
+		api.WithClicks(clickService, clickService),
+		api.WithTrustedProxies(trustedProxies),
+		api.WithMetrics(serviceMetrics),
+		api.WithAPIKeys(apiKeyService),
+		api.WithTrash(trash),
+		api.WithTokens(tokens),
+		api.WithCookie(api.CookieOptions{
+			Domain:   cfg.CookieDomain,
+			Secure:   cfg.CookieSecure || cfg.EnableHTTPS,
+			SameSite: sameSite,
+		}),
+		api.WithRateLimits(
+			repository.NewRateLimitStore(cfg, db, logger),
+			domain.RateLimit{Rate: cfg.WriteRateLimit, Burst: cfg.WriteRateBurst},
+			domain.RateLimit{Rate: cfg.RedirectRateLimit, Burst: cfg.RedirectRateBurst},
+		),
+	}
+	if cfg.TrustedSubnet != "" {
+		_, trustedSubnet, err := net.ParseCIDR(cfg.TrustedSubnet)
+		if err != nil {
+			logger.Panicf("parse trusted subnet: %v", err)
+		}
+		apiOptions = append(apiOptions, api.WithTrustedSubnet(trustedSubnet))
+	}
+	if cfg.NotFoundPage != "" {
+		page, err := os.ReadFile(cfg.NotFoundPage)
+		if err != nil {
+			logger.Panicf("read not found page: %v", err)
+		}
+		apiOptions = append(apiOptions, api.WithNotFoundPage(page))
+	}
+
+	serviceAPI := api.NewAPI(shortenerService, dbCheckService, logger, deleter, cfg.SecretKey, apiOptions...)
 	handler := serviceAPI.Routes()
 
 	serverObj := api.NewServer(serverCtx, cfg.Addr, handler)
 
+	grpcServer := grpcapi.NewServer(grpcapi.NewAPI(shortenerService, deleter, logger, cfg.SecretKey,
+		grpcapi.WithTokens(tokens), grpcapi.WithAPIKeys(apiKeyService)))
+	if cfg.GRPCAddr != "" {
+		listener, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			logger.Panicf("grpc listen: %v", err)
+		}
+		go func() {
+			logger.Info("Starting grpc server...", "addr=", cfg.GRPCAddr)
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Errorf("grpc serve: %v", err)
+			}
+		}()
+	}
+
+	shutdownSignals := []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}
+	if domainPolicy != nil {
+		reloadChan := make(chan os.Signal, 1)
+		signal.Notify(reloadChan, syscall.SIGHUP)
+		go func() {
+			for range reloadChan {
+				if err := domainPolicy.Reload(); err != nil {
+					logger.Errorf("reload domain policy: %v", err)
+					continue
+				}
+				logger.Info("Domain policy reloaded by SIGHUP")
+			}
+		}()
+	} else {
+		shutdownSignals = append(shutdownSignals, syscall.SIGHUP)
+	}
+
 	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	signal.Notify(signalChan, shutdownSignals...)
 
 	go func() {
 		s := <-signalChan
 		logger.Info("Received os.Signal. Try graceful shutdown.", "signal=", s)
 
+		healthService.Shutdown()
+
+		logger.Debug("drain before shutdown", "delay=", cfg.ShutdownDrainDelay)
+		time.Sleep(cfg.ShutdownDrainDelay)
+
 		shutdownCtx, shutdownCancel := context.WithTimeout(serverCtx, 30*time.Second)
 		defer shutdownCancel()
 
@@ -114,14 +329,35 @@ func Example() {
 			logger.Error("Error while shutdown", "err", err)
 		}
 
+		logger.Debug("start shutdown grpc server")
+
+		grpcServer.GracefulStop()
+
 		logger.Debug("start serverCancel")
 
 		serverCancel()
 
 		logger.Info("Server shutdowned")
 	}()
-	logger.Info("Starting listen and serve...", "addr=", serverObj.Addr)
-	err = serverObj.ListenAndServe()
+	if cfg.EnableHTTPS {
+		certFile, keyFile := cfg.TLSCertFile, cfg.TLSKeyFile
+		if certFile == "" || keyFile == "" {
+			host, _, err := net.SplitHostPort(cfg.Addr)
+			if err != nil {
+				logger.Panicf("split server addr: %v", err)
+			}
+			certFile, keyFile, err = tlscert.SelfSigned(tlscert.DefaultCacheDir(), []string{host})
+			if err != nil {
+				logger.Panicf("self signed cert: %v", err)
+			}
+			logger.Infoln("Using self signed certificate...", "cert=", certFile)
+		}
+		logger.Info("Starting listen and serve tls...", "addr=", serverObj.Addr)
+		err = serverObj.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		logger.Info("Starting listen and serve...", "addr=", serverObj.Addr)
+		err = serverObj.ListenAndServe()
+	}
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Errorf("ListenAndServe: %v", err)
 	}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAddInvalidURL(t *testing.T) {
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), inmemory.NewShortenerRepository(), 8, 5, "http://localhost:8080")
+	router := NewAPI(shortener, &service.NoOpDBCheck{}, zap.NewNop().Sugar(), nil, "fake_secret_key").Routes()
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		status int
+	}{
+		{name: "text javascript", target: "/", body: "javascript:alert(1)", status: http.StatusBadRequest},
+		{name: "text no host", target: "/", body: "svirex.ru", status: http.StatusBadRequest},
+		{name: "json garbage", target: "/api/shorten", body: `{"url":"not a url"}`, status: http.StatusBadRequest},
+		{name: "text valid", target: "/", body: "HTTP://Svirex.RU:80/", status: http.StatusCreated},
+		{name: "json same normalized", target: "/api/shorten", body: `{"url":"http://svirex.ru/"}`, status: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			if tt.target != "/" {
+				request.Header.Set("Content-Type", "application/json")
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			require.Equal(t, tt.status, recorder.Code)
+			if tt.status == http.StatusBadRequest {
+				require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+				require.Contains(t, recorder.Body.String(), `"error":"invalid url`)
+			}
+		})
+	}
+}
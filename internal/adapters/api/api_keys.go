@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/go-chi/chi"
+)
+
+type apiKeyRequestJSON struct {
+	Name   string         `json:"name"`
+	Scopes []domain.Scope `json:"scopes"`
+}
+
+type apiKeyJSON struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name,omitempty"`
+	Scopes    []domain.Scope `json:"scopes"`
+	CreatedAt time.Time      `json:"created_at"`
+	RevokedAt *time.Time     `json:"revoked_at,omitempty"`
+	Token     string         `json:"token,omitempty"`
+}
+
+func newAPIKeyJSON(key *domain.APIKey) apiKeyJSON {
+	return apiKeyJSON{
+		ID:        key.ID,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
+
+// PostAPIKey - выпуск API ключа, токен возвращается только в этом ответе.
+func (api *API) PostAPIKey(response http.ResponseWriter, request *http.Request) {
+	uid, ok := api.sessionUID(response, request)
+	if !ok {
+		return
+	}
+	var input apiKeyRequestJSON
+	err := json.NewDecoder(request.Body).Decode(&input)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer request.Body.Close()
+	key, token, err := api.apiKeys.Issue(request.Context(), domain.UID(uid), input.Name, input.Scopes)
+	if err != nil {
+		if errors.Is(err, ports.ErrInvalidScope) {
+			api.marshalAndSendJSON(errorJSON{Error: ports.ErrInvalidScope.Error()}, http.StatusBadRequest, response)
+			return
+		}
+		api.logger.Errorln("service issue api key", "err", err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	result := newAPIKeyJSON(key)
+	result.Token = token
+	api.marshalAndSendJSON(result, http.StatusCreated, response)
+}
+
+// GetAPIKeys - список API ключей пользователя без токенов.
+func (api *API) GetAPIKeys(response http.ResponseWriter, request *http.Request) {
+	uid, ok := api.sessionUID(response, request)
+	if !ok {
+		return
+	}
+	keys, err := api.apiKeys.UserKeys(request.Context(), domain.UID(uid))
+	if err != nil {
+		api.logger.Errorln("service get api keys", "err", err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(keys) == 0 {
+		response.WriteHeader(http.StatusNoContent)
+		return
+	}
+	result := make([]apiKeyJSON, 0, len(keys))
+	for i := range keys {
+		result = append(result, newAPIKeyJSON(&keys[i]))
+	}
+	api.marshalAndSendJSON(result, http.StatusOK, response)
+}
+
+// DeleteAPIKey - отзыв API ключа пользователя.
+func (api *API) DeleteAPIKey(response http.ResponseWriter, request *http.Request) {
+	uid, ok := api.sessionUID(response, request)
+	if !ok {
+		return
+	}
+	err := api.apiKeys.Revoke(request.Context(), domain.UID(uid), chi.URLParam(request, "keyID"))
+	if err != nil {
+		if errors.Is(err, ports.ErrNotFound) {
+			response.WriteHeader(http.StatusNotFound)
+			return
+		}
+		api.logger.Errorln("service revoke api key", "err", err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// sessionUID - uid пользователя, вошедшего по jwt cookie. Ключами управляют только из сессии,
+// запрос по API ключу отклоняется.
+func (api *API) sessionUID(response http.ResponseWriter, request *http.Request) (string, bool) {
+	if _, ok := request.Context().Value(JWTKey("apikey")).(*domain.APIKey); ok {
+		response.WriteHeader(http.StatusForbidden)
+		return "", false
+	}
+	uid, ok := request.Context().Value(JWTKey("uid")).(string)
+	if !ok || uid == "" {
+		api.logger.Error("not uid in context")
+		response.WriteHeader(http.StatusUnauthorized)
+		return "", false
+	}
+	return uid, true
+}
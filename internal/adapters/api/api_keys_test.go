@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	repo := inmemory.NewShortenerRepository()
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), repo, 8, 5, "http://localhost:8080")
+	keys := service.NewAPIKeyService(inmemory.NewAPIKeyRepository())
+	router := NewAPI(shortener, &service.NoOpDBCheck{}, zap.NewNop().Sugar(), nil, "fake_secret_key",
+		WithAPIKeys(keys)).Routes()
+
+	send := func(method, target, body string, auth string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		if auth != "" {
+			request.Header.Set("Authorization", auth)
+		}
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	require.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/api/user/keys", `{"scopes":["read"]}`, "", nil).Code)
+
+	recorder := send(http.MethodPost, "/api/shorten", `{"url":"http://svirex.ru"}`, "", nil)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	cookies := recorder.Result().Cookies()
+
+	require.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/api/user/keys", `{"scopes":["admin"]}`, "", cookies).Code)
+	recorder = send(http.MethodPost, "/api/user/keys", `{"name":"ci","scopes":["read"]}`, "", cookies)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	var issued apiKeyJSON
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &issued))
+	require.NotEmpty(t, issued.Token)
+	bearer := "Bearer " + issued.Token
+
+	recorder = send(http.MethodGet, "/api/user/urls", "", bearer, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "http://svirex.ru")
+	require.Empty(t, recorder.Result().Cookies())
+
+	require.Equal(t, http.StatusForbidden, send(http.MethodPost, "/api/shorten", `{"url":"http://ya.ru"}`, bearer, nil).Code)
+	require.Equal(t, http.StatusForbidden, send(http.MethodDelete, "/api/user/urls", `[]`, bearer, nil).Code)
+	require.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/user/keys", "", bearer, nil).Code)
+	recorder = send(http.MethodGet, "/api/user/urls", "", "Bearer mu_bad.token", nil)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Empty(t, recorder.Result().Cookies())
+
+	recorder = send(http.MethodGet, "/api/user/keys", "", "", cookies)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotContains(t, recorder.Body.String(), issued.Token)
+	require.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/api/user/keys/"+issued.ID, "", "", cookies).Code)
+	require.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/api/user/keys/"+issued.ID, "", "", cookies).Code)
+	require.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/api/user/urls", "", bearer, nil).Code)
+}
@@ -3,47 +3,53 @@ package api
 import (
 	"context"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
+	"github.com/Svirex/microurl/internal/core/domain"
 )
 
-// Claims - данные для JWT
-type Claims struct {
-	jwt.RegisteredClaims
-	UserID string
-}
-
 // JWTKey - тип для записи в контекст запроса uid пользователя
 type JWTKey string
 
+// cookieAuth - аутентификация по API ключу из заголовка Authorization или по jwt cookie.
+// Без cookie и без ключа для запросов к данным пользователя создается новый пользователь.
 func (api *API) cookieAuth(next http.Handler) http.Handler {
 	fn := func(response http.ResponseWriter, request *http.Request) {
+		if header := request.Header.Get("Authorization"); header != "" && api.apiKeys != nil {
+			api.bearerAuth(next, response, request, header)
+			return
+		}
 		jwtKey, err := request.Cookie("jwt")
 		if errors.Is(err, http.ErrNoCookie) {
-			if strings.Contains(request.URL.Path, "api/user/urls") {
-				api.logger.Error("not found auth cookei for api/user/urls")
+			if strings.Contains(request.URL.Path, "api/user/") {
+				api.logger.Error("not found auth cookei for api/user/")
 				response.WriteHeader(http.StatusUnauthorized)
 				return
 			}
 			api.generateCookieAndHandleNext(next, response, request)
 			return
 		}
-		uid, err := getUserID(api.secretKey, jwtKey.Value)
-		if err != nil { // если токен не проходит проверку на подлинность
+		claims, err := api.tokens.Parse(jwtKey.Value)
+		if err != nil { // если токен не проходит проверку на подлинность или истек
 			api.logger.Errorln("cookie auth middleware, jwt not valid", err)
 			api.generateCookieAndHandleNext(next, response, request)
 			return
 		}
+		uid := claims.UserID
 		if uid == "" {
 			api.logger.Infoln("cookie auth middleware, uid is empty")
 			response.WriteHeader(http.StatusUnauthorized)
 			return
 		}
-		http.SetCookie(response, jwtKey)
+		if api.tokens.NeedsRefresh(claims) { // скользящее продление и переподпись текущим ключом
+			token, err := api.tokens.Build(uid)
+			if err != nil {
+				api.logger.Errorln("cookie auth middleware, couldn't refresh jwt: ", err)
+			} else {
+				api.setAuthCookie(response, token)
+			}
+		}
 		ctx := context.WithValue(request.Context(), JWTKey("uid"), uid)
 		next.ServeHTTP(response, request.WithContext(ctx))
 
@@ -51,63 +57,66 @@ func (api *API) cookieAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
-func (api *API) generateCookieAndHandleNext(next http.Handler, response http.ResponseWriter, request *http.Request) {
-	uid, err := generateUserIDAndJWTAndSetCookie(api.secretKey, response)
-	if err != nil {
-		api.logger.Errorln("cookie auth middleware, couldn't generate jwt, cookie exist: ", err)
-		response.WriteHeader(http.StatusBadRequest)
+// bearerAuth - аутентификация по API ключу. Для неверного ключа новый пользователь не создается.
+func (api *API) bearerAuth(next http.Handler, response http.ResponseWriter, request *http.Request, header string) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		response.WriteHeader(http.StatusUnauthorized)
 		return
 	}
-	ctx := context.WithValue(request.Context(), JWTKey("uid"), uid)
-	next.ServeHTTP(response, request.WithContext(ctx))
-}
-
-func generateUserID() (string, error) {
-	v, err := uuid.NewRandom()
+	key, err := api.apiKeys.Authenticate(request.Context(), strings.TrimSpace(token))
 	if err != nil {
-		return "", fmt.Errorf("generate uuid: %w", err)
+		api.logger.Infoln("bearer auth middleware, api key not valid", err)
+		response.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		response.WriteHeader(http.StatusUnauthorized)
+		return
 	}
-	return v.String(), nil
+	ctx := context.WithValue(request.Context(), JWTKey("uid"), string(key.UID))
+	ctx = context.WithValue(ctx, JWTKey("apikey"), key)
+	next.ServeHTTP(response, request.WithContext(ctx))
 }
 
-func buildJWTString(secretKey string, uid string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
-		UserID: uid,
-	})
-	tokenString, err := token.SignedString([]byte(secretKey))
-	if err != nil {
-		return "", fmt.Errorf("build jwt token: %w", err)
+// requireScope - запрос по API ключу пропускается, только если у ключа есть право scope.
+// Запросы с jwt cookie пропускаются всегда.
+func requireScope(scope domain.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(response http.ResponseWriter, request *http.Request) {
+			if key, ok := request.Context().Value(JWTKey("apikey")).(*domain.APIKey); ok && !key.HasScope(scope) {
+				http.Error(response, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(response, request)
+		}
+		return http.HandlerFunc(fn)
 	}
-	return tokenString, nil
 }
 
-func getUserID(secretKey string, token string) (string, error) {
-	claims := &Claims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return []byte(secretKey), nil
-	})
+func (api *API) generateCookieAndHandleNext(next http.Handler, response http.ResponseWriter, request *http.Request) {
+	uid, token, err := api.tokens.NewUser()
 	if err != nil {
-		return "", fmt.Errorf("parse user id from jwt: %w", err)
+		api.logger.Errorln("cookie auth middleware, couldn't generate jwt, cookie exist: ", err)
+		response.WriteHeader(http.StatusBadRequest)
+		return
 	}
-	return claims.UserID, nil
+	api.setAuthCookie(response, token)
+	ctx := context.WithValue(request.Context(), JWTKey("uid"), uid)
+	next.ServeHTTP(response, request.WithContext(ctx))
 }
 
-func generateUserIDAndJWTAndSetCookie(secretKey string, response http.ResponseWriter) (string, error) {
-	uid, err := generateUserID()
-	if err != nil {
-		return "", fmt.Errorf("generateUserID: %w", err)
-	}
-	token, err := buildJWTString(secretKey, uid)
-	if err != nil {
-		return "", fmt.Errorf("buildJWTString: %w", err)
+// setAuthCookie - записать JWT в cookie, срок жизни cookie совпадает со сроком действия токена.
+func (api *API) setAuthCookie(response http.ResponseWriter, token string) {
+	cookie := &http.Cookie{
+		Name:     "jwt",
+		Value:    token,
+		Path:     "/",
+		Domain:   api.cookie.Domain,
+		HttpOnly: true,
+		Secure:   api.cookie.Secure,
+		SameSite: api.cookie.SameSite,
 	}
-	jwtCookie := &http.Cookie{
-		Name:  "jwt",
-		Value: token,
+	if ttl := api.tokens.TTL(); ttl > 0 {
+		cookie.MaxAge = int(ttl.Seconds())
 	}
-	http.SetCookie(response, jwtCookie)
-	return uid, nil
+	http.SetCookie(response, cookie)
 }
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/adapters/auth"
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAuthCookie(t *testing.T) {
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), inmemory.NewShortenerRepository(), 8, 5, "http://localhost:8080")
+	oldRing, err := auth.ParseKeyRing("fake_secret_key", nil, "")
+	require.NoError(t, err)
+	ring, err := auth.ParseKeyRing("fake_secret_key", []string{"k2:new_secret_key"}, "k2")
+	require.NoError(t, err)
+	router := NewAPI(shortener, &service.NoOpDBCheck{}, zap.NewNop().Sugar(), nil, "fake_secret_key",
+		WithTokens(auth.NewTokens(ring, time.Hour, time.Minute)),
+		WithCookie(CookieOptions{Domain: "example.com", Secure: true, SameSite: http.SameSiteStrictMode})).Routes()
+	send := func(cookie *http.Cookie) *http.Response {
+		request := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if cookie != nil {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Result()
+	}
+
+	response := send(nil)
+	defer response.Body.Close()
+	cookies := response.Cookies()
+	require.Len(t, cookies, 1)
+	cookie := cookies[0]
+	require.True(t, cookie.HttpOnly)
+	require.True(t, cookie.Secure)
+	require.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	require.Equal(t, "example.com", cookie.Domain)
+	require.Equal(t, 3600, cookie.MaxAge)
+
+	// свежий токен текущего ключа не перевыпускается
+	response = send(&http.Cookie{Name: "jwt", Value: cookie.Value})
+	defer response.Body.Close()
+	require.Empty(t, response.Cookies())
+
+	// токен старого ключа перевыпускается для того же пользователя
+	uid, oldToken, err := auth.NewTokens(oldRing, time.Hour, 0).NewUser()
+	require.NoError(t, err)
+	response = send(&http.Cookie{Name: "jwt", Value: oldToken})
+	defer response.Body.Close()
+	require.Len(t, response.Cookies(), 1)
+	claims, err := auth.NewTokens(ring, time.Hour, time.Minute).Parse(response.Cookies()[0].Value)
+	require.NoError(t, err)
+	require.Equal(t, uid, claims.UserID)
+	require.Equal(t, "k2", claims.KeyID)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBatchRecordsUser(t *testing.T) {
+	repo := inmemory.NewShortenerRepository()
+	repo.Add(context.Background(), "existing", &domain.Record{UID: "other", URL: "http://ya.ru"})
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), repo, 8, 5, "http://localhost:8080")
+	router := NewAPI(shortener, &service.NoOpDBCheck{}, zap.NewNop().Sugar(), nil, "fake_secret_key").Routes()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(
+		`[{"correlation_id":"1","original_url":"http://svirex.ru"},{"correlation_id":"2","original_url":"http://ya.ru"}]`))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	var batch []domain.BatchRecord
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &batch))
+	require.Len(t, batch, 2)
+	require.Equal(t, domain.BatchStatusCreated, batch[0].Status)
+	require.Equal(t, domain.BatchStatusExisting, batch[1].Status)
+	require.Equal(t, domain.URL("http://localhost:8080/existing"), batch[1].ShortURL)
+
+	cookies := recorder.Result().Cookies()
+	require.NotEmpty(t, cookies)
+	request = httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var urls []domain.URLData
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &urls))
+	require.Len(t, urls, 2)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseTrustedProxies - разобрать список доверенных прокси в виде CIDR или отдельных IP-адресов.
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	result := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("parse trusted proxy ip %q", proxy)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			result = append(result, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse trusted proxy cidr: %w", err)
+		}
+		result = append(result, ipNet)
+	}
+	return result, nil
+}
+
+// clientIP - IP-адрес клиента. Заголовкам X-Forwarded-For и X-Real-IP доверяем,
+// только если запрос пришел от доверенного прокси.
+func (api *API) clientIP(request *http.Request) string {
+	remoteIP := request.RemoteAddr
+	if host, _, err := net.SplitHostPort(request.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	if !api.isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+	if forwarded := request.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if i == 0 || !api.isTrustedProxy(hop) {
+				return hop
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(request.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return remoteIP
+}
+
+func (api *API) isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range api.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
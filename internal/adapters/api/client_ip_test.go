@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIP(t *testing.T) {
+	trustedProxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+	api := &API{trustedProxies: trustedProxies}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		expected   string
+	}{
+		{name: "direct", remoteAddr: "1.2.3.4:5555", expected: "1.2.3.4"},
+		{name: "untrusted proxy", remoteAddr: "1.2.3.4:5555", forwarded: "5.6.7.8", expected: "1.2.3.4"},
+		{name: "trusted proxy", remoteAddr: "10.1.1.1:5555", forwarded: "5.6.7.8", expected: "5.6.7.8"},
+		{name: "proxy chain", remoteAddr: "192.168.1.1:5555", forwarded: "9.9.9.9, 5.6.7.8, 10.2.2.2", expected: "5.6.7.8"},
+		{name: "real ip", remoteAddr: "10.1.1.1:5555", realIP: "5.6.7.8", expected: "5.6.7.8"},
+		{name: "garbage header", remoteAddr: "10.1.1.1:5555", forwarded: "unknown", expected: "10.1.1.1"},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.RemoteAddr = test.remoteAddr
+		if test.forwarded != "" {
+			request.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+		if test.realIP != "" {
+			request.Header.Set("X-Real-IP", test.realIP)
+		}
+		require.Equal(t, test.expected, api.clientIP(request), test.name)
+	}
+
+	_, err = ParseTrustedProxies([]string{"not-an-ip"})
+	require.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDeletionJob(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	repo := inmemory.NewShortenerRepository()
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), repo, 8, 5, "http://localhost:8080")
+	deleter, err := service.NewDeleter(inmemory.NewDeleterRepository(repo), logger, 10)
+	require.NoError(t, err)
+	require.NoError(t, deleter.Run())
+	defer deleter.Shutdown()
+	router := NewAPI(shortener, &service.NoOpDBCheck{}, logger, deleter, "fake_secret_key").Routes()
+	send := func(method, target, body string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send(http.MethodPost, "/api/shorten", `{"url":"http://svirex.ru","custom_alias":"promo"}`, nil)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	owner := recorder.Result().Cookies()
+	recorder = send(http.MethodPost, "/api/shorten", `{"url":"http://ya.ru","custom_alias":"other"}`, nil)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	stranger := recorder.Result().Cookies()
+
+	recorder = send(http.MethodDelete, "/api/user/urls", `["promo","other","missing"]`, owner)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	var job domain.DeletionJob
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &job))
+	require.NotEmpty(t, job.ID)
+	require.Equal(t, "/api/user/deletions/"+job.ID, recorder.Header().Get("Location"))
+	require.Equal(t, domain.DeletionJobPending, job.Status)
+
+	require.Equal(t, http.StatusNotFound, send(http.MethodGet, "/api/user/deletions/"+job.ID, "", stranger).Code)
+	require.Equal(t, http.StatusNotFound, send(http.MethodGet, "/api/user/deletions/unknown", "", owner).Code)
+	require.Eventually(t, func() bool {
+		recorder = send(http.MethodGet, "/api/user/deletions/"+job.ID, "", owner)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &job))
+		return job.Status != domain.DeletionJobPending
+	}, 3*time.Second, 50*time.Millisecond)
+	require.Equal(t, domain.DeletionJobDone, job.Status)
+	require.Equal(t, []domain.DeletionItem{
+		{ShortID: "promo", Status: domain.DeleteStatusDeleted},
+		{ShortID: "other", Status: domain.DeleteStatusNotOwned},
+		{ShortID: "missing", Status: domain.DeleteStatusNotFound},
+	}, job.Items)
+	require.Equal(t, http.StatusGone, send(http.MethodGet, "/promo", "", nil).Code)
+}
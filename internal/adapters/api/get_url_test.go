@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGetURLStatuses(t *testing.T) {
+	repo := inmemory.NewShortenerRepository()
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), repo, 8, 5, "http://localhost:8080")
+	api := NewAPI(shortener, &service.NoOpDBCheck{}, zap.NewNop().Sugar(), nil, "fake_secret_key",
+		WithNotFoundPage([]byte("<h1>not found</h1>")),
+	)
+	testServer := httptest.NewServer(api.Routes())
+	defer testServer.Close()
+
+	repo.Add(context.Background(), "alive", &domain.Record{UID: "uid", URL: "http://svirex.ru"})
+	repo.Add(context.Background(), "deleted", &domain.Record{UID: "uid", URL: "http://ya.ru"})
+	inmemory.NewDeleterRepository(repo).Delete(context.Background(), []*domain.DeleteData{{UID: "uid", ShortID: "deleted"}})
+
+	client := testServer.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := client.Get(testServer.URL + "/alive")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	require.Equal(t, "http://svirex.ru", resp.Header.Get("Location"))
+
+	resp, err = client.Get(testServer.URL + "/deleted")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusGone, resp.StatusCode)
+
+	resp, err = client.Get(testServer.URL + "/typo")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+}
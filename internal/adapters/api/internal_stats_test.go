@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGetInternalStats(t *testing.T) {
+	repo := inmemory.NewShortenerRepository()
+	repo.Add(context.Background(), "first", &domain.Record{UID: "uid1", URL: "http://svirex.ru"})
+	repo.Add(context.Background(), "second", &domain.Record{UID: "uid1", URL: "http://ya.ru"})
+	repo.Add(context.Background(), "third", &domain.Record{UID: "uid2", URL: "http://go.dev"})
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), repo, 8, 5, "http://localhost:8080")
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		realIP string
+		status int
+	}{
+		{name: "subnet not set", realIP: "10.0.0.1", status: http.StatusForbidden},
+		{name: "ip not in subnet", opts: []Option{WithTrustedSubnet(subnet)}, realIP: "192.168.0.1", status: http.StatusForbidden},
+		{name: "no ip", opts: []Option{WithTrustedSubnet(subnet)}, status: http.StatusForbidden},
+		{name: "ip in subnet", opts: []Option{WithTrustedSubnet(subnet)}, realIP: "10.1.2.3", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := NewAPI(shortener, &service.NoOpDBCheck{}, zap.NewNop().Sugar(), nil, "fake_secret_key", tt.opts...)
+			request := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+			if tt.realIP != "" {
+				request.Header.Set("X-Real-IP", tt.realIP)
+			}
+			recorder := httptest.NewRecorder()
+			api.Routes().ServeHTTP(recorder, request)
+			require.Equal(t, tt.status, recorder.Code)
+			if tt.status == http.StatusOK {
+				body, err := io.ReadAll(recorder.Body)
+				require.NoError(t, err)
+				require.JSONEq(t, `{"urls":3,"users":2}`, string(body))
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package api
 import (
 	"net/http"
 	"time"
+
+	"github.com/go-chi/chi"
 )
 
 type responseData struct {
@@ -17,6 +19,9 @@ type loggingResponseWriter struct {
 
 // Write - запись тела ответа
 func (w *loggingResponseWriter) Write(data []byte) (int, error) {
+	if w.responseData.status == 0 {
+		w.responseData.status = http.StatusOK
+	}
 	size, err := w.ResponseWriter.Write(data)
 	w.responseData.size += size
 	return size, err
@@ -55,6 +60,19 @@ func (api *API) loggingMiddleware(next http.Handler) http.Handler {
 				"size", responseData.size, // получаем перехваченный размер ответа
 			)
 		}
+
+		if api.metrics != nil {
+			api.metrics.ObserveRequest(routePattern(request), request.Method, responseData.status, duration)
+		}
 	}
 	return http.HandlerFunc(fn)
 }
+
+// routePattern - шаблон маршрута chi, чтобы метрики не зависели от значений параметров.
+func routePattern(request *http.Request) string {
+	routeContext := chi.RouteContext(request.Context())
+	if routeContext == nil || routeContext.RoutePattern() == "" {
+		return "unmatched"
+	}
+	return routeContext.RoutePattern()
+}
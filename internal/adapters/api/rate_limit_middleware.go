@@ -0,0 +1,47 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+)
+
+// rateLimitMiddleware - ограничение частоты запросов по uid пользователя и IP клиента.
+// Сначала проверяется корзина пользователя, и если она отказала, то токен IP не тратится.
+// name разделяет корзины разных групп маршрутов. Если хранилище недоступно, то запрос пропускается.
+func (api *API) rateLimitMiddleware(name string, limit domain.RateLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if api.rateLimits == nil || !limit.Enabled() {
+			return next
+		}
+		fn := func(writer http.ResponseWriter, request *http.Request) {
+			keys := make([]string, 0, 2)
+			if uid, ok := request.Context().Value(JWTKey("uid")).(string); ok && uid != "" {
+				keys = append(keys, name+":uid:"+uid)
+			}
+			keys = append(keys, name+":ip:"+api.clientIP(request))
+			now := time.Now()
+			for _, key := range keys {
+				allowed, retryAfter, err := api.rateLimits.Take(request.Context(), key, limit, now)
+				if err != nil {
+					api.logger.Errorln("rate limit middleware, take token", "key", key, "err", err)
+					continue
+				}
+				if !allowed {
+					seconds := int(math.Ceil(retryAfter.Seconds()))
+					if seconds < 1 {
+						seconds = 1
+					}
+					writer.Header().Set("Retry-After", strconv.Itoa(seconds))
+					http.Error(writer, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+					return
+				}
+			}
+			next.ServeHTTP(writer, request)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
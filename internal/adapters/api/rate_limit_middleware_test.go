@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	repo := inmemory.NewShortenerRepository()
+	repo.Add(context.Background(), "first", &domain.Record{UID: "uid1", URL: "http://svirex.ru"})
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), repo, 8, 5, "http://localhost:8080")
+	api := NewAPI(shortener, &service.NoOpDBCheck{}, zap.NewNop().Sugar(), nil, "fake_secret_key",
+		WithRateLimits(inmemory.NewRateLimitStore(), domain.RateLimit{Rate: 0.01, Burst: 2}, domain.RateLimit{Rate: 0.01, Burst: 1}))
+	router := api.Routes()
+
+	counter := 0
+	do := func(method, target, remoteAddr string) *httptest.ResponseRecorder {
+		counter++
+		request := httptest.NewRequest(method, target, strings.NewReader("http://go.dev/"+strconv.Itoa(counter)))
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/", "10.0.0.1:1234").Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/", "10.0.0.1:1234").Code)
+	recorder := do(http.MethodPost, "/", "10.0.0.1:1234")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "100", recorder.Header().Get("Retry-After"))
+
+	// у другого клиента своя корзина
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/", "10.0.0.2:1234").Code)
+
+	// редиректы ограничиваются отдельно от записи
+	require.Equal(t, http.StatusTemporaryRedirect, do(http.MethodGet, "/first", "10.0.0.1:1234").Code)
+	require.Equal(t, http.StatusTooManyRequests, do(http.MethodGet, "/first", "10.0.0.1:1234").Code)
+
+	// без ограничений не трогаем запросы на чтение
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/ping", "10.0.0.1:1234").Code)
+}
+
+func TestRateLimitMiddlewareSkipsIPWhenUserDenied(t *testing.T) {
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), inmemory.NewShortenerRepository(), 8, 5, "http://localhost:8080")
+	api := NewAPI(shortener, &service.NoOpDBCheck{}, zap.NewNop().Sugar(), nil, "fake_secret_key",
+		WithRateLimits(inmemory.NewRateLimitStore(), domain.RateLimit{Rate: 0.01, Burst: 2}, domain.RateLimit{Rate: 0.01, Burst: 1}))
+	router := api.Routes()
+
+	counter := 0
+	do := func(remoteAddr string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		counter++
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("http://go.dev/"+strconv.Itoa(counter)))
+		request.RemoteAddr = remoteAddr
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := do("10.0.0.1:1234", nil)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	user := recorder.Result().Cookies()
+	require.Equal(t, http.StatusCreated, do("10.0.0.2:1234", user).Code)
+	require.Equal(t, http.StatusTooManyRequests, do("10.0.0.3:1234", user).Code)
+
+	// отказ по пользователю не тратит токен IP
+	require.Equal(t, http.StatusCreated, do("10.0.0.3:1234", nil).Code)
+	require.Equal(t, http.StatusCreated, do("10.0.0.3:1234", nil).Code)
+}
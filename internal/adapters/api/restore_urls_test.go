@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/adapters/auth"
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPostRestoreURLs(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	repo := inmemory.NewShortenerRepository()
+	deleter := inmemory.NewDeleterRepository(repo)
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), repo, 8, 5, "http://localhost:8080")
+	trash := service.NewTrashService(deleter, logger, time.Hour, time.Hour)
+	router := NewAPI(shortener, &service.NoOpDBCheck{}, logger, nil, "fake_secret_key", WithTrash(trash)).Routes()
+	send := func(method, target, body string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send(http.MethodPost, "/api/shorten", `{"url":"http://svirex.ru","custom_alias":"promo"}`, nil)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	owner := recorder.Result().Cookies()
+	uid, err := auth.NewSecretTokens("fake_secret_key").GetUserID(owner[0].Value)
+	require.NoError(t, err)
+	require.NoError(t, deleter.Delete(context.Background(), []*domain.DeleteData{{UID: uid, ShortID: "promo"}}))
+	require.Equal(t, http.StatusGone, send(http.MethodGet, "/promo", "", nil).Code)
+
+	require.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/api/user/urls/restore", `["promo"]`, nil).Code)
+	require.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/api/user/urls/restore", `[]`, owner).Code)
+
+	recorder = send(http.MethodPost, "/api/user/urls/restore", `["promo","missing"]`, owner)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var results []domain.RestoreResult
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &results))
+	require.Equal(t, []domain.RestoreResult{
+		{ShortID: "promo", Status: domain.RestoreStatusRestored},
+		{ShortID: "missing", Status: domain.RestoreStatusNotFound},
+	}, results)
+	require.Equal(t, int64(1), trash.Restored())
+	require.Equal(t, http.StatusTemporaryRedirect, send(http.MethodGet, "/promo", "", nil).Code)
+}
@@ -7,7 +7,9 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"time"
 
+	"github.com/Svirex/microurl/internal/adapters/auth"
 	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/Svirex/microurl/internal/core/ports"
 	"github.com/go-chi/chi"
@@ -16,11 +18,124 @@ import (
 
 // API - структура апи.
 type API struct {
-	shortener ports.ShortenerService
-	ping      ports.DBCheck
-	logger    ports.Logger
-	deleter   ports.DeleterService
-	secretKey string
+	shortener      ports.ShortenerService
+	ping           ports.DBCheck
+	logger         ports.Logger
+	deleter        ports.DeleterService
+	clicks         ports.ClickRecorder
+	clickStats     ports.ClickStatsService
+	tokens         *auth.Tokens
+	cookie         CookieOptions
+	trustedProxies []*net.IPNet
+	trustedSubnet  *net.IPNet
+	notFoundPage   []byte
+	metrics        Metrics
+	health         ports.HealthService
+	rateLimits     ports.RateLimitStore
+	writeLimit     domain.RateLimit
+	redirectLimit  domain.RateLimit
+	apiKeys        ports.APIKeyService
+	trash          ports.TrashService
+}
+
+// Metrics - сбор метрик HTTP запросов.
+type Metrics interface {
+	// ObserveRequest - учесть обработанный запрос, route - шаблон маршрута chi.
+	ObserveRequest(route, method string, status int, duration time.Duration)
+	// Redirect - учесть редирект на оригинальный URL.
+	Redirect()
+	// Handler - обработчик для сбора метрик.
+	Handler() http.Handler
+}
+
+// Option - дополнительный параметр апи.
+type Option func(*API)
+
+// WithClicks - запись переходов по ссылкам и статистика по ним.
+func WithClicks(clicks ports.ClickRecorder, clickStats ports.ClickStatsService) Option {
+	return func(api *API) {
+		api.clicks = clicks
+		api.clickStats = clickStats
+	}
+}
+
+// WithTrustedProxies - прокси, которым доверяем заголовки с IP-адресом клиента.
+func WithTrustedProxies(trustedProxies []*net.IPNet) Option {
+	return func(api *API) {
+		api.trustedProxies = trustedProxies
+	}
+}
+
+// WithTrustedSubnet - подсеть, из которой доступна внутренняя статистика сервиса.
+func WithTrustedSubnet(trustedSubnet *net.IPNet) Option {
+	return func(api *API) {
+		api.trustedSubnet = trustedSubnet
+	}
+}
+
+// WithMetrics - сбор метрик и маршрут /metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(api *API) {
+		api.metrics = metrics
+	}
+}
+
+// WithHealth - проверки готовности для /readyz.
+func WithHealth(health ports.HealthService) Option {
+	return func(api *API) {
+		api.health = health
+	}
+}
+
+// WithRateLimits - ограничение частоты запросов на создание и удаление ссылок и на редиректы.
+func WithRateLimits(store ports.RateLimitStore, write, redirect domain.RateLimit) Option {
+	return func(api *API) {
+		api.rateLimits = store
+		api.writeLimit = write
+		api.redirectLimit = redirect
+	}
+}
+
+// CookieOptions - атрибуты cookie с JWT, cookie всегда HttpOnly.
+type CookieOptions struct {
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// WithTokens - выпуск и проверка JWT с набором ключей, сроком действия и продлением.
+func WithTokens(tokens *auth.Tokens) Option {
+	return func(api *API) {
+		api.tokens = tokens
+	}
+}
+
+// WithCookie - атрибуты cookie с JWT.
+func WithCookie(cookie CookieOptions) Option {
+	return func(api *API) {
+		api.cookie = cookie
+	}
+}
+
+// WithAPIKeys - аутентификация по API ключам и маршруты /api/user/keys.
+func WithAPIKeys(apiKeys ports.APIKeyService) Option {
+	return func(api *API) {
+		api.apiKeys = apiKeys
+	}
+}
+
+// WithTrash - маршрут POST /api/user/urls/restore для отмены удаления ссылок.
+func WithTrash(trash ports.TrashService) Option {
+	return func(api *API) {
+		api.trash = trash
+	}
+}
+
+// WithNotFoundPage - HTML-страница, которую отдаем для несуществующих коротких ссылок.
+func WithNotFoundPage(page []byte) Option {
+	return func(api *API) {
+		api.notFoundPage = page
+	}
 }
 
 // NewAPI - создание нового апи.
@@ -30,14 +145,20 @@ func NewAPI(
 	logger ports.Logger,
 	deleter ports.DeleterService,
 	secretKey string,
+	opts ...Option,
 ) *API {
-	return &API{
+	api := &API{
 		shortener: shortener,
 		ping:      ping,
 		logger:    logger,
 		deleter:   deleter,
-		secretKey: secretKey,
+		tokens:    auth.NewSecretTokens(secretKey),
+		cookie:    CookieOptions{SameSite: http.SameSiteLaxMode},
+	}
+	for _, opt := range opts {
+		opt(api)
 	}
+	return api
 }
 
 // NewServer - создание нового сервера.
@@ -54,19 +175,41 @@ func (api *API) Routes() chi.Router {
 	router := chi.NewRouter()
 
 	router.Use(middleware.Recoverer)
+	router.Use(api.tracingMiddleware)
 	router.Use(api.loggingMiddleware)
 	router.Use(api.gzipHandler)
 	router.Use(middleware.Compress(5, "text/html", "application/json"))
 	router.Use(api.cookieAuth)
 
-	router.Get("/{shortID:[A-Za-z]+}", api.GetURL)
-	router.Post("/", api.PostAddURL)
+	limitWrite := api.rateLimitMiddleware("write", api.writeLimit)
+	limitRedirect := api.rateLimitMiddleware("redirect", api.redirectLimit)
+
+	router.With(limitRedirect).Get("/{shortID:[A-Za-z0-9_-]+}", api.GetURL)
+	router.With(limitWrite, requireScope(domain.ScopeShorten)).Post("/", api.PostAddURL)
 	router.Get("/ping", api.GetPingDB)
+	router.Get("/healthz", api.GetHealthz)
+	router.Get("/readyz", api.GetReadyz)
+	if api.metrics != nil {
+		router.Handle("/metrics", api.metrics.Handler())
+	}
 	router.Route("/api", func(router chi.Router) {
-		router.Post("/shorten", api.JSONShorten)
-		router.Post("/shorten/batch", api.PostAddBatch)
-		router.Get("/user/urls", api.GetAllUrls)
-		router.Delete("/user/urls", api.DeleteUrls)
+		router.With(limitWrite, requireScope(domain.ScopeShorten)).Post("/shorten", api.JSONShorten)
+		router.With(limitWrite, requireScope(domain.ScopeShorten)).Post("/shorten/batch", api.PostAddBatch)
+		router.With(requireScope(domain.ScopeRead)).Get("/user/urls", api.GetAllUrls)
+		router.With(limitWrite, requireScope(domain.ScopeDelete)).Delete("/user/urls", api.DeleteUrls)
+		router.With(requireScope(domain.ScopeDelete)).Get("/user/deletions/{jobID}", api.GetDeletionJob)
+		if api.trash != nil {
+			router.With(limitWrite, requireScope(domain.ScopeDelete)).Post("/user/urls/restore", api.PostRestoreURLs)
+		}
+		router.With(limitWrite, requireScope(domain.ScopeShorten)).Patch("/user/urls/{shortID}", api.PatchURL)
+		router.With(requireScope(domain.ScopeRead)).Get("/user/urls/{shortID}/stats", api.GetURLStats)
+		router.With(requireScope(domain.ScopeRead)).Get("/user/urls/{shortID}/revisions", api.GetURLRevisions)
+		if api.apiKeys != nil {
+			router.Post("/user/keys", api.PostAPIKey)
+			router.Get("/user/keys", api.GetAPIKeys)
+			router.Delete("/user/keys/{keyID}", api.DeleteAPIKey)
+		}
+		router.Get("/internal/stats", api.GetInternalStats)
 	})
 
 	return router
@@ -89,6 +232,9 @@ func (api *API) PostAddURL(w http.ResponseWriter, r *http.Request) {
 		URL: domain.URL(url),
 	})
 	if err != nil {
+		if api.sendInputError(err, w) {
+			return
+		}
 		if errors.Is(err, ports.ErrAlreadyExists) {
 			w.WriteHeader(http.StatusConflict)
 			w.Write([]byte(string(shortURL)))
@@ -106,26 +252,75 @@ func (api *API) GetURL(w http.ResponseWriter, r *http.Request) {
 	shortID := domain.ShortID(chi.URLParam(r, "shortID"))
 	url, err := api.shortener.Get(r.Context(), shortID)
 	if err != nil {
-		if errors.Is(err, ports.ErrNotFound) {
+		if errors.Is(err, ports.ErrDeleted) || errors.Is(err, ports.ErrExpired) {
 			w.WriteHeader(http.StatusGone)
 			return
 		}
+		if errors.Is(err, ports.ErrNotFound) {
+			api.sendNotFound(w)
+			return
+		}
+		if errors.Is(err, ports.ErrForbiddenDomain) {
+			api.logger.Infoln("get url by short id, forbidden domain", "err", err)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
 		api.logger.Errorln("get url by short id: ", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	api.recordClick(r, shortID)
+	if api.metrics != nil {
+		api.metrics.Redirect()
+	}
 	w.Header().Set("Location", string(url))
 	w.WriteHeader(http.StatusTemporaryRedirect)
 }
 
+func (api *API) sendNotFound(w http.ResponseWriter) {
+	if len(api.notFoundPage) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	w.Write(api.notFoundPage)
+}
+
+func (api *API) recordClick(r *http.Request, shortID domain.ShortID) {
+	if api.clicks == nil {
+		return
+	}
+	api.clicks.Record(r.Context(), &domain.Click{
+		ShortID:   shortID,
+		ClickedAt: time.Now().UTC(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IP:        api.clientIP(r),
+	})
+}
+
 type inputJSON struct {
-	URL domain.URL `json:"url"`
+	URL         domain.URL     `json:"url"`
+	CustomAlias domain.ShortID `json:"custom_alias,omitempty"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+	TTL         int64          `json:"ttl,omitempty"`
+}
+
+type updateJSON struct {
+	URL       *domain.URL `json:"original_url,omitempty"`
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"`
+	TTL       int64       `json:"ttl,omitempty"`
 }
 
 type outJSON struct {
 	ShortURL domain.ShortURL `json:"result"`
 }
 
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
 // JSONShorten - добавление записи из json запроса.
 func (api *API) JSONShorten(w http.ResponseWriter, r *http.Request) {
 	contentType := r.Header.Get("Content-Type")
@@ -153,10 +348,16 @@ func (api *API) JSONShorten(w http.ResponseWriter, r *http.Request) {
 		uid = ""
 	}
 	shortURL, err := api.shortener.Add(r.Context(), &domain.Record{
-		UID: domain.UID(uid),
-		URL: inJSON.URL,
+		UID:         domain.UID(uid),
+		URL:         inJSON.URL,
+		CustomAlias: inJSON.CustomAlias,
+		ExpiresAt:   inJSON.ExpiresAt,
+		TTL:         inJSON.TTL,
 	})
 	if err != nil {
+		if api.sendInputError(err, w) {
+			return
+		}
 		if errors.Is(err, ports.ErrAlreadyExists) {
 			result := outJSON{
 				ShortURL: shortURL,
@@ -174,6 +375,28 @@ func (api *API) JSONShorten(w http.ResponseWriter, r *http.Request) {
 	api.marshalAndSendJSON(result, http.StatusCreated, w)
 }
 
+// GetHealthz - процесс жив и обрабатывает запросы.
+func (api *API) GetHealthz(response http.ResponseWriter, _ *http.Request) {
+	api.marshalAndSendJSON(&domain.HealthReport{
+		Status: domain.HealthStatusOK,
+		Checks: make([]domain.CheckResult, 0),
+	}, http.StatusOK, response)
+}
+
+// GetReadyz - отчет о проверках готовности, 503 если хотя бы одна не прошла или сервис останавливается.
+func (api *API) GetReadyz(response http.ResponseWriter, request *http.Request) {
+	if api.health == nil {
+		api.GetHealthz(response, request)
+		return
+	}
+	report := api.health.Ready(request.Context())
+	status := http.StatusOK
+	if report.Status != domain.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	api.marshalAndSendJSON(report, status, response)
+}
+
 // GetPingDB - проверка работоспособности подключения к БД.
 func (api *API) GetPingDB(w http.ResponseWriter, r *http.Request) {
 	err := api.ping.Ping(r.Context())
@@ -206,9 +429,16 @@ func (api *API) PostAddBatch(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	uid, ok := r.Context().Value(JWTKey("uid")).(string)
+	if !ok {
+		uid = ""
+	}
 	if len(batch) != 0 {
-		batch, err = api.shortener.Batch(r.Context(), domain.UID(""), batch)
+		batch, err = api.shortener.Batch(r.Context(), domain.UID(uid), batch)
 		if err != nil {
+			if api.sendInputError(err, w) {
+				return
+			}
 			api.logger.Errorf("api, batch, service error: %w, result: %v", err, batch)
 			w.WriteHeader(http.StatusBadRequest)
 			return
@@ -240,6 +470,147 @@ func (api *API) GetAllUrls(response http.ResponseWriter, request *http.Request)
 	api.marshalAndSendJSON(result, http.StatusOK, response)
 }
 
+// GetURLStats - статистика переходов по ссылке пользователя.
+func (api *API) GetURLStats(response http.ResponseWriter, request *http.Request) {
+	var uid string
+	var ok bool
+	if uid, ok = request.Context().Value(JWTKey("uid")).(string); !ok || uid == "" {
+		api.logger.Error("not uid in context")
+		response.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if api.clickStats == nil {
+		response.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	shortID := domain.ShortID(chi.URLParam(request, "shortID"))
+	stats, err := api.clickStats.LinkStats(request.Context(), domain.UID(uid), shortID)
+	if err != nil {
+		if errors.Is(err, ports.ErrNotFound) {
+			response.WriteHeader(http.StatusNotFound)
+			return
+		}
+		api.logger.Errorln("service get url stats", "err", err)
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	api.marshalAndSendJSON(stats, http.StatusOK, response)
+}
+
+// PatchURL - изменение адреса назначения и срока действия ссылки пользователя.
+// "expires_at": null делает ссылку бессрочной.
+func (api *API) PatchURL(response http.ResponseWriter, request *http.Request) {
+	var uid string
+	var ok bool
+	if uid, ok = request.Context().Value(JWTKey("uid")).(string); !ok || uid == "" {
+		api.logger.Error("not uid in context")
+		response.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if request.Header.Get("Content-Type") != "application/json" {
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(request.Body)
+	if err != nil || len(body) == 0 {
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer request.Body.Close()
+	var input updateJSON
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &input) != nil || json.Unmarshal(body, &fields) != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	update := &domain.URLUpdate{
+		URL:         input.URL,
+		ExpiresAt:   input.ExpiresAt,
+		TTL:         input.TTL,
+		ClearExpiry: string(fields["expires_at"]) == "null",
+	}
+	if update.URL == nil && update.ExpiresAt == nil && update.TTL == 0 && !update.ClearExpiry {
+		api.marshalAndSendJSON(errorJSON{Error: "nothing to update"}, http.StatusBadRequest, response)
+		return
+	}
+	shortID := domain.ShortID(chi.URLParam(request, "shortID"))
+	data, err := api.shortener.Update(request.Context(), domain.UID(uid), shortID, update)
+	if err != nil {
+		if api.sendInputError(err, response) {
+			return
+		}
+		switch {
+		case errors.Is(err, ports.ErrNotFound):
+			response.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, ports.ErrNotCreator):
+			api.marshalAndSendJSON(errorJSON{Error: ports.ErrNotCreator.Error()}, http.StatusForbidden, response)
+		case errors.Is(err, ports.ErrExpired):
+			response.WriteHeader(http.StatusGone)
+		case errors.Is(err, ports.ErrSharedLink):
+			api.marshalAndSendJSON(errorJSON{Error: ports.ErrSharedLink.Error()}, http.StatusConflict, response)
+		case errors.Is(err, ports.ErrAlreadyExists):
+			api.marshalAndSendJSON(errorJSON{Error: ports.ErrAlreadyExists.Error()}, http.StatusConflict, response)
+		default:
+			api.logger.Errorln("service update url", "err", err)
+			response.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+	api.marshalAndSendJSON(data, http.StatusOK, response)
+}
+
+// GetURLRevisions - прежние состояния ссылки пользователя от старых к новым.
+func (api *API) GetURLRevisions(response http.ResponseWriter, request *http.Request) {
+	var uid string
+	var ok bool
+	if uid, ok = request.Context().Value(JWTKey("uid")).(string); !ok || uid == "" {
+		api.logger.Error("not uid in context")
+		response.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	shortID := domain.ShortID(chi.URLParam(request, "shortID"))
+	revisions, err := api.shortener.URLRevisions(request.Context(), domain.UID(uid), shortID)
+	if err != nil {
+		if errors.Is(err, ports.ErrNotFound) {
+			response.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, ports.ErrNotCreator) {
+			api.marshalAndSendJSON(errorJSON{Error: ports.ErrNotCreator.Error()}, http.StatusForbidden, response)
+			return
+		}
+		api.logger.Errorln("service get url revisions", "err", err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(revisions) == 0 {
+		response.WriteHeader(http.StatusNoContent)
+		return
+	}
+	api.marshalAndSendJSON(revisions, http.StatusOK, response)
+}
+
+// GetInternalStats - количество сокращенных ссылок и пользователей.
+// Доступно только из доверенной подсети, IP-адрес клиента берется из заголовка X-Real-IP.
+func (api *API) GetInternalStats(response http.ResponseWriter, request *http.Request) {
+	if api.trustedSubnet == nil {
+		response.WriteHeader(http.StatusForbidden)
+		return
+	}
+	ip := net.ParseIP(request.Header.Get("X-Real-IP"))
+	if ip == nil || !api.trustedSubnet.Contains(ip) {
+		response.WriteHeader(http.StatusForbidden)
+		return
+	}
+	stats, err := api.shortener.Stats(request.Context())
+	if err != nil {
+		api.logger.Errorln("service get stats", "err", err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	api.marshalAndSendJSON(stats, http.StatusOK, response)
+}
+
 // DeleteUrls - обработка запроса для удаления записей
 func (api *API) DeleteUrls(response http.ResponseWriter, request *http.Request) {
 	var uid string
@@ -268,8 +639,92 @@ func (api *API) DeleteUrls(response http.ResponseWriter, request *http.Request)
 		response.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	api.deleter.Process(request.Context(), uid, shortIDs)
-	response.WriteHeader(http.StatusAccepted)
+	jobID, err := api.deleter.Process(request.Context(), uid, shortIDs)
+	if err != nil {
+		api.logger.Errorf("api, delete: %v", err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	job, err := api.deleter.Job(request.Context(), domain.UID(uid), jobID)
+	if err != nil {
+		api.logger.Errorf("api, delete, get job: %v", err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	response.Header().Set("Location", "/api/user/deletions/"+jobID)
+	api.marshalAndSendJSON(job, http.StatusAccepted, response)
+}
+
+// GetDeletionJob - состояние задачи удаления пользователя: pending, done или failed и результат по каждой ссылке.
+// Задачи хранятся только в памяти процесса, который принял DELETE: после перезапуска или на другой реплике
+// ответ 404, хотя сами ссылки удаляются и недошедшие батчи попадают в dead letter.
+func (api *API) GetDeletionJob(response http.ResponseWriter, request *http.Request) {
+	uid, ok := request.Context().Value(JWTKey("uid")).(string)
+	if !ok || uid == "" {
+		api.logger.Error("not uid in context")
+		response.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	job, err := api.deleter.Job(request.Context(), domain.UID(uid), chi.URLParam(request, "jobID"))
+	if errors.Is(err, ports.ErrNotFound) {
+		response.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		api.logger.Errorf("api, get deletion job: %v", err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	api.marshalAndSendJSON(job, http.StatusOK, response)
+}
+
+// PostRestoreURLs - отменить удаление ссылок пользователя, в ответе статус по каждому идентификатору.
+// Удаление выполняется асинхронно, поэтому ссылку, удаление которой еще в очереди, восстановить нельзя.
+func (api *API) PostRestoreURLs(response http.ResponseWriter, request *http.Request) {
+	uid, ok := request.Context().Value(JWTKey("uid")).(string)
+	if !ok || uid == "" {
+		api.logger.Error("not uid in context")
+		response.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	contentType := request.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		api.logger.Errorf("api, restore, Content-Type not json: %s", contentType)
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer request.Body.Close()
+	var shortIDs []domain.ShortID
+	err := json.NewDecoder(request.Body).Decode(&shortIDs)
+	if err != nil || len(shortIDs) == 0 {
+		response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	results, err := api.trash.Restore(request.Context(), domain.UID(uid), shortIDs)
+	if err != nil {
+		api.logger.Errorf("api, restore: %v", err)
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	api.marshalAndSendJSON(results, http.StatusOK, response)
+}
+
+// sendInputError - отправить ответ, если ошибка связана с некорректными параметрами записи.
+func (api *API) sendInputError(err error, w http.ResponseWriter) bool {
+	var urlErr *ports.URLValidationError
+	switch {
+	case errors.As(err, &urlErr):
+		api.marshalAndSendJSON(errorJSON{Error: urlErr.Error()}, http.StatusBadRequest, w)
+	case errors.Is(err, ports.ErrInvalidAlias), errors.Is(err, ports.ErrInvalidExpiry):
+		api.marshalAndSendJSON(errorJSON{Error: err.Error()}, http.StatusBadRequest, w)
+	case errors.Is(err, ports.ErrForbiddenDomain):
+		api.marshalAndSendJSON(errorJSON{Error: ports.ErrForbiddenDomain.Error()}, http.StatusForbidden, w)
+	case errors.Is(err, ports.ErrAliasAlreadyExists):
+		api.marshalAndSendJSON(errorJSON{Error: ports.ErrAliasAlreadyExists.Error()}, http.StatusConflict, w)
+	default:
+		return false
+	}
+	return true
 }
 
 func (api *API) marshalAndSendJSON(data any, okStatus int, w http.ResponseWriter) {
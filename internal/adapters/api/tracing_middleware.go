@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName - имя трассировщика HTTP запросов.
+const tracerName = "github.com/Svirex/microurl/internal/adapters/api"
+
+// tracingMiddleware - спан на каждый запрос, контекст трассировки берется из заголовка traceparent.
+func (api *API) tracingMiddleware(next http.Handler) http.Handler {
+	fn := func(writer http.ResponseWriter, request *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+		ctx, span := otel.Tracer(tracerName).Start(ctx, request.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(request.Method),
+				semconv.URLPath(request.URL.Path),
+			),
+		)
+		defer span.End()
+
+		responseData := &responseData{}
+		next.ServeHTTP(&loggingResponseWriter{
+			ResponseWriter: writer,
+			responseData:   responseData,
+		}, request.WithContext(ctx))
+
+		route := routePattern(request)
+		span.SetName(request.Method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(responseData.status))
+		if responseData.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(responseData.status))
+		}
+	}
+	return http.HandlerFunc(fn)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func TestTracingPropagatesTraceparent(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := otel.GetTracerProvider()
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	}()
+
+	repo := inmemory.NewShortenerRepository()
+	repo.Add(context.Background(), "alive", &domain.Record{UID: "uid", URL: "http://svirex.ru"})
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), repo, 8, 5, "http://localhost:8080")
+	api := NewAPI(shortener, &service.NoOpDBCheck{}, zap.NewNop().Sugar(), nil, "fake_secret_key")
+
+	request := httptest.NewRequest(http.MethodGet, "/alive", nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	response := httptest.NewRecorder()
+	api.Routes().ServeHTTP(response, request)
+	require.Equal(t, http.StatusTemporaryRedirect, response.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	serviceSpan, httpSpan := spans[0], spans[1]
+	require.Equal(t, "ShortenerService.Get", serviceSpan.Name())
+	require.Equal(t, "GET /{shortID:[A-Za-z0-9_-]+}", httpSpan.Name())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", httpSpan.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", httpSpan.Parent().SpanID().String())
+	require.Equal(t, httpSpan.SpanContext().SpanID(), serviceSpan.Parent().SpanID())
+}
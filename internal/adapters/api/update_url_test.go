@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPatchURL(t *testing.T) {
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), inmemory.NewShortenerRepository(), 8, 5, "http://localhost:8080")
+	router := NewAPI(shortener, &service.NoOpDBCheck{}, zap.NewNop().Sugar(), nil, "fake_secret_key").Routes()
+	send := func(method, target, body string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send(http.MethodPost, "/api/shorten", `{"url":"http://typo.ru","custom_alias":"promo"}`, nil)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	owner := recorder.Result().Cookies()
+	recorder = send(http.MethodPost, "/api/shorten", `{"url":"http://ya.ru"}`, nil)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	stranger := recorder.Result().Cookies()
+
+	require.Equal(t, http.StatusNotFound, send(http.MethodPatch, "/api/user/urls/promo", `{"original_url":"http://evil.ru"}`, stranger).Code)
+	require.Equal(t, http.StatusBadRequest, send(http.MethodPatch, "/api/user/urls/promo", `{}`, owner).Code)
+	require.Equal(t, http.StatusBadRequest, send(http.MethodPatch, "/api/user/urls/promo", `{"original_url":"ftp://svirex.ru"}`, owner).Code)
+	require.Equal(t, http.StatusNoContent, send(http.MethodGet, "/api/user/urls/promo/revisions", "", owner).Code)
+
+	recorder = send(http.MethodPatch, "/api/user/urls/promo", `{"original_url":"http://SVIREX.ru","ttl":3600}`, owner)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var data domain.URLData
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &data))
+	require.Equal(t, domain.URL("http://svirex.ru"), data.URL)
+	require.Equal(t, domain.URL("http://localhost:8080/promo"), data.ShortURL)
+	require.NotNil(t, data.ExpiresAt)
+
+	recorder = send(http.MethodGet, "/promo", "", nil)
+	require.Equal(t, http.StatusTemporaryRedirect, recorder.Code)
+	require.Equal(t, "http://svirex.ru", recorder.Header().Get("Location"))
+
+	recorder = send(http.MethodPatch, "/api/user/urls/promo", `{"expires_at":null}`, owner)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotContains(t, recorder.Body.String(), "expires_at")
+
+	recorder = send(http.MethodGet, "/api/user/urls/promo/revisions", "", owner)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var revisions []domain.URLRevision
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &revisions))
+	require.Len(t, revisions, 2)
+	require.Equal(t, domain.URL("http://typo.ru"), revisions[0].URL)
+	require.Nil(t, revisions[0].ExpiresAt)
+	require.NotNil(t, revisions[1].ExpiresAt)
+	require.Equal(t, http.StatusNotFound, send(http.MethodGet, "/api/user/urls/promo/revisions", "", stranger).Code)
+
+	recorder = send(http.MethodPost, "/api/shorten", `{"url":"http://svirex.ru"}`, nil)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+	coOwner := recorder.Result().Cookies()
+	require.Equal(t, http.StatusForbidden, send(http.MethodPatch, "/api/user/urls/promo", `{"original_url":"http://evil.ru"}`, coOwner).Code)
+	require.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/user/urls/promo/revisions", "", coOwner).Code)
+	require.Equal(t, http.StatusConflict, send(http.MethodPatch, "/api/user/urls/promo", `{"original_url":"http://evil.ru"}`, owner).Code)
+	recorder = send(http.MethodGet, "/promo", "", nil)
+	require.Equal(t, "http://svirex.ru", recorder.Header().Get("Location"))
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGetURLStats(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	repo := inmemory.NewShortenerRepository()
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), repo, 8, 5, "http://localhost:8080")
+	clicks := service.NewClickService(repo, logger, 10, 10)
+	require.NoError(t, clicks.Run())
+	router := NewAPI(shortener, &service.NoOpDBCheck{}, logger, nil, "fake_secret_key", WithClicks(clicks, clicks)).Routes()
+	send := func(method, target, body string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("User-Agent", "test")
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send(http.MethodPost, "/api/shorten", `{"url":"http://svirex.ru","custom_alias":"promo"}`, nil)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	owner := recorder.Result().Cookies()
+	recorder = send(http.MethodPost, "/api/shorten", `{"url":"http://ya.ru"}`, nil)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	stranger := recorder.Result().Cookies()
+
+	require.Equal(t, http.StatusTemporaryRedirect, send(http.MethodGet, "/promo", "", nil).Code)
+	require.Equal(t, http.StatusTemporaryRedirect, send(http.MethodGet, "/promo", "", nil).Code)
+	require.NoError(t, clicks.Shutdown())
+
+	require.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/api/user/urls/promo/stats", "", nil).Code)
+	require.Equal(t, http.StatusNotFound, send(http.MethodGet, "/api/user/urls/promo/stats", "", stranger).Code)
+	recorder = send(http.MethodGet, "/api/user/urls/promo/stats", "", owner)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var stats domain.LinkStats
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &stats))
+	require.Equal(t, int64(2), stats.TotalClicks)
+	require.Equal(t, int64(1), stats.UniqueVisitors)
+	require.Len(t, stats.Daily, 1)
+	require.Equal(t, int64(2), stats.Daily[0].Clicks)
+}
@@ -0,0 +1,192 @@
+// Пакет auth содержит общую логику JWT для HTTP и gRPC апи.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// DefaultKeyID - kid основного секретного ключа, им же проверяются токены без kid.
+const DefaultKeyID = "default"
+
+// Claims - данные для JWT
+type Claims struct {
+	jwt.RegisteredClaims
+	UserID string
+	// KeyID - kid ключа, которым подписан токен, заполняется при проверке.
+	KeyID string `json:"-"`
+}
+
+// KeyRing - ключи подписи JWT по kid. Новые токены подписываются текущим ключом,
+// остальные ключи только проверяют ранее выданные токены.
+type KeyRing struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyRing - новый набор ключей, keys - секреты по kid, current - kid ключа для подписи.
+func NewKeyRing(current string, keys map[string]string) (*KeyRing, error) {
+	ring := &KeyRing{
+		current: current,
+		keys:    make(map[string][]byte, len(keys)),
+	}
+	for kid, secret := range keys {
+		if kid == "" || secret == "" {
+			return nil, errors.New("new key ring: empty kid or secret")
+		}
+		ring.keys[kid] = []byte(secret)
+	}
+	if _, ok := ring.keys[current]; !ok {
+		return nil, fmt.Errorf("new key ring: unknown current kid %q", current)
+	}
+	return ring, nil
+}
+
+// ParseKeyRing - набор ключей из основного секрета (kid DefaultKeyID) и записей вида kid:secret.
+// Пустой current означает DefaultKeyID.
+func ParseKeyRing(secretKey string, entries []string, current string) (*KeyRing, error) {
+	keys := make(map[string]string, len(entries)+1)
+	if secretKey != "" {
+		keys[DefaultKeyID] = secretKey
+	}
+	for _, entry := range entries {
+		kid, secret, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("parse key ring: entry must be kid:secret")
+		}
+		keys[kid] = secret
+	}
+	if current == "" {
+		current = DefaultKeyID
+	}
+	return NewKeyRing(current, keys)
+}
+
+// Tokens - выпуск и проверка JWT пользователей.
+type Tokens struct {
+	ring    *KeyRing
+	ttl     time.Duration
+	refresh time.Duration
+}
+
+// NewTokens - ttl - срок действия токена, 0 - бессрочный; refresh - возраст токена,
+// после которого он перевыпускается при очередном запросе, 0 отключает продление.
+func NewTokens(ring *KeyRing, ttl, refresh time.Duration) *Tokens {
+	return &Tokens{
+		ring:    ring,
+		ttl:     ttl,
+		refresh: refresh,
+	}
+}
+
+// NewSecretTokens - бессрочные токены с одним ключом.
+func NewSecretTokens(secretKey string) *Tokens {
+	return &Tokens{
+		ring: &KeyRing{
+			current: DefaultKeyID,
+			keys:    map[string][]byte{DefaultKeyID: []byte(secretKey)},
+		},
+	}
+}
+
+// TTL - срок действия токена.
+func (t *Tokens) TTL() time.Duration {
+	return t.ttl
+}
+
+// Build - подписать текущим ключом JWT с uid пользователя.
+func (t *Tokens) Build(uid string) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(now),
+		},
+		UserID: uid,
+	}
+	if t.ttl > 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(t.ttl))
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = t.ring.current
+	tokenString, err := token.SignedString(t.ring.keys[t.ring.current])
+	if err != nil {
+		return "", fmt.Errorf("build jwt token: %w", err)
+	}
+	return tokenString, nil
+}
+
+// Parse - проверить подпись и срок действия JWT. Токен без kid проверяется ключом DefaultKeyID.
+// Если срок действия включен, то токен без exp не принимается.
+func (t *Tokens) Parse(token string) (*Claims, error) {
+	claims := &Claims{}
+	var options []jwt.ParserOption
+	if t.ttl > 0 {
+		options = append(options, jwt.WithExpirationRequired())
+	}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid := DefaultKeyID
+		if value, ok := token.Header["kid"]; ok {
+			kid, _ = value.(string)
+		}
+		key, ok := t.ring.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		claims.KeyID = kid
+		return key, nil
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("parse jwt: %w", err)
+	}
+	return claims, nil
+}
+
+// NeedsRefresh - токен нужно перевыпустить: он подписан не текущим ключом или старше периода продления.
+func (t *Tokens) NeedsRefresh(claims *Claims) bool {
+	if claims.KeyID != t.ring.current {
+		return true
+	}
+	if t.refresh <= 0 {
+		return false
+	}
+	return claims.IssuedAt == nil || time.Since(claims.IssuedAt.Time) >= t.refresh
+}
+
+// GetUserID - проверить JWT и получить из него uid пользователя.
+func (t *Tokens) GetUserID(token string) (string, error) {
+	claims, err := t.Parse(token)
+	if err != nil {
+		return "", fmt.Errorf("parse user id from jwt: %w", err)
+	}
+	return claims.UserID, nil
+}
+
+// NewUser - сгенерировать uid нового пользователя и JWT для него.
+func (t *Tokens) NewUser() (uid string, token string, err error) {
+	uid, err = GenerateUserID()
+	if err != nil {
+		return "", "", fmt.Errorf("GenerateUserID: %w", err)
+	}
+	token, err = t.Build(uid)
+	if err != nil {
+		return "", "", fmt.Errorf("build jwt: %w", err)
+	}
+	return uid, token, nil
+}
+
+// GenerateUserID - сгенерировать uid нового пользователя.
+func GenerateUserID() (string, error) {
+	v, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("generate uuid: %w", err)
+	}
+	return v.String(), nil
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRotation(t *testing.T) {
+	oldRing, err := ParseKeyRing("old_secret", nil, "")
+	require.NoError(t, err)
+	oldTokens := NewTokens(oldRing, time.Hour, 0)
+	uid, oldToken, err := oldTokens.NewUser()
+	require.NoError(t, err)
+
+	ring, err := ParseKeyRing("old_secret", []string{"k2:new_secret"}, "k2")
+	require.NoError(t, err)
+	tokens := NewTokens(ring, time.Hour, 0)
+	claims, err := tokens.Parse(oldToken)
+	require.NoError(t, err)
+	require.Equal(t, uid, claims.UserID)
+	require.Equal(t, DefaultKeyID, claims.KeyID)
+	require.True(t, tokens.NeedsRefresh(claims))
+
+	newToken, err := tokens.Build(uid)
+	require.NoError(t, err)
+	claims, err = tokens.Parse(newToken)
+	require.NoError(t, err)
+	require.Equal(t, "k2", claims.KeyID)
+	require.False(t, tokens.NeedsRefresh(claims))
+	_, err = oldTokens.Parse(newToken)
+	require.Error(t, err)
+
+	_, err = ParseKeyRing("secret", []string{"broken"}, "")
+	require.Error(t, err)
+	_, err = ParseKeyRing("secret", nil, "missing")
+	require.Error(t, err)
+}
+
+func TestExpiryAndRefresh(t *testing.T) {
+	ring, err := ParseKeyRing("secret", nil, "")
+	require.NoError(t, err)
+	tokens := NewTokens(ring, time.Hour, time.Minute)
+	sign := func(claims Claims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["kid"] = DefaultKeyID
+		signed, err := token.SignedString([]byte("secret"))
+		require.NoError(t, err)
+		return signed
+	}
+	now := time.Now()
+
+	_, err = tokens.Parse(sign(Claims{UserID: "user", RegisteredClaims: jwt.RegisteredClaims{
+		IssuedAt: jwt.NewNumericDate(now.Add(-2 * time.Hour)), ExpiresAt: jwt.NewNumericDate(now.Add(-time.Hour)),
+	}}))
+	require.ErrorIs(t, err, jwt.ErrTokenExpired)
+
+	claims, err := tokens.Parse(sign(Claims{UserID: "user", RegisteredClaims: jwt.RegisteredClaims{
+		IssuedAt: jwt.NewNumericDate(now.Add(-10 * time.Minute)), ExpiresAt: jwt.NewNumericDate(now.Add(50 * time.Minute)),
+	}}))
+	require.NoError(t, err)
+	require.True(t, tokens.NeedsRefresh(claims))
+
+	fresh, err := tokens.Build("user")
+	require.NoError(t, err)
+	claims, err = tokens.Parse(fresh)
+	require.NoError(t, err)
+	require.False(t, tokens.NeedsRefresh(claims))
+	require.WithinDuration(t, now.Add(time.Hour), claims.ExpiresAt.Time, 5*time.Second)
+
+	// при включенном сроке действия токены без exp не принимаются
+	_, err = tokens.Parse(sign(Claims{UserID: "user", RegisteredClaims: jwt.RegisteredClaims{
+		IssuedAt: jwt.NewNumericDate(now),
+	}}))
+	require.ErrorIs(t, err, jwt.ErrTokenRequiredClaimMissing)
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: "user"})
+	signed, err := legacy.SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = tokens.Parse(signed)
+	require.Error(t, err)
+	claims, err = NewTokens(ring, 0, 0).Parse(signed)
+	require.NoError(t, err)
+	require.Equal(t, "user", claims.UserID)
+}
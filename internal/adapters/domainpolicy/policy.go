@@ -0,0 +1,132 @@
+package domainpolicy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// Policy - правила доступа к доменам из файла.
+// Если хост совпал с правилом deny, то он запрещен. Если есть правила allow, то разрешены только совпавшие с ними.
+// Файл перечитывается по Reload и при изменении, при ошибке в файле остаются прежние правила.
+type Policy struct {
+	path     string
+	interval time.Duration
+	logger   ports.Logger
+	rules    atomic.Pointer[rules]
+	mutex    sync.Mutex
+	modTime  time.Time
+	size     int64
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+var _ ports.DomainPolicy = (*Policy)(nil)
+
+// NewPolicy - загрузить правила из файла path, interval - период проверки изменения файла.
+func NewPolicy(path string, interval time.Duration, logger ports.Logger) (*Policy, error) {
+	p := &Policy{
+		path:     path,
+		interval: interval,
+		logger:   logger,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Check - проверить хост, если он запрещен, то вернуть ошибку ErrForbiddenDomain.
+func (p *Policy) Check(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	rules := p.rules.Load()
+	for _, r := range rules.deny {
+		if r.match(host) {
+			return fmt.Errorf("domain %q denied by rule %q: %w", host, r.source, ports.ErrForbiddenDomain)
+		}
+	}
+	if len(rules.allow) == 0 {
+		return nil
+	}
+	for _, r := range rules.allow {
+		if r.match(host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("domain %q is not in allowlist: %w", host, ports.ErrForbiddenDomain)
+}
+
+// Reload - перечитать файл правил.
+func (p *Policy) Reload() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("domain policy, stat %s: %w", p.path, err)
+	}
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("domain policy, read %s: %w", p.path, err)
+	}
+	rules, err := parseRules(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("domain policy, parse %s: %w", p.path, err)
+	}
+	p.rules.Store(rules)
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	return nil
+}
+
+// Run - запуск проверки изменения файла.
+func (p *Policy) Run() error {
+	go p.watch()
+	return nil
+}
+
+// Shutdown - остановка проверки изменения файла.
+func (p *Policy) Shutdown() error {
+	close(p.done)
+	<-p.stopped
+	return nil
+}
+
+func (p *Policy) watch() {
+	defer close(p.stopped)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			if !p.changed() {
+				continue
+			}
+			if err := p.Reload(); err != nil {
+				p.logger.Errorln("domain policy, reload on change", "err", err)
+				continue
+			}
+			p.logger.Infoln("domain policy reloaded", "path", p.path)
+		}
+	}
+}
+
+func (p *Policy) changed() bool {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		p.logger.Errorln("domain policy, stat", "err", err)
+		return false
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return !info.ModTime().Equal(p.modTime) || info.Size() != p.size
+}
@@ -0,0 +1,91 @@
+package domainpolicy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.txt")
+	require.NoError(t, os.WriteFile(path, []byte(`
+# phishing
+deny evil.com
+deny *.phish.net
+deny regexp:^login-.*\.com$
+`), 0o644))
+	policy, err := NewPolicy(path, time.Hour, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{host: "evil.com", allowed: false},
+		{host: "EVIL.com.", allowed: false},
+		{host: "sub.evil.com", allowed: true},
+		{host: "a.phish.net", allowed: false},
+		{host: "a.b.phish.net", allowed: false},
+		{host: "phish.net", allowed: true},
+		{host: "login-bank.com", allowed: false},
+		{host: "ya.ru", allowed: true},
+	}
+	for _, test := range tests {
+		err := policy.Check(test.host)
+		if test.allowed {
+			require.NoError(t, err, test.host)
+		} else {
+			require.ErrorIs(t, err, ports.ErrForbiddenDomain, test.host)
+		}
+	}
+}
+
+func TestPolicyAllowlistAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.txt")
+	require.NoError(t, os.WriteFile(path, []byte("allow svirex.ru\nallow *.пример.рф\n"), 0o644))
+	policy, err := NewPolicy(path, 10*time.Millisecond, zap.NewNop().Sugar())
+	require.NoError(t, err)
+	require.NoError(t, policy.Check("svirex.ru"))
+	require.NoError(t, policy.Check("www.xn--e1afmkfd.xn--p1ai"))
+	require.ErrorIs(t, policy.Check("ya.ru"), ports.ErrForbiddenDomain)
+
+	require.NoError(t, policy.Run())
+	defer policy.Shutdown()
+
+	require.NoError(t, os.WriteFile(path, []byte("allow svirex.ru\nallow ya.ru\ndeny svirex.ru\n"), 0o644))
+	require.Eventually(t, func() bool {
+		return policy.Check("ya.ru") == nil
+	}, time.Second, 10*time.Millisecond)
+	require.ErrorIs(t, policy.Check("svirex.ru"), ports.ErrForbiddenDomain)
+
+	require.NoError(t, os.WriteFile(path, []byte("bogus line here\n"), 0o644))
+	require.Error(t, policy.Reload())
+	require.NoError(t, policy.Check("ya.ru"))
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	_, err := NewPolicy(filepath.Join(t.TempDir(), "missing.txt"), time.Hour, zap.NewNop().Sugar())
+	require.Error(t, err)
+	for _, text := range []string{"block ya.ru", "deny", "deny regexp:(", "deny a b"} {
+		path := filepath.Join(t.TempDir(), "policy.txt")
+		require.NoError(t, os.WriteFile(path, []byte(text), 0o644))
+		_, err := NewPolicy(path, time.Hour, zap.NewNop().Sugar())
+		require.Error(t, err, text)
+	}
+}
+
+func TestRegexpRuleMatchesWholeHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.txt")
+	require.NoError(t, os.WriteFile(path, []byte("allow regexp:svirex\\.ru\ndeny regexp:evil|phish\n"), 0o644))
+	policy, err := NewPolicy(path, time.Hour, zap.NewNop().Sugar())
+	require.NoError(t, err)
+	require.NoError(t, policy.Check("svirex.ru"))
+	require.ErrorIs(t, policy.Check("svirex.ru.evil.net"), ports.ErrForbiddenDomain)
+	require.ErrorIs(t, policy.Check("fake-svirex.ru"), ports.ErrForbiddenDomain)
+	require.ErrorIs(t, policy.Check("evilsvirex.ru"), ports.ErrForbiddenDomain)
+}
@@ -0,0 +1,108 @@
+package domainpolicy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	actionAllow = "allow"
+	actionDeny  = "deny"
+
+	wildcardPrefix = "*."
+	regexpPrefix   = "regexp:"
+)
+
+// rule - одно правило: точный хост, все поддомены (*.example.com) или регулярное выражение.
+type rule struct {
+	source string
+	exact  string
+	suffix string
+	re     *regexp.Regexp
+}
+
+func (r *rule) match(host string) bool {
+	switch {
+	case r.re != nil:
+		return r.re.MatchString(host)
+	case r.suffix != "":
+		return strings.HasSuffix(host, r.suffix)
+	default:
+		return host == r.exact
+	}
+}
+
+// rules - списки разрешающих и запрещающих правил.
+type rules struct {
+	allow []*rule
+	deny  []*rule
+}
+
+// parseRules - прочитать правила, по одному на строку: "allow <шаблон>" или "deny <шаблон>".
+// Пустые строки и строки, начинающиеся с #, пропускаются.
+func parseRules(reader io.Reader) (*rules, error) {
+	result := &rules{}
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"<allow|deny> <pattern>\", got %q", line, text)
+		}
+		r, err := parseRule(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch strings.ToLower(fields[0]) {
+		case actionAllow:
+			result.allow = append(result.allow, r)
+		case actionDeny:
+			result.deny = append(result.deny, r)
+		default:
+			return nil, fmt.Errorf("line %d: unknown action %q", line, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read rules: %w", err)
+	}
+	return result, nil
+}
+
+func parseRule(pattern string) (*rule, error) {
+	if expr, ok := strings.CutPrefix(pattern, regexpPrefix); ok {
+		re, err := regexp.Compile(`^(?:` + expr + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("compile %q: %w", expr, err)
+		}
+		return &rule{source: pattern, re: re}, nil
+	}
+	if domain, ok := strings.CutPrefix(pattern, wildcardPrefix); ok {
+		host, err := normalizeHost(domain)
+		if err != nil {
+			return nil, fmt.Errorf("wildcard %q: %w", pattern, err)
+		}
+		return &rule{source: pattern, suffix: "." + host}, nil
+	}
+	host, err := normalizeHost(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("host %q: %w", pattern, err)
+	}
+	return &rule{source: pattern, exact: host}, nil
+}
+
+// normalizeHost - хост в нижнем регистре и punycode, как его сохраняет сервис.
+func normalizeHost(host string) (string, error) {
+	host, err := idna.Lookup.ToASCII(strings.TrimSuffix(host, "."))
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(host), nil
+}
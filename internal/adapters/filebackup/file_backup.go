@@ -7,11 +7,15 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/Svirex/microurl/internal/core/ports"
 )
 
+// ErrSkippedRecords - при восстановлении часть записей не удалось применить, остальные данные восстановлены.
+var ErrSkippedRecords = errors.New("backup records skipped")
+
 // FileBackupReader - структура reader.
 type FileBackupReader struct {
 	reader *json.Decoder
@@ -44,22 +48,90 @@ func (reader *FileBackupReader) Read(ctx context.Context) (*domain.BackupRecord,
 	return nil, io.EOF
 }
 
-// Restore - восстановить данные из файла
-func (reader *FileBackupReader) Restore(ctx context.Context, repo ports.ShortenerRepository) error {
+// Restore - восстановить данные из файла. Записи-надгробия помечают ссылки удаленными через deleter,
+// записи изменения повторяют изменение ссылки, записи переходов восстанавливают статистику,
+// записи очистки повторяют удаление истекших и окончательно удаленных ссылок.
+// Записи добавления и изменения, которые не удалось применить, пропускаются, а в конце возвращается
+// ErrSkippedRecords с их числом и первой ошибкой.
+func (reader *FileBackupReader) Restore(ctx context.Context, repo ports.ShortenerRepository, deleter ports.DeleterRepository) error {
 	record, err := reader.Read(ctx)
 	if err != nil && !errors.Is(err, io.EOF) {
 		return fmt.Errorf("restore data, read: %w", err)
 	}
+	var skipped int
+	var skipErr error
+	skip := func(err error) {
+		skipped++
+		if skipErr == nil {
+			skipErr = err
+		}
+	}
 	for record != nil {
-		repo.Add(context.Background(), record.ShortID, &domain.Record{
-			UID: record.UID,
-			URL: record.URL,
-		})
+		switch {
+		case record.IsDeleted:
+			data := &domain.DeleteData{
+				UID:     string(record.UID),
+				ShortID: string(record.ShortID),
+			}
+			if record.DeletedAt != nil {
+				data.DeletedAt = *record.DeletedAt
+			}
+			err = deleter.Delete(ctx, []*domain.DeleteData{data})
+			if err != nil {
+				return fmt.Errorf("restore data, delete: %w", err)
+			}
+		case record.IsRestored:
+			_, err = deleter.Restore(ctx, record.UID, []domain.ShortID{record.ShortID}, time.Time{})
+			if err != nil {
+				return fmt.Errorf("restore data, undelete: %w", err)
+			}
+		case record.PurgedBefore != nil:
+			_, err = deleter.PurgeDeleted(ctx, *record.PurgedBefore)
+			if err != nil {
+				return fmt.Errorf("restore data, purge deleted: %w", err)
+			}
+		case record.ExpiredBefore != nil:
+			_, err = repo.PurgeExpired(ctx, *record.ExpiredBefore)
+			if err != nil {
+				return fmt.Errorf("restore data, purge expired: %w", err)
+			}
+		case record.Click != nil:
+			if clicks, ok := repo.(ports.ClickRepository); ok {
+				err = clicks.SaveClicks(ctx, []*domain.Click{record.Click})
+				if err != nil {
+					return fmt.Errorf("restore data, save click: %w", err)
+				}
+			}
+		case record.UpdatedAt != nil:
+			url := record.URL
+			_, err = repo.Update(ctx, record.UID, record.ShortID, &domain.URLUpdate{
+				URL:         &url,
+				ExpiresAt:   record.ExpiresAt,
+				ClearExpiry: record.ExpiresAt == nil,
+				UpdatedAt:   *record.UpdatedAt,
+			})
+			if err != nil {
+				skip(fmt.Errorf("update %q, record %s: %w", record.ShortID, record.UUID, err))
+			}
+		default:
+			_, err = repo.Add(ctx, record.ShortID, &domain.Record{
+				UID:       record.UID,
+				URL:       record.URL,
+				ExpiresAt: record.ExpiresAt,
+			})
+			// связь еще одного владельца с существующей записью возвращает ErrAlreadyExists
+			if err != nil && !errors.Is(err, ports.ErrAlreadyExists) {
+				skip(fmt.Errorf("add %q, record %s: %w", record.ShortID, record.UUID, err))
+			}
+		}
 		record, err = reader.Read(ctx)
 		if err != nil && !errors.Is(err, io.EOF) {
 			return fmt.Errorf("restore data, while read: %w", err)
 		}
 	}
+	if skipped > 0 {
+		return fmt.Errorf("restore data: %w: %d, first: %w", ErrSkippedRecords, skipped, skipErr)
+	}
 	return nil
 }
 
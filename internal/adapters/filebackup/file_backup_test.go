@@ -8,8 +8,11 @@ import (
 	"io"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
 	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
 	"github.com/stretchr/testify/require"
 )
 
@@ -111,3 +114,63 @@ func TestWriteGood(t *testing.T) {
 	expected := []byte(string(d) + "\n")
 	require.Equal(t, expected, data)
 }
+
+func TestRestoreWithTombstone(t *testing.T) {
+	file, err := os.CreateTemp("", "test-file-backup-")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	writer := NewFileBackupWriter(file)
+	records := []*domain.BackupRecord{
+		{UUID: "1", ShortID: "42fasfasf", URL: "http://svirex.ru", UID: "uid"},
+		{UUID: "2", ShortID: "qwerty", URL: "http://ya.ru", UID: "uid"},
+		{UUID: "3", ShortID: "42fasfasf", UID: "uid", IsDeleted: true},
+	}
+	for _, record := range records {
+		require.NoError(t, writer.Write(context.Background(), record))
+	}
+	file.Seek(0, 0)
+
+	repo := inmemory.NewShortenerRepository()
+	reader := NewFileBackupReader(file)
+	err = reader.Restore(context.Background(), repo, inmemory.NewDeleterRepository(repo))
+	require.NoError(t, err)
+
+	_, err = repo.Get(context.Background(), "42fasfasf")
+	require.ErrorIs(t, err, ports.ErrDeleted)
+	url, err := repo.Get(context.Background(), "qwerty")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://ya.ru"), url)
+}
+
+func TestRestoreReportsSkippedRecords(t *testing.T) {
+	file, err := os.CreateTemp("", "test-file-backup-")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	writer := NewFileBackupWriter(file)
+	updatedAt := time.Now().UTC()
+	records := []*domain.BackupRecord{
+		{UUID: "1", ShortID: "promo", URL: "http://svirex.ru", UID: "alice"},
+		{UUID: "2", ShortID: "promo", URL: "http://svirex.ru", UID: "bob"},
+		{UUID: "3", ShortID: "promo", URL: "http://ya.ru", UID: "carol"},
+		{UUID: "4", ShortID: "missing", URL: "http://ya.ru", UID: "alice", UpdatedAt: &updatedAt},
+	}
+	for _, record := range records {
+		require.NoError(t, writer.Write(context.Background(), record))
+	}
+	file.Seek(0, 0)
+
+	repo := inmemory.NewShortenerRepository()
+	err = NewFileBackupReader(file).Restore(context.Background(), repo, inmemory.NewDeleterRepository(repo))
+	require.ErrorIs(t, err, ErrSkippedRecords)
+	require.ErrorIs(t, err, ports.ErrShortIDExists)
+	require.Contains(t, err.Error(), ": 2,")
+
+	url, err := repo.Get(context.Background(), "promo")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://svirex.ru"), url)
+	require.True(t, repo.IsOwner("promo", "bob"))
+}
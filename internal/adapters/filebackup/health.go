@@ -0,0 +1,35 @@
+package filebackup
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// WritableCheck - проверка, что в файл бэкапа можно писать.
+type WritableCheck struct {
+	path string
+}
+
+var _ ports.HealthChecker = (*WritableCheck)(nil)
+
+// NewWritableCheck - новая проверка файла path.
+func NewWritableCheck(path string) *WritableCheck {
+	return &WritableCheck{path: path}
+}
+
+// Name - имя проверки.
+func (c *WritableCheck) Name() string {
+	return "backup_file"
+}
+
+// Check - открываем файл на дозапись, ничего не записывая.
+func (c *WritableCheck) Check(_ context.Context) error {
+	f, err := os.OpenFile(c.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("backup file is not writable: %w", err)
+	}
+	return f.Close()
+}
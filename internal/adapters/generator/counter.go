@@ -0,0 +1,109 @@
+package generator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+const base62 = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// maxCounterIDSize - максимальная длина идентификатора, при которой 62^size помещается в uint64
+const maxCounterIDSize = 10
+
+// feistelRounds - число раундов сети Фейстеля
+const feistelRounds = 8
+
+// ErrCounterExhausted - значения счетчика закончились для заданной длины идентификатора.
+var ErrCounterExhausted = errors.New("counter exhausted for short id size")
+
+// CounterGenerator - генератор идентификаторов на основе монотонного счетчика.
+// Значение счетчика перемешивается ключевой перестановкой (сеть Фейстеля с cycle-walking)
+// в пределах 62^size и кодируется в base62, поэтому идентификаторы уникальны и их нельзя угадать по соседним.
+type CounterGenerator struct {
+	counter ports.Counter
+	key     []byte
+}
+
+var _ ports.StringGenerator = (*CounterGenerator)(nil)
+
+// NewCounterGenerator - новый генератор, key - секретный ключ перестановки.
+func NewCounterGenerator(counter ports.Counter, key string) *CounterGenerator {
+	return &CounterGenerator{
+		counter: counter,
+		key:     []byte(key),
+	}
+}
+
+// Generate - следующий идентификатор длины size
+func (g *CounterGenerator) Generate(ctx context.Context, size uint) (string, error) {
+	if size == 0 || size > maxCounterIDSize {
+		return "", fmt.Errorf("counter generator, unsupported size %d", size)
+	}
+	value, err := g.counter.Next(ctx)
+	if err != nil {
+		return "", fmt.Errorf("counter generator, next: %w", err)
+	}
+	domain := pow62(size)
+	if value >= domain {
+		return "", fmt.Errorf("counter generator, value %d: %w", value, ErrCounterExhausted)
+	}
+	return encodeBase62(g.permute(value, domain), size), nil
+}
+
+// permute - биекция на [0, domain): сеть Фейстеля на ближайшем четном числе бит,
+// результаты вне диапазона повторно прогоняются через сеть.
+func (g *CounterGenerator) permute(value, domain uint64) uint64 {
+	width := bits.Len64(domain - 1)
+	if width%2 == 1 {
+		width++
+	}
+	half := uint(width / 2)
+	for {
+		value = g.feistel(value, half)
+		if value < domain {
+			return value
+		}
+	}
+}
+
+func (g *CounterGenerator) feistel(value uint64, half uint) uint64 {
+	mask := uint64(1)<<half - 1
+	left, right := value>>half&mask, value&mask
+	for round := 0; round < feistelRounds; round++ {
+		left, right = right, left^(g.round(round, right)&mask)
+	}
+	return left<<half | right
+}
+
+func (g *CounterGenerator) round(round int, value uint64) uint64 {
+	var buf [9]byte
+	buf[0] = byte(round)
+	binary.BigEndian.PutUint64(buf[1:], value)
+	mac := hmac.New(sha256.New, g.key)
+	mac.Write(buf[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+func pow62(size uint) uint64 {
+	result := uint64(1)
+	for i := uint(0); i < size; i++ {
+		result *= uint64(len(base62))
+	}
+	return result
+}
+
+func encodeBase62(value uint64, size uint) string {
+	result := make([]byte, size)
+	for i := int(size) - 1; i >= 0; i-- {
+		result[i] = base62[value%uint64(len(base62))]
+		value /= uint64(len(base62))
+	}
+	return string(result)
+}
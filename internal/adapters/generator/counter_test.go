@@ -0,0 +1,48 @@
+package generator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermuteIsBijection(t *testing.T) {
+	gen := NewCounterGenerator(nil, "key")
+	domain := pow62(2)
+	seen := make(map[uint64]struct{}, domain)
+	for value := uint64(0); value < domain; value++ {
+		permuted := gen.permute(value, domain)
+		require.Less(t, permuted, domain)
+		seen[permuted] = struct{}{}
+	}
+	require.Len(t, seen, int(domain))
+}
+
+func TestCounterGenerate(t *testing.T) {
+	gen := NewCounterGenerator(inmemory.NewCounter(), "key")
+	first, err := gen.Generate(context.Background(), 8)
+	require.NoError(t, err)
+	second, err := gen.Generate(context.Background(), 8)
+	require.NoError(t, err)
+	require.Len(t, first, 8)
+	require.NotEqual(t, first, second)
+
+	other, err := NewCounterGenerator(inmemory.NewCounter(), "other key").Generate(context.Background(), 8)
+	require.NoError(t, err)
+	require.NotEqual(t, first, other)
+
+	_, err = gen.Generate(context.Background(), maxCounterIDSize+1)
+	require.Error(t, err)
+}
+
+func TestCounterExhausted(t *testing.T) {
+	gen := NewCounterGenerator(inmemory.NewCounter(), "key")
+	for i := 0; i < 61; i++ {
+		_, err := gen.Generate(context.Background(), 1)
+		require.NoError(t, err)
+	}
+	_, err := gen.Generate(context.Background(), 1)
+	require.ErrorIs(t, err, ErrCounterExhausted)
+}
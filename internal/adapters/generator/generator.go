@@ -2,32 +2,44 @@ package generator
 
 import (
 	"context"
-	"math/rand"
+	"crypto/rand"
+	"fmt"
 
 	"github.com/Svirex/microurl/internal/core/ports"
 )
 
 const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
-// StringGenerator - генератор строк
-type StringGenerator struct {
-	rand *rand.Rand
-}
+// maxByte - байты не меньше этого значения отбрасываются, чтобы все символы были равновероятны
+const maxByte = 256 - 256%len(letters)
+
+// StringGenerator - генератор строк на основе crypto/rand, безопасен для конкурентного использования
+type StringGenerator struct{}
 
 var _ ports.StringGenerator = (*StringGenerator)(nil)
 
-// Generate - создать рандомную последовательность символов определенной длины
-func (g *StringGenerator) Generate(ctx context.Context, size uint) string {
-	b := make([]byte, size)
-	for i := range b {
-		b[i] = letters[g.rand.Intn(len(letters))]
+// Generate - создать случайную последовательность символов определенной длины
+func (g *StringGenerator) Generate(_ context.Context, size uint) (string, error) {
+	result := make([]byte, 0, size)
+	buf := make([]byte, size+size/4+1)
+	for uint(len(result)) < size {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("generate string, read random bytes: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) >= maxByte {
+				continue
+			}
+			result = append(result, letters[int(b)%len(letters)])
+			if uint(len(result)) == size {
+				break
+			}
+		}
 	}
-	return string(b)
+	return string(result), nil
 }
 
 // NewStringGenerator - новый генератор
-func NewStringGenerator(seed int64) *StringGenerator {
-	return &StringGenerator{
-		rand: rand.New(rand.NewSource(seed)),
-	}
+func NewStringGenerator() *StringGenerator {
+	return &StringGenerator{}
 }
@@ -0,0 +1,24 @@
+package generator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	gen := NewStringGenerator()
+	seen := make(map[string]struct{})
+	for i := 0; i < 1000; i++ {
+		s, err := gen.Generate(context.Background(), 8)
+		require.NoError(t, err)
+		require.Len(t, s, 8)
+		for _, r := range s {
+			require.True(t, strings.ContainsRune(letters, r))
+		}
+		seen[s] = struct{}{}
+	}
+	require.Len(t, seen, 1000)
+}
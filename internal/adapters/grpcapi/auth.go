@@ -0,0 +1,151 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	pb "github.com/Svirex/microurl/internal/adapters/grpcapi/proto"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authMetadataKey - ключ метаданных с JWT пользователя.
+const authMetadataKey = "authorization"
+
+type uidKey struct{}
+
+type authPolicy int
+
+const (
+	authNone     authPolicy = iota // пользователь не нужен
+	authOptional                   // если токена нет, то создаем нового пользователя
+	authRequired                   // нужен валидный токен
+)
+
+var methodAuthPolicy = map[string]authPolicy{
+	pb.Shortener_Shorten_FullMethodName:      authOptional,
+	pb.Shortener_BatchShorten_FullMethodName: authOptional,
+	pb.Shortener_Resolve_FullMethodName:      authNone,
+	pb.Shortener_ListUserURLs_FullMethodName: authRequired,
+	pb.Shortener_DeleteURLs_FullMethodName:   authRequired,
+}
+
+// methodScope - право API ключа, нужное для вызова метода. Методы без права по API ключу недоступны.
+var methodScope = map[string]domain.Scope{
+	pb.Shortener_Shorten_FullMethodName:      domain.ScopeShorten,
+	pb.Shortener_BatchShorten_FullMethodName: domain.ScopeShorten,
+	pb.Shortener_ListUserURLs_FullMethodName: domain.ScopeRead,
+	pb.Shortener_DeleteURLs_FullMethodName:   domain.ScopeDelete,
+}
+
+var errNoToken = errors.New("no auth token in metadata")
+
+func (api *API) authInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	policy, ok := methodAuthPolicy[info.FullMethod]
+	if !ok {
+		policy = authRequired
+	}
+	if policy == authNone {
+		return handler(ctx, req)
+	}
+	if token, ok := api.apiKeyToken(ctx); ok {
+		return api.apiKeyAuth(ctx, token, req, info, handler)
+	}
+	uid, err := api.userFromMetadata(ctx)
+	if err != nil {
+		if policy == authRequired {
+			api.logger.Errorln("grpc auth interceptor, ", info.FullMethod, err)
+			return nil, status.Error(codes.Unauthenticated, "valid auth token required")
+		}
+		var token string
+		uid, token, err = api.tokens.NewUser()
+		if err != nil {
+			api.logger.Errorln("grpc auth interceptor, couldn't create user: ", err)
+			return nil, status.Error(codes.Internal, "couldn't create user")
+		}
+		api.sendToken(ctx, token)
+	}
+	return handler(context.WithValue(ctx, uidKey{}, uid), req)
+}
+
+// apiKeyAuth - вызов от имени владельца API ключа. Для неверного ключа новый пользователь не создается,
+// а вызов метода, на который у ключа нет права, отклоняется с PermissionDenied.
+func (api *API) apiKeyAuth(ctx context.Context, token string, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	key, err := api.apiKeys.Authenticate(ctx, token)
+	if err != nil {
+		api.logger.Infoln("grpc auth interceptor, api key not valid", info.FullMethod, err)
+		return nil, status.Error(codes.Unauthenticated, "valid api key required")
+	}
+	scope, ok := methodScope[info.FullMethod]
+	if !ok || !key.HasScope(scope) {
+		return nil, status.Error(codes.PermissionDenied, "api key has no scope for method")
+	}
+	return handler(context.WithValue(ctx, uidKey{}, string(key.UID)), req)
+}
+
+// apiKeyToken - токен API ключа из метаданных запроса, если API ключи включены.
+func (api *API) apiKeyToken(ctx context.Context) (string, bool) {
+	if api.apiKeys == nil {
+		return "", false
+	}
+	token, err := bearerToken(ctx)
+	if err != nil || !strings.HasPrefix(token, domain.APIKeyPrefix) {
+		return "", false
+	}
+	return token, true
+}
+
+// bearerToken - токен из метаданных authorization.
+func bearerToken(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", errNoToken
+	}
+	values := md.Get(authMetadataKey)
+	if len(values) == 0 {
+		return "", errNoToken
+	}
+	return strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer ")), nil
+}
+
+// sendToken - передать клиенту новый JWT в заголовке ответа.
+func (api *API) sendToken(ctx context.Context, token string) {
+	err := grpc.SetHeader(ctx, metadata.Pairs(authMetadataKey, "Bearer "+token))
+	if err != nil {
+		api.logger.Errorln("grpc auth interceptor, set header: ", err)
+	}
+}
+
+// userFromMetadata - uid из JWT в метаданных запроса. Старый или подписанный не текущим ключом
+// токен перевыпускается и отправляется клиенту в заголовке ответа.
+func (api *API) userFromMetadata(ctx context.Context) (string, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	claims, err := api.tokens.Parse(token)
+	if err != nil {
+		return "", err
+	}
+	if claims.UserID == "" {
+		return "", errNoToken
+	}
+	if api.tokens.NeedsRefresh(claims) {
+		token, err = api.tokens.Build(claims.UserID)
+		if err != nil {
+			api.logger.Errorln("grpc auth interceptor, couldn't refresh jwt: ", err)
+		} else {
+			api.sendToken(ctx, token)
+		}
+	}
+	return claims.UserID, nil
+}
+
+func uidFromContext(ctx context.Context) string {
+	uid, _ := ctx.Value(uidKey{}).(string)
+	return uid
+}
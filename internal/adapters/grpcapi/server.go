@@ -0,0 +1,213 @@
+// Пакет grpcapi - gRPC апи сервиса сокращения ссылок, повторяющее HTTP апи.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Svirex/microurl/internal/adapters/auth"
+	pb "github.com/Svirex/microurl/internal/adapters/grpcapi/proto"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// deletionJobMetadataKey - ключ метаданных ответа с ID задачи удаления.
+const deletionJobMetadataKey = "deletion-job-id"
+
+// API - реализация gRPC сервиса.
+type API struct {
+	pb.UnimplementedShortenerServer
+	shortener ports.ShortenerService
+	deleter   ports.DeleterService
+	logger    ports.Logger
+	tokens    *auth.Tokens
+	apiKeys   ports.APIKeyService
+}
+
+// Option - дополнительный параметр апи.
+type Option func(*API)
+
+// WithTokens - выпуск и проверка JWT с набором ключей, сроком действия и продлением.
+func WithTokens(tokens *auth.Tokens) Option {
+	return func(api *API) {
+		api.tokens = tokens
+	}
+}
+
+// WithAPIKeys - аутентификация по API ключам mu_... в метаданных authorization с проверкой прав ключа.
+func WithAPIKeys(keys ports.APIKeyService) Option {
+	return func(api *API) {
+		api.apiKeys = keys
+	}
+}
+
+// NewAPI - создание нового апи.
+func NewAPI(
+	shortener ports.ShortenerService,
+	deleter ports.DeleterService,
+	logger ports.Logger,
+	secretKey string,
+	opts ...Option,
+) *API {
+	api := &API{
+		shortener: shortener,
+		deleter:   deleter,
+		logger:    logger,
+		tokens:    auth.NewSecretTokens(secretKey),
+	}
+	for _, opt := range opts {
+		opt(api)
+	}
+	return api
+}
+
+var _ pb.ShortenerServer = (*API)(nil)
+
+// NewServer - создание gRPC сервера с зарегистрированным апи.
+func NewServer(api *API) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(api.authInterceptor))
+	pb.RegisterShortenerServer(server, api)
+	return server
+}
+
+// Shorten - сократить ссылку.
+func (api *API) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	if req.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty url")
+	}
+	shortURL, err := api.shortener.Add(ctx, &domain.Record{
+		UID:         domain.UID(uidFromContext(ctx)),
+		URL:         domain.URL(req.GetUrl()),
+		CustomAlias: domain.ShortID(req.GetCustomAlias()),
+		ExpiresAt:   fromTimestamp(req.GetExpiresAt()),
+		TTL:         req.GetTtl(),
+	})
+	if err != nil {
+		if errors.Is(err, ports.ErrAlreadyExists) {
+			return &pb.ShortenResponse{
+				ShortUrl:      string(shortURL),
+				AlreadyExists: true,
+			}, nil
+		}
+		return nil, api.toStatus("shorten", err)
+	}
+	return &pb.ShortenResponse{
+		ShortUrl: string(shortURL),
+	}, nil
+}
+
+// BatchShorten - сократить несколько ссылок.
+func (api *API) BatchShorten(ctx context.Context, req *pb.BatchShortenRequest) (*pb.BatchShortenResponse, error) {
+	batch := make([]domain.BatchRecord, 0, len(req.GetItems()))
+	for _, item := range req.GetItems() {
+		batch = append(batch, domain.BatchRecord{
+			CorrID:      item.GetCorrelationId(),
+			URL:         domain.URL(item.GetOriginalUrl()),
+			CustomAlias: domain.ShortID(item.GetCustomAlias()),
+			ExpiresAt:   fromTimestamp(item.GetExpiresAt()),
+			TTL:         item.GetTtl(),
+		})
+	}
+	resp := &pb.BatchShortenResponse{
+		Items: make([]*pb.BatchShortenResponse_Item, 0, len(batch)),
+	}
+	if len(batch) == 0 {
+		return resp, nil
+	}
+	batch, err := api.shortener.Batch(ctx, domain.UID(uidFromContext(ctx)), batch)
+	if err != nil {
+		return nil, api.toStatus("batch shorten", err)
+	}
+	for i := range batch {
+		resp.Items = append(resp.Items, &pb.BatchShortenResponse_Item{
+			CorrelationId: batch[i].CorrID,
+			ShortUrl:      string(batch[i].ShortURL),
+			Existing:      batch[i].Status == domain.BatchStatusExisting,
+		})
+	}
+	return resp, nil
+}
+
+// Resolve - получить оригинальную ссылку.
+func (api *API) Resolve(ctx context.Context, req *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+	url, err := api.shortener.Get(ctx, domain.ShortID(req.GetShortId()))
+	if err != nil {
+		return nil, api.toStatus("resolve", err)
+	}
+	return &pb.ResolveResponse{
+		OriginalUrl: string(url),
+	}, nil
+}
+
+// ListUserURLs - получить все ссылки пользователя.
+func (api *API) ListUserURLs(ctx context.Context, _ *emptypb.Empty) (*pb.ListUserURLsResponse, error) {
+	urls, err := api.shortener.UserURLs(ctx, domain.UID(uidFromContext(ctx)))
+	if err != nil {
+		return nil, api.toStatus("list user urls", err)
+	}
+	resp := &pb.ListUserURLsResponse{
+		Urls: make([]*pb.URLData, 0, len(urls)),
+	}
+	for i := range urls {
+		data := &pb.URLData{
+			ShortUrl:    string(urls[i].ShortURL),
+			OriginalUrl: string(urls[i].URL),
+		}
+		if urls[i].ExpiresAt != nil {
+			data.ExpiresAt = timestamppb.New(*urls[i].ExpiresAt)
+		}
+		resp.Urls = append(resp.Urls, data)
+	}
+	return resp, nil
+}
+
+// DeleteURLs - асинхронно удалить ссылки пользователя. ID задачи удаления передается
+// в заголовке ответа deletion-job-id, ее состояние доступно в GET /api/user/deletions/{id}.
+func (api *API) DeleteURLs(ctx context.Context, req *pb.DeleteURLsRequest) (*emptypb.Empty, error) {
+	if len(req.GetShortIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "empty short ids")
+	}
+	jobID, err := api.deleter.Process(ctx, uidFromContext(ctx), req.GetShortIds())
+	if err != nil {
+		api.logger.Errorln("grpc delete urls: ", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	err = grpc.SetHeader(ctx, metadata.Pairs(deletionJobMetadataKey, jobID))
+	if err != nil {
+		api.logger.Errorln("grpc delete urls, set header: ", err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// toStatus - преобразовать ошибку сервиса в статус gRPC.
+func (api *API) toStatus(method string, err error) error {
+	switch {
+	case errors.Is(err, ports.ErrInvalidURL), errors.Is(err, ports.ErrInvalidAlias), errors.Is(err, ports.ErrInvalidExpiry):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ports.ErrForbiddenDomain):
+		return status.Error(codes.PermissionDenied, ports.ErrForbiddenDomain.Error())
+	case errors.Is(err, ports.ErrAliasAlreadyExists):
+		return status.Error(codes.AlreadyExists, ports.ErrAliasAlreadyExists.Error())
+	case errors.Is(err, ports.ErrNotFound):
+		return status.Error(codes.NotFound, ports.ErrNotFound.Error())
+	case errors.Is(err, ports.ErrDeleted), errors.Is(err, ports.ErrExpired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	api.logger.Errorln("grpc api, ", method, ": ", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+func fromTimestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
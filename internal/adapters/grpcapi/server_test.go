@@ -0,0 +1,119 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	pb "github.com/Svirex/microurl/internal/adapters/grpcapi/proto"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/service"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func newTestClient(t *testing.T, opts ...Option) pb.ShortenerClient {
+	repo := inmemory.NewShortenerRepository()
+	shortener := service.NewShortenerService(generator.NewStringGenerator(), repo, 8, 5, "http://localhost:8080")
+	server := NewServer(NewAPI(shortener, nil, zap.NewNop().Sugar(), "fake_secret_key", opts...))
+
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewShortenerClient(conn)
+}
+
+func TestShortenAndResolve(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	var header metadata.MD
+	resp, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "http://svirex.ru", CustomAlias: "svirex"}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:8080/svirex", resp.GetShortUrl())
+	require.False(t, resp.GetAlreadyExists())
+	require.Len(t, header.Get(authMetadataKey), 1)
+
+	authCtx := metadata.AppendToOutgoingContext(ctx, authMetadataKey, header.Get(authMetadataKey)[0])
+	resp, err = client.Shorten(authCtx, &pb.ShortenRequest{Url: "http://svirex.ru"})
+	require.NoError(t, err)
+	require.True(t, resp.GetAlreadyExists())
+
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "http://ya.ru", CustomAlias: "svirex"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "http://ya.ru", CustomAlias: "a"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resolved, err := client.Resolve(ctx, &pb.ResolveRequest{ShortId: "svirex"})
+	require.NoError(t, err)
+	require.Equal(t, "http://svirex.ru", resolved.GetOriginalUrl())
+
+	_, err = client.Resolve(ctx, &pb.ResolveRequest{ShortId: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	urls, err := client.ListUserURLs(authCtx, &emptypb.Empty{})
+	require.NoError(t, err)
+	require.Len(t, urls.GetUrls(), 1)
+	require.Equal(t, "http://svirex.ru", urls.GetUrls()[0].GetOriginalUrl())
+}
+
+func TestUserMethodsRequireToken(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.ListUserURLs(ctx, &emptypb.Empty{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	badCtx := metadata.AppendToOutgoingContext(ctx, authMetadataKey, "Bearer invalid")
+	_, err = client.DeleteURLs(badCtx, &pb.DeleteURLsRequest{ShortIds: []string{"svirex"}})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	ctx := context.Background()
+	keys := service.NewAPIKeyService(inmemory.NewAPIKeyRepository())
+	_, readToken, err := keys.Issue(ctx, "alice", "read", []domain.Scope{domain.ScopeRead})
+	require.NoError(t, err)
+	_, shortenToken, err := keys.Issue(ctx, "alice", "shorten", []domain.Scope{domain.ScopeShorten})
+	require.NoError(t, err)
+	client := newTestClient(t, WithAPIKeys(keys))
+	withKey := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, authMetadataKey, "Bearer "+token)
+	}
+
+	var header metadata.MD
+	_, err = client.Shorten(withKey(shortenToken), &pb.ShortenRequest{Url: "http://svirex.ru"}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Empty(t, header.Get(authMetadataKey))
+
+	urls, err := client.ListUserURLs(withKey(readToken), &emptypb.Empty{})
+	require.NoError(t, err)
+	require.Len(t, urls.GetUrls(), 1)
+
+	_, err = client.Shorten(withKey(readToken), &pb.ShortenRequest{Url: "http://ya.ru"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.DeleteURLs(withKey(shortenToken), &pb.DeleteURLsRequest{ShortIds: []string{"svirex"}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Shorten(withKey(domain.APIKeyPrefix+"bogus.secret"), &pb.ShortenRequest{Url: "http://ya.ru"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
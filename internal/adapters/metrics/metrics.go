@@ -0,0 +1,161 @@
+// Пакет metrics - метрики сервиса в формате Prometheus.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "microurl"
+
+// ShortenerStats - счетчики сервиса сокращения ссылок.
+type ShortenerStats interface {
+	ShortensCreated() int64
+	ShortensConflicted() int64
+}
+
+// DeleterStats - состояние сервиса удаления ссылок.
+type DeleterStats interface {
+	QueueDepth() int64
+	FailedBatches() int64
+	DeadLetters() int64
+}
+
+// TrashStats - счетчики восстановления и окончательного удаления ссылок.
+type TrashStats interface {
+	Restored() int64
+	PurgedRecords() int64
+	PurgedOwners() int64
+}
+
+// Metrics - реестр метрик сервиса.
+type Metrics struct {
+	registry  *prometheus.Registry
+	requests  *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	redirects prometheus.Counter
+}
+
+// New - новый реестр с метриками HTTP, рантайма Go и информацией о сборке.
+func New(buildVersion, buildCommit string) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		redirects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Number of redirects to original URLs.",
+		}),
+	}
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "build_info",
+		Help:        "Build information.",
+		ConstLabels: prometheus.Labels{"version": buildVersion, "commit": buildCommit},
+	})
+	buildInfo.Set(1)
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		m.redirects,
+		buildInfo,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler - обработчик для сбора метрик.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest - учесть обработанный HTTP запрос.
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	m.requests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	m.duration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// Redirect - учесть редирект на оригинальный URL.
+func (m *Metrics) Redirect() {
+	m.redirects.Inc()
+}
+
+// RegisterShortener - метрики созданных ссылок и конфликтов.
+func (m *Metrics) RegisterShortener(stats ShortenerStats) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shortens_created_total",
+			Help:      "Number of created short URLs.",
+		}, func() float64 { return float64(stats.ShortensCreated()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shortens_conflicted_total",
+			Help:      "Number of shorten requests and batch items for already existing URLs or aliases.",
+		}, func() float64 { return float64(stats.ShortensConflicted()) }),
+	)
+}
+
+// RegisterDeleter - метрики очереди удаления.
+func (m *Metrics) RegisterDeleter(stats DeleterStats) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "deleter_queue_depth",
+			Help:      "Number of short URLs waiting for deletion.",
+		}, func() float64 { return float64(stats.QueueDepth()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "deleter_batch_failures_total",
+			Help:      "Number of failed deletion batch writes.",
+		}, func() float64 { return float64(stats.FailedBatches()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "deleter_dead_letters_total",
+			Help:      "Number of short URLs moved to dead letters after all deletion attempts failed.",
+		}, func() float64 { return float64(stats.DeadLetters()) }),
+	)
+}
+
+// RegisterTrash - метрики восстановления и окончательного удаления ссылок.
+func (m *Metrics) RegisterTrash(stats TrashStats) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "restored_urls_total",
+			Help:      "Number of short URLs restored after deletion.",
+		}, func() float64 { return float64(stats.Restored()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "purged_records_total",
+			Help:      "Number of deleted short URLs removed after the grace period.",
+		}, func() float64 { return float64(stats.PurgedRecords()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "purged_owners_total",
+			Help:      "Number of deleted ownerships removed after the grace period.",
+		}, func() float64 { return float64(stats.PurgedOwners()) }),
+	)
+}
+
+// RegisterDBPool - статистика пула соединений с БД.
+func (m *Metrics) RegisterDBPool(db *pgxpool.Pool) {
+	m.registry.MustRegister(newPoolCollector(db))
+}
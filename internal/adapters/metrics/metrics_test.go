@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStats struct{}
+
+func (fakeStats) ShortensCreated() int64    { return 3 }
+func (fakeStats) ShortensConflicted() int64 { return 1 }
+func (fakeStats) QueueDepth() int64         { return 7 }
+func (fakeStats) FailedBatches() int64      { return 2 }
+func (fakeStats) DeadLetters() int64        { return 8 }
+func (fakeStats) Restored() int64           { return 4 }
+func (fakeStats) PurgedRecords() int64      { return 5 }
+func (fakeStats) PurgedOwners() int64       { return 6 }
+
+func TestHandler(t *testing.T) {
+	m := New("v1.0.0", "abc")
+	m.RegisterShortener(fakeStats{})
+	m.RegisterDeleter(fakeStats{})
+	m.RegisterTrash(fakeStats{})
+	m.ObserveRequest("/{shortID}", http.MethodGet, http.StatusTemporaryRedirect, 10*time.Millisecond)
+	m.Redirect()
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	for _, line := range []string{
+		`microurl_http_requests_total{method="GET",route="/{shortID}",status="307"} 1`,
+		`microurl_redirects_total 1`,
+		`microurl_shortens_created_total 3`,
+		`microurl_shortens_conflicted_total 1`,
+		`microurl_deleter_queue_depth 7`,
+		`microurl_deleter_batch_failures_total 2`,
+		`microurl_deleter_dead_letters_total 8`,
+		`microurl_restored_urls_total 4`,
+		`microurl_purged_records_total 5`,
+		`microurl_purged_owners_total 6`,
+		`microurl_build_info{commit="abc",version="v1.0.0"} 1`,
+	} {
+		require.Contains(t, string(body), line)
+	}
+}
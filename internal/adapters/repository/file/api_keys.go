@@ -0,0 +1,129 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// APIKeyRepository - API ключи в памяти с сохранением в файл, по одному ключу в JSON строке.
+// Файл перезаписывается атомарно через временный файл после каждого изменения.
+// Ключи хранятся отдельно от файла бэкапа: тот только дописывается и доступен всем, кто читает записи,
+// а файл ключей создается с правами 0600 и после отзыва ключа не содержит его прежнего состояния.
+type APIKeyRepository struct {
+	keys  *inmemory.APIKeyRepository
+	path  string
+	mutex sync.Mutex
+}
+
+var _ ports.APIKeyRepository = (*APIKeyRepository)(nil)
+
+// NewAPIKeyRepository - новое хранилище, ключи восстанавливаются из файла.
+func NewAPIKeyRepository(path string) (*APIKeyRepository, error) {
+	r := &APIKeyRepository{
+		keys: inmemory.NewAPIKeyRepository(),
+		path: path,
+	}
+	err := r.restore()
+	if err != nil {
+		return nil, fmt.Errorf("file api keys, new: %w", err)
+	}
+	return r, nil
+}
+
+// Add - сохранить ключ.
+func (r *APIKeyRepository) Add(ctx context.Context, key *domain.APIKey) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	err := r.keys.Add(ctx, key)
+	if err != nil {
+		return fmt.Errorf("file api keys, add: %w", err)
+	}
+	err = r.write()
+	if err != nil {
+		return fmt.Errorf("file api keys, add: %w", err)
+	}
+	return nil
+}
+
+// Get - получить ключ по ID.
+func (r *APIKeyRepository) Get(ctx context.Context, id string) (*domain.APIKey, error) {
+	return r.keys.Get(ctx, id)
+}
+
+// UserKeys - ключи пользователя.
+func (r *APIKeyRepository) UserKeys(ctx context.Context, uid domain.UID) ([]domain.APIKey, error) {
+	return r.keys.UserKeys(ctx, uid)
+}
+
+// Revoke - отозвать ключ пользователя.
+func (r *APIKeyRepository) Revoke(ctx context.Context, uid domain.UID, id string, at time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	err := r.keys.Revoke(ctx, uid, id, at)
+	if err != nil {
+		return fmt.Errorf("file api keys, revoke: %w", err)
+	}
+	err = r.write()
+	if err != nil {
+		return fmt.Errorf("file api keys, revoke: %w", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) restore() error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(f)
+	for decoder.More() {
+		var key domain.APIKey
+		err = decoder.Decode(&key)
+		if err != nil {
+			return fmt.Errorf("decode: %w", err)
+		}
+		err = r.keys.Add(context.Background(), &key)
+		if err != nil {
+			return fmt.Errorf("restore key %s: %w", key.ID, err)
+		}
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) write() error {
+	tmp := r.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("open tmp: %w", err)
+	}
+	encoder := json.NewEncoder(f)
+	for _, key := range r.keys.Keys() {
+		err = encoder.Encode(&key)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("encode: %w", err)
+		}
+	}
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("close tmp: %w", err)
+	}
+	err = os.Rename(tmp, r.path)
+	if err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package file
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/google/uuid"
+)
+
+var _ ports.ClickRepository = (*ShortenerRepository)(nil)
+
+// SaveClicks - сохранить переходы по ссылкам. Перед сохранением в памяти переходы дописываются в файл,
+// чтобы статистика пережила восстановление из файла.
+func (repo *ShortenerRepository) SaveClicks(ctx context.Context, batch []*domain.Click) error {
+	records := make([]domain.BackupRecord, 0, len(batch))
+	for _, click := range batch {
+		if click == nil {
+			continue
+		}
+		c := *click
+		records = append(records, domain.BackupRecord{
+			UUID:    uuid.New().String(),
+			ShortID: click.ShortID,
+			Click:   &c,
+		})
+	}
+	err := repo.writeBatchToFile(records)
+	if err != nil {
+		return fmt.Errorf("file repository, save clicks, write to file: %w", err)
+	}
+	return repo.repo.SaveClicks(ctx, batch)
+}
+
+// ClickStats - статистика переходов по ссылке пользователя.
+func (repo *ShortenerRepository) ClickStats(ctx context.Context, uid domain.UID, shortID domain.ShortID) (*domain.LinkStats, error) {
+	return repo.repo.ClickStats(ctx, uid, shortID)
+}
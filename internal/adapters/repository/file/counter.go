@@ -0,0 +1,85 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// counterReserve - сколько значений счетчика резервируется одной записью в файл.
+const counterReserve = 100
+
+// Counter - счетчик, который хранит в файле верхнюю границу выданных значений.
+// Значения резервируются блоками, файл перезаписывается атомарно через временный файл,
+// поэтому после перезапуска значения не повторяются, а неиспользованный остаток блока пропускается.
+type Counter struct {
+	path     string
+	next     uint64
+	reserved uint64
+	mutex    sync.Mutex
+}
+
+var _ ports.Counter = (*Counter)(nil)
+
+// NewCounter - загрузить счетчик из файла, если файла нет, то счетчик начинается с нуля.
+func NewCounter(path string) (*Counter, error) {
+	counter := &Counter{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return counter, nil
+		}
+		return nil, fmt.Errorf("file counter, read: %w", err)
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("file counter, parse %s: %w", path, err)
+	}
+	counter.next = value
+	counter.reserved = value
+	return counter, nil
+}
+
+// Next - следующее значение счетчика.
+func (c *Counter) Next(_ context.Context) (uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.next == c.reserved {
+		err := c.persist(c.reserved + counterReserve)
+		if err != nil {
+			return 0, err
+		}
+		c.reserved += counterReserve
+	}
+	c.next++
+	return c.next, nil
+}
+
+func (c *Counter) persist(value uint64) error {
+	tmp := c.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return fmt.Errorf("file counter, open tmp: %w", err)
+	}
+	_, err = f.WriteString(strconv.FormatUint(value, 10))
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("file counter, write tmp: %w", err)
+	}
+	err = os.Rename(tmp, c.path)
+	if err != nil {
+		return fmt.Errorf("file counter, rename: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package file
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+	counter, err := NewCounter(path)
+	require.NoError(t, err)
+	for i := uint64(1); i <= 3; i++ {
+		value, err := counter.Next(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, i, value)
+	}
+
+	counter, err = NewCounter(path)
+	require.NoError(t, err)
+	value, err := counter.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(counterReserve+1), value)
+}
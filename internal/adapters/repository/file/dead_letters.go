@@ -0,0 +1,50 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// DeadLetterRepository - ссылки, удаление которых не удалось записать, дописываются в файл по одной в JSON строке.
+type DeadLetterRepository struct {
+	path  string
+	mutex sync.Mutex
+}
+
+var _ ports.DeadLetterRepository = (*DeadLetterRepository)(nil)
+
+// NewDeadLetterRepository - новое хранилище.
+func NewDeadLetterRepository(path string) *DeadLetterRepository {
+	return &DeadLetterRepository{
+		path: path,
+	}
+}
+
+// Add - дописать ссылки в файл.
+func (r *DeadLetterRepository) Add(_ context.Context, letters []domain.DeadLetter) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("file dead letters, open: %w", err)
+	}
+	encoder := json.NewEncoder(f)
+	for i := range letters {
+		err = encoder.Encode(&letters[i])
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("file dead letters, write: %w", err)
+		}
+	}
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("file dead letters, close: %w", err)
+	}
+	return nil
+}
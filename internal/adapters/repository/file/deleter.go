@@ -0,0 +1,90 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/google/uuid"
+)
+
+// DeleterRepository - репозиторий, который помечает записи удаленными
+// и сохраняет в файл записи-надгробия, чтобы удаление пережило восстановление из файла.
+type DeleterRepository struct {
+	repo *ShortenerRepository
+}
+
+// NewDeleterRepository - новый репозиторий.
+func NewDeleterRepository(repo *ShortenerRepository) *DeleterRepository {
+	return &DeleterRepository{
+		repo: repo,
+	}
+}
+
+var _ ports.DeleterRepository = (*DeleterRepository)(nil)
+
+// Delete - помечает урлы как удаленные. Проверка, запись надгробий в файл и пометка в памяти
+// выполняются под одной блокировкой.
+func (r *DeleterRepository) Delete(_ context.Context, batch []*domain.DeleteData) error {
+	err := r.repo.repo.DeleteWith(batch, func(deletable []*domain.DeleteData) error {
+		now := time.Now().UTC()
+		tombstones := make([]domain.BackupRecord, 0, len(deletable))
+		for _, v := range deletable {
+			if v.DeletedAt.IsZero() {
+				v.DeletedAt = now
+			}
+			deletedAt := v.DeletedAt
+			tombstones = append(tombstones, domain.BackupRecord{
+				UUID:      uuid.New().String(),
+				ShortID:   domain.ShortID(v.ShortID),
+				UID:       domain.UID(v.UID),
+				IsDeleted: true,
+				DeletedAt: &deletedAt,
+			})
+		}
+		return r.repo.writeBatchToFile(tombstones)
+	})
+	if err != nil {
+		return fmt.Errorf("file deleter repository, delete, write to file: %w", err)
+	}
+	return nil
+}
+
+// Restore - отменить удаление ссылок. Перед изменением в файл дописываются записи восстановления.
+func (r *DeleterRepository) Restore(_ context.Context, uid domain.UID, shortIDs []domain.ShortID, since time.Time) ([]domain.RestoreResult, error) {
+	results, err := r.repo.repo.RestoreWith(uid, shortIDs, since, func(restoring []domain.ShortID) error {
+		records := make([]domain.BackupRecord, 0, len(restoring))
+		for _, shortID := range restoring {
+			records = append(records, domain.BackupRecord{
+				UUID:       uuid.New().String(),
+				ShortID:    shortID,
+				UID:        uid,
+				IsRestored: true,
+			})
+		}
+		return r.repo.writeBatchToFile(records)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("file deleter repository, restore: %w", err)
+	}
+	return results, nil
+}
+
+// PurgeDeleted - окончательно удалить записи, удаленные раньше before. В файл дописывается запись очистки,
+// чтобы освободившиеся короткие идентификаторы можно было занять снова и после восстановления из файла.
+func (r *DeleterRepository) PurgeDeleted(ctx context.Context, before time.Time) (*domain.PurgeResult, error) {
+	if !r.repo.repo.Purgeable(before) {
+		return &domain.PurgeResult{}, nil
+	}
+	purgedBefore := before.UTC()
+	err := r.repo.writeToFile(&domain.BackupRecord{
+		UUID:         uuid.New().String(),
+		PurgedBefore: &purgedBefore,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("file deleter repository, purge deleted, write to file: %w", err)
+	}
+	return r.repo.repo.PurgeDeleted(ctx, before)
+}
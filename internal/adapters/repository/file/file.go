@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
 	"github.com/Svirex/microurl/internal/core/domain"
@@ -28,23 +29,34 @@ func NewShortenerRepository(inmemoryRepo *inmemory.ShortenerRepository, writer p
 
 var _ ports.ShortenerRepository = (*ShortenerRepository)(nil)
 
-// Add - добавить запись.
-func (repo *ShortenerRepository) Add(ctx context.Context, shortID domain.ShortID, data *domain.Record) (domain.ShortID, error) {
-	if id, exist := repo.repo.CheckExists(data.URL); exist {
-		return id, fmt.Errorf("file repository, add: %w", ports.ErrAlreadyExists)
+// Add - добавить запись. Если для пользователя уже есть действующая запись урла, то в файл пишется
+// только связь пользователя с ней, чтобы владение пережило восстановление из файла.
+// Проверка, запись в файл и добавление в память выполняются под одной блокировкой.
+func (repo *ShortenerRepository) Add(_ context.Context, shortID domain.ShortID, data *domain.Record) (domain.ShortID, error) {
+	id, err := repo.repo.AddWith(shortID, data, func(changes []domain.URLData) error {
+		return repo.writeChanges(data.UID, changes)
+	})
+	if err != nil {
+		return id, fmt.Errorf("file repository, add: %w", err)
 	}
-	backupRecord := &domain.BackupRecord{
-		UUID:    uuid.New().String(),
-		ShortID: shortID,
-		URL:     data.URL,
-		UID:     data.UID,
+	return id, nil
+}
+
+func (repo *ShortenerRepository) writeChanges(uid domain.UID, changes []domain.URLData) error {
+	backupRecords := make([]domain.BackupRecord, 0, len(changes))
+	for i := range changes {
+		backupRecords = append(backupRecords, domain.BackupRecord{
+			UUID:      uuid.New().String(),
+			ShortID:   changes[i].ShortID,
+			URL:       changes[i].URL,
+			UID:       uid,
+			ExpiresAt: changes[i].ExpiresAt,
+		})
 	}
-	err := repo.writeToFile(backupRecord)
-	if err != nil {
-		return domain.ShortID(""), fmt.Errorf("file repository, add, write to file: %w", err)
+	if err := repo.writeBatchToFile(backupRecords); err != nil {
+		return fmt.Errorf("write to file: %w", err)
 	}
-	repo.repo.Add(ctx, shortID, data)
-	return shortID, nil
+	return nil
 }
 
 func (repo *ShortenerRepository) writeToFile(record *domain.BackupRecord) error {
@@ -58,23 +70,16 @@ func (repo *ShortenerRepository) Get(ctx context.Context, shortID domain.ShortID
 	return repo.repo.Get(ctx, shortID)
 }
 
-// Batch - добавить несоклько записей.
-func (repo *ShortenerRepository) Batch(ctx context.Context, uid domain.UID, data []domain.BatchRecord) ([]domain.BatchRecord, error) {
-	backupRecords := make([]domain.BackupRecord, 0, len(data))
-	for i := range data {
-		record := &data[i]
-		backupRecords = append(backupRecords, domain.BackupRecord{
-			UUID:    uuid.New().String(),
-			ShortID: record.ShortID,
-			URL:     record.URL,
-			UID:     uid,
-		})
-	}
-	err := repo.writeBatchToFile(backupRecords)
+// Batch - добавить несоклько записей. Записи пишутся в файл уже после проверки батча
+// и под той же блокировкой, что и добавление в память.
+func (repo *ShortenerRepository) Batch(_ context.Context, uid domain.UID, data []domain.BatchRecord) ([]domain.BatchRecord, error) {
+	result, err := repo.repo.BatchWith(uid, data, func(changes []domain.URLData) error {
+		return repo.writeChanges(uid, changes)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("file repository, batch, write to file: %w", err)
+		return nil, fmt.Errorf("file repository, batch: %w", err)
 	}
-	return repo.repo.Batch(ctx, uid, data)
+	return result, nil
 }
 
 func (repo *ShortenerRepository) writeBatchToFile(data []domain.BackupRecord) error {
@@ -95,6 +100,51 @@ func (repo *ShortenerRepository) UserURLs(ctx context.Context, uid domain.UID) (
 	return repo.repo.UserURLs(ctx, uid)
 }
 
+// Update - изменить ссылку. Перед изменением в файл дописывается запись изменения с новыми значениями.
+func (repo *ShortenerRepository) Update(_ context.Context, uid domain.UID, shortID domain.ShortID, update *domain.URLUpdate) (*domain.URLData, error) {
+	data, err := repo.repo.UpdateWith(uid, shortID, update, func(data *domain.URLData) error {
+		updatedAt := update.UpdatedAt
+		return repo.writeToFile(&domain.BackupRecord{
+			UUID:      uuid.New().String(),
+			ShortID:   shortID,
+			URL:       data.URL,
+			UID:       uid,
+			ExpiresAt: data.ExpiresAt,
+			UpdatedAt: &updatedAt,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("file repository, update: %w", err)
+	}
+	return data, nil
+}
+
+// URLRevisions - история изменений ссылки.
+func (repo *ShortenerRepository) URLRevisions(ctx context.Context, uid domain.UID, shortID domain.ShortID) ([]domain.URLRevision, error) {
+	return repo.repo.URLRevisions(ctx, uid, shortID)
+}
+
+// Stats - получить количество урлов и пользователей.
+func (repo *ShortenerRepository) Stats(ctx context.Context) (*domain.Stats, error) {
+	return repo.repo.Stats(ctx)
+}
+
+// PurgeExpired - удалить записи с истекшим сроком действия. В файл дописывается запись очистки,
+// чтобы освободившиеся короткие идентификаторы можно было занять снова и после восстановления из файла.
+func (repo *ShortenerRepository) PurgeExpired(_ context.Context, now time.Time) (int64, error) {
+	count, err := repo.repo.PurgeExpiredWith(now, func() error {
+		expiredBefore := now.UTC()
+		return repo.writeToFile(&domain.BackupRecord{
+			UUID:          uuid.New().String(),
+			ExpiredBefore: &expiredBefore,
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("file repository, purge expired, write to file: %w", err)
+	}
+	return count, nil
+}
+
 // Shutdown - выключить сервис.
 func (repo *ShortenerRepository) Shutdown() error {
 	return nil
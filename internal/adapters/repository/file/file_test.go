@@ -0,0 +1,346 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/adapters/filebackup"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOwnershipSurvivesRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backup.json")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	require.NoError(t, err)
+	repo := NewShortenerRepository(inmemory.NewShortenerRepository(), filebackup.NewFileBackupWriter(f))
+	shortID, err := repo.Add(context.Background(), "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(context.Background(), "second", &domain.Record{UID: "bob", URL: "http://svirex.ru"})
+	require.ErrorIs(t, err, ports.ErrAlreadyExists)
+	err = NewDeleterRepository(repo).Delete(context.Background(), []*domain.DeleteData{{UID: "alice", ShortID: string(shortID)}})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	restored := inmemory.NewShortenerRepository()
+	err = filebackup.NewFileBackupReader(f).Restore(context.Background(), restored, inmemory.NewDeleterRepository(restored))
+	require.NoError(t, err)
+
+	urls, err := restored.UserURLs(context.Background(), "bob")
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, shortID, urls[0].ShortID)
+	require.False(t, restored.IsOwner(shortID, "alice"))
+	url, err := restored.Get(context.Background(), shortID)
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://svirex.ru"), url)
+}
+
+func TestAPIKeysSurviveRestore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backup.json.apikeys")
+	repo, err := NewAPIKeyRepository(path)
+	require.NoError(t, err)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, repo.Add(ctx, &domain.APIKey{ID: "first", UID: "alice", Hash: "h1", Scopes: []domain.Scope{domain.ScopeRead}, CreatedAt: now}))
+	require.NoError(t, repo.Add(ctx, &domain.APIKey{ID: "second", UID: "alice", Hash: "h2", Scopes: []domain.Scope{domain.ScopeShorten}, CreatedAt: now.Add(time.Second)}))
+	require.ErrorIs(t, repo.Add(ctx, &domain.APIKey{ID: "first", UID: "bob"}), ports.ErrAlreadyExists)
+	require.NoError(t, repo.Revoke(ctx, "alice", "first", now))
+
+	restored, err := NewAPIKeyRepository(path)
+	require.NoError(t, err)
+	keys, err := restored.UserKeys(ctx, "alice")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, "first", keys[0].ID)
+	require.NotNil(t, keys[0].RevokedAt)
+	require.Equal(t, "h2", keys[1].Hash)
+	require.Nil(t, keys[1].RevokedAt)
+}
+
+func TestUpdateSurvivesRestore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backup.json")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	require.NoError(t, err)
+	repo := NewShortenerRepository(inmemory.NewShortenerRepository(), filebackup.NewFileBackupWriter(f))
+	_, err = repo.Add(ctx, "first", &domain.Record{UID: "alice", URL: "http://typo.ru"})
+	require.NoError(t, err)
+	newURL := domain.URL("http://svirex.ru")
+	changedAt := time.Now().UTC().Truncate(time.Second)
+	_, err = repo.Update(ctx, "alice", "first", &domain.URLUpdate{URL: &newURL, UpdatedAt: changedAt})
+	require.NoError(t, err)
+	_, err = repo.Update(ctx, "bob", "first", &domain.URLUpdate{URL: &newURL, UpdatedAt: changedAt})
+	require.ErrorIs(t, err, ports.ErrNotFound)
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	restored := inmemory.NewShortenerRepository()
+	require.NoError(t, filebackup.NewFileBackupReader(f).Restore(ctx, restored, inmemory.NewDeleterRepository(restored)))
+	url, err := restored.Get(ctx, "first")
+	require.NoError(t, err)
+	require.Equal(t, newURL, url)
+	revisions, err := restored.URLRevisions(ctx, "alice", "first")
+	require.NoError(t, err)
+	require.Equal(t, []domain.URLRevision{{URL: "http://typo.ru", ChangedAt: changedAt}}, revisions)
+}
+
+func TestOnlyCreatorUpdatesAfterRestore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backup.json")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	require.NoError(t, err)
+	repo := NewShortenerRepository(inmemory.NewShortenerRepository(), filebackup.NewFileBackupWriter(f))
+	_, err = repo.Add(ctx, "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(ctx, "second", &domain.Record{UID: "bob", URL: "http://svirex.ru"})
+	require.ErrorIs(t, err, ports.ErrAlreadyExists)
+	newURL := domain.URL("http://ya.ru")
+	_, err = repo.Update(ctx, "bob", "first", &domain.URLUpdate{URL: &newURL, UpdatedAt: time.Now()})
+	require.ErrorIs(t, err, ports.ErrNotCreator)
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	restored := inmemory.NewShortenerRepository()
+	require.NoError(t, filebackup.NewFileBackupReader(f).Restore(ctx, restored, inmemory.NewDeleterRepository(restored)))
+	_, err = restored.Update(ctx, "bob", "first", &domain.URLUpdate{URL: &newURL, UpdatedAt: time.Now()})
+	require.ErrorIs(t, err, ports.ErrNotCreator)
+	_, err = restored.URLRevisions(ctx, "bob", "first")
+	require.ErrorIs(t, err, ports.ErrNotCreator)
+	_, err = restored.Update(ctx, "alice", "first", &domain.URLUpdate{URL: &newURL, UpdatedAt: time.Now()})
+	require.ErrorIs(t, err, ports.ErrSharedLink)
+	urls, err := restored.UserURLs(ctx, "bob")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://svirex.ru"), urls[0].URL)
+}
+
+func TestRestoreAndPurgeSurviveRestore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backup.json")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	require.NoError(t, err)
+	repo := NewShortenerRepository(inmemory.NewShortenerRepository(), filebackup.NewFileBackupWriter(f))
+	deleter := NewDeleterRepository(repo)
+	_, err = repo.Add(ctx, "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(ctx, "old", &domain.Record{UID: "alice", URL: "http://ya.ru"})
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, deleter.Delete(ctx, []*domain.DeleteData{
+		{UID: "alice", ShortID: "first"},
+		{UID: "alice", ShortID: "old", DeletedAt: now.Add(-2 * time.Hour)},
+	}))
+	results, err := deleter.Restore(ctx, "alice", []domain.ShortID{"first"}, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, domain.RestoreStatusRestored, results[0].Status)
+	result, err := deleter.PurgeDeleted(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.Records)
+	_, err = repo.Add(ctx, "old", &domain.Record{UID: "bob", URL: "http://typo.ru"})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	restored := inmemory.NewShortenerRepository()
+	require.NoError(t, filebackup.NewFileBackupReader(f).Restore(ctx, restored, inmemory.NewDeleterRepository(restored)))
+	url, err := restored.Get(ctx, "first")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://svirex.ru"), url)
+	url, err = restored.Get(ctx, "old")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://typo.ru"), url)
+}
+
+func TestDeleteStatuses(t *testing.T) {
+	ctx := context.Background()
+	f, err := os.Create(filepath.Join(t.TempDir(), "backup.json"))
+	require.NoError(t, err)
+	defer f.Close()
+	repo := NewShortenerRepository(inmemory.NewShortenerRepository(), filebackup.NewFileBackupWriter(f))
+	_, err = repo.Add(ctx, "mine", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(ctx, "theirs", &domain.Record{UID: "bob", URL: "http://ya.ru"})
+	require.NoError(t, err)
+
+	batch := []*domain.DeleteData{
+		{UID: "alice", ShortID: "mine"},
+		{UID: "alice", ShortID: "theirs"},
+		{UID: "alice", ShortID: "missing"},
+	}
+	require.NoError(t, NewDeleterRepository(repo).Delete(ctx, batch))
+	require.Equal(t, domain.DeleteStatusDeleted, batch[0].Status)
+	require.Equal(t, domain.DeleteStatusNotOwned, batch[1].Status)
+	require.Equal(t, domain.DeleteStatusNotFound, batch[2].Status)
+}
+
+func TestReusedAliasSurvivesRestore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backup.json")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	require.NoError(t, err)
+	repo := NewShortenerRepository(inmemory.NewShortenerRepository(), filebackup.NewFileBackupWriter(f))
+	expiresAt := time.Now().Add(-time.Minute).UTC()
+	_, err = repo.Add(ctx, "promo", &domain.Record{UID: "alice", URL: "http://svirex.ru", ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	count, err := repo.PurgeExpired(ctx, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+	_, err = repo.Add(ctx, "promo", &domain.Record{UID: "bob", URL: "http://ya.ru"})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	restored := inmemory.NewShortenerRepository()
+	require.NoError(t, filebackup.NewFileBackupReader(f).Restore(ctx, restored, inmemory.NewDeleterRepository(restored)))
+	url, err := restored.Get(ctx, "promo")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://ya.ru"), url)
+	urls, err := restored.UserURLs(ctx, "bob")
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+}
+
+func TestClicksSurviveRestore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "backup.json")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+	require.NoError(t, err)
+	repo := NewShortenerRepository(inmemory.NewShortenerRepository(), filebackup.NewFileBackupWriter(f))
+	_, err = repo.Add(ctx, "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	clickedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.SaveClicks(ctx, []*domain.Click{
+		{ShortID: "first", ClickedAt: clickedAt, IP: "1.1.1.1"},
+		{ShortID: "first", ClickedAt: clickedAt.Add(time.Hour), IP: "2.2.2.2"},
+		nil,
+	}))
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	restored := inmemory.NewShortenerRepository()
+	require.NoError(t, filebackup.NewFileBackupReader(f).Restore(ctx, restored, inmemory.NewDeleterRepository(restored)))
+	stats, err := restored.ClickStats(ctx, "alice", "first")
+	require.NoError(t, err)
+	require.Equal(t, &domain.LinkStats{
+		TotalClicks:    2,
+		UniqueVisitors: 2,
+		Daily:          []domain.DailyClicks{{Date: "2024-05-01", Clicks: 2}},
+	}, stats)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(context.Context, *domain.BackupRecord) error {
+	return errors.New("disk full")
+}
+
+func TestFailedWriteDoesNotChangeMemory(t *testing.T) {
+	ctx := context.Background()
+	memory := inmemory.NewShortenerRepository()
+	repo := NewShortenerRepository(memory, failingWriter{})
+	_, err := repo.Add(ctx, "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.Error(t, err)
+	_, err = repo.Batch(ctx, "alice", []domain.BatchRecord{{ShortID: "second", URL: "http://ya.ru"}})
+	require.Error(t, err)
+	_, err = memory.Get(ctx, "first")
+	require.ErrorIs(t, err, ports.ErrNotFound)
+	_, err = memory.Get(ctx, "second")
+	require.ErrorIs(t, err, ports.ErrNotFound)
+}
+
+type recordingWriter struct {
+	mutex   sync.Mutex
+	records []domain.BackupRecord
+}
+
+func (w *recordingWriter) Write(_ context.Context, record *domain.BackupRecord) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.records = append(w.records, *record)
+	return nil
+}
+
+func TestConcurrentAddWritesOnlyAddedRecords(t *testing.T) {
+	ctx := context.Background()
+	writer := &recordingWriter{}
+	repo := NewShortenerRepository(inmemory.NewShortenerRepository(), writer)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = repo.Add(ctx, "same", &domain.Record{UID: "alice", URL: domain.URL(fmt.Sprintf("http://svirex.ru/%d", i))})
+			_, _ = repo.Batch(ctx, "bob", []domain.BatchRecord{{ShortID: "batch", URL: domain.URL(fmt.Sprintf("http://ya.ru/%d", i))}})
+		}(i)
+	}
+	wg.Wait()
+	require.Len(t, writer.records, 2)
+	require.Equal(t, domain.ShortID("same"), writer.records[0].ShortID)
+	require.Equal(t, domain.ShortID("batch"), writer.records[1].ShortID)
+}
+
+func TestConcurrentDeleteAndRestoreKeepFileInSync(t *testing.T) {
+	ctx := context.Background()
+	writer := &recordingWriter{}
+	memory := inmemory.NewShortenerRepository()
+	repo := NewShortenerRepository(memory, writer)
+	_, err := repo.Add(ctx, "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	deleter := NewDeleterRepository(repo)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			batch := []*domain.DeleteData{{UID: "alice", ShortID: "first"}, {UID: "alice", ShortID: "first"}}
+			require.NoError(t, deleter.Delete(ctx, batch))
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := deleter.Restore(ctx, "alice", []domain.ShortID{"first"}, time.Time{})
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	balance := 0
+	for _, record := range writer.records {
+		switch {
+		case record.IsDeleted:
+			balance++
+		case record.IsRestored:
+			balance--
+		}
+		require.True(t, balance == 0 || balance == 1)
+	}
+	require.Equal(t, memory.IsOwner("first", "alice"), balance == 0)
+
+	failing := NewDeleterRepository(NewShortenerRepository(memory, failingWriter{}))
+	_, err = deleter.Restore(ctx, "alice", []domain.ShortID{"first"}, time.Time{})
+	require.NoError(t, err)
+	batch := []*domain.DeleteData{{UID: "alice", ShortID: "first"}}
+	require.Error(t, failing.Delete(ctx, batch))
+	require.True(t, memory.IsOwner("first", "alice"))
+}
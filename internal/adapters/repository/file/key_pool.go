@@ -0,0 +1,99 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// KeyPoolRepository - хранилище ключей в файле, по одному ключу в строке.
+// Файл перезаписывается атомарно через временный файл.
+type KeyPoolRepository struct {
+	path    string
+	records *ShortenerRepository
+	mutex   sync.Mutex
+}
+
+var _ ports.KeyPoolRepository = (*KeyPoolRepository)(nil)
+
+// NewKeyPoolRepository - новое хранилище ключей для записей records.
+func NewKeyPoolRepository(path string, records *ShortenerRepository) *KeyPoolRepository {
+	return &KeyPoolRepository{
+		path:    path,
+		records: records,
+	}
+}
+
+// TakeKeys - забрать до n ключей из файла.
+func (r *KeyPoolRepository) TakeKeys(_ context.Context, n int) ([]domain.ShortID, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	keys, err := r.read()
+	if err != nil {
+		return nil, fmt.Errorf("file key pool, take keys: %w", err)
+	}
+	n = min(n, len(keys))
+	if n == 0 {
+		return nil, nil
+	}
+	err = r.write(keys[n:])
+	if err != nil {
+		return nil, fmt.Errorf("file key pool, take keys: %w", err)
+	}
+	return keys[:n], nil
+}
+
+// PutKeys - дописать в файл ключи, которые еще не заняты записями.
+func (r *KeyPoolRepository) PutKeys(_ context.Context, keys []domain.ShortID) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored, err := r.read()
+	if err != nil {
+		return fmt.Errorf("file key pool, put keys: %w", err)
+	}
+	err = r.write(r.records.repo.FreeKeys(stored, keys))
+	if err != nil {
+		return fmt.Errorf("file key pool, put keys: %w", err)
+	}
+	return nil
+}
+
+func (r *KeyPoolRepository) read() ([]domain.ShortID, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	lines := strings.Fields(string(data))
+	keys := make([]domain.ShortID, 0, len(lines))
+	for _, line := range lines {
+		keys = append(keys, domain.ShortID(line))
+	}
+	return keys, nil
+}
+
+func (r *KeyPoolRepository) write(keys []domain.ShortID) error {
+	var builder strings.Builder
+	for _, key := range keys {
+		builder.WriteString(string(key))
+		builder.WriteByte('\n')
+	}
+	tmp := r.path + ".tmp"
+	err := os.WriteFile(tmp, []byte(builder.String()), 0666)
+	if err != nil {
+		return fmt.Errorf("write tmp: %w", err)
+	}
+	err = os.Rename(tmp, r.path)
+	if err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// APIKeyRepository - API ключи в памяти.
+type APIKeyRepository struct {
+	keys  map[string]domain.APIKey
+	mutex sync.RWMutex
+}
+
+var _ ports.APIKeyRepository = (*APIKeyRepository)(nil)
+
+// NewAPIKeyRepository - новое хранилище.
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{
+		keys: make(map[string]domain.APIKey),
+	}
+}
+
+// Add - сохранить ключ.
+func (r *APIKeyRepository) Add(_ context.Context, key *domain.APIKey) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.keys[key.ID]; ok {
+		return ports.ErrAlreadyExists
+	}
+	r.keys[key.ID] = copyAPIKey(key)
+	return nil
+}
+
+// Get - получить ключ по ID.
+func (r *APIKeyRepository) Get(_ context.Context, id string) (*domain.APIKey, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ports.ErrNotFound
+	}
+	result := copyAPIKey(&key)
+	return &result, nil
+}
+
+// UserKeys - ключи пользователя в порядке выпуска.
+func (r *APIKeyRepository) UserKeys(_ context.Context, uid domain.UID) ([]domain.APIKey, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var keys []domain.APIKey
+	for _, key := range r.keys {
+		if key.UID == uid {
+			keys = append(keys, copyAPIKey(&key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// Revoke - отозвать ключ пользователя.
+func (r *APIKeyRepository) Revoke(_ context.Context, uid domain.UID, id string, at time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	key, ok := r.keys[id]
+	if !ok || key.UID != uid || key.RevokedAt != nil {
+		return ports.ErrNotFound
+	}
+	key.RevokedAt = &at
+	r.keys[id] = key
+	return nil
+}
+
+// Keys - все ключи, используется для сохранения в файл.
+func (r *APIKeyRepository) Keys() []domain.APIKey {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	keys := make([]domain.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, copyAPIKey(&key))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+func copyAPIKey(key *domain.APIKey) domain.APIKey {
+	result := *key
+	result.Scopes = append([]domain.Scope(nil), key.Scopes...)
+	if key.RevokedAt != nil {
+		at := *key.RevokedAt
+		result.RevokedAt = &at
+	}
+	return result
+}
@@ -0,0 +1,59 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+var _ ports.ClickRepository = (*ShortenerRepository)(nil)
+
+// SaveClicks - сохранить переходы по ссылкам.
+func (m *ShortenerRepository) SaveClicks(_ context.Context, batch []*domain.Click) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, click := range batch {
+		if click == nil {
+			continue
+		}
+		if _, exist := m.data[click.ShortID]; !exist {
+			continue
+		}
+		m.clicks[click.ShortID] = append(m.clicks[click.ShortID], *click)
+	}
+	return nil
+}
+
+// ClickStats - статистика переходов по ссылке пользователя.
+func (m *ShortenerRepository) ClickStats(_ context.Context, uid domain.UID, shortID domain.ShortID) (*domain.LinkStats, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, owner := m.owners[shortID][uid]; !owner {
+		return nil, fmt.Errorf("click stats from map repository: %w", ports.ErrNotFound)
+	}
+	clicks := m.clicks[shortID]
+	visitors := make(map[string]struct{})
+	daily := make(map[string]int64)
+	for i := range clicks {
+		visitors[clicks[i].IP] = struct{}{}
+		daily[clicks[i].ClickedAt.UTC().Format(domain.DateLayout)]++
+	}
+	stats := &domain.LinkStats{
+		TotalClicks:    int64(len(clicks)),
+		UniqueVisitors: int64(len(visitors)),
+		Daily:          make([]domain.DailyClicks, 0, len(daily)),
+	}
+	for date, count := range daily {
+		stats.Daily = append(stats.Daily, domain.DailyClicks{
+			Date:   date,
+			Clicks: count,
+		})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date < stats.Daily[j].Date
+	})
+	return stats, nil
+}
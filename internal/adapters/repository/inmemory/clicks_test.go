@@ -0,0 +1,39 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickStats(t *testing.T) {
+	repo := NewShortenerRepository()
+	repo.Add(context.Background(), "afASDFqwe", &domain.Record{
+		UID: domain.UID("uuid"),
+		URL: domain.URL("http://svirex.ru"),
+	})
+	day := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	err := repo.SaveClicks(context.Background(), []*domain.Click{
+		{ShortID: "afASDFqwe", ClickedAt: day, IP: "10.0.0.1"},
+		{ShortID: "afASDFqwe", ClickedAt: day.Add(time.Hour), IP: "10.0.0.1"},
+		{ShortID: "afASDFqwe", ClickedAt: day.Add(24 * time.Hour), IP: "10.0.0.2"},
+		{ShortID: "unknown", ClickedAt: day, IP: "10.0.0.3"},
+	})
+	require.NoError(t, err)
+
+	stats, err := repo.ClickStats(context.Background(), "uuid", "afASDFqwe")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), stats.TotalClicks)
+	require.Equal(t, int64(2), stats.UniqueVisitors)
+	require.Equal(t, []domain.DailyClicks{
+		{Date: "2024-06-01", Clicks: 2},
+		{Date: "2024-06-02", Clicks: 1},
+	}, stats.Daily)
+
+	_, err = repo.ClickStats(context.Background(), "other", "afASDFqwe")
+	require.ErrorIs(t, err, ports.ErrNotFound)
+}
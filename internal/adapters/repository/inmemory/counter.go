@@ -0,0 +1,25 @@
+package inmemory
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// Counter - счетчик в памяти.
+type Counter struct {
+	value atomic.Uint64
+}
+
+var _ ports.Counter = (*Counter)(nil)
+
+// NewCounter - новый счетчик.
+func NewCounter() *Counter {
+	return &Counter{}
+}
+
+// Next - следующее значение счетчика, начиная с 1.
+func (c *Counter) Next(_ context.Context) (uint64, error) {
+	return c.value.Add(1), nil
+}
@@ -0,0 +1,39 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// DeadLetterRepository - ссылки, удаление которых не удалось записать, в памяти.
+type DeadLetterRepository struct {
+	letters []domain.DeadLetter
+	mutex   sync.Mutex
+}
+
+var _ ports.DeadLetterRepository = (*DeadLetterRepository)(nil)
+
+// NewDeadLetterRepository - новое хранилище.
+func NewDeadLetterRepository() *DeadLetterRepository {
+	return &DeadLetterRepository{}
+}
+
+// Add - сохранить ссылки.
+func (r *DeadLetterRepository) Add(_ context.Context, letters []domain.DeadLetter) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.letters = append(r.letters, letters...)
+	return nil
+}
+
+// Letters - все сохраненные ссылки.
+func (r *DeadLetterRepository) Letters() []domain.DeadLetter {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	letters := make([]domain.DeadLetter, len(r.letters))
+	copy(letters, r.letters)
+	return letters
+}
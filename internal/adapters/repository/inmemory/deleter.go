@@ -0,0 +1,250 @@
+package inmemory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// deleteKey - ссылка пользователя в батче удаления.
+type deleteKey struct {
+	uid     string
+	shortID string
+}
+
+// DeleterRepository - репозиторий, который помечает записи в памяти удаленными.
+type DeleterRepository struct {
+	repo *ShortenerRepository
+}
+
+// NewDeleterRepository - новый репозиторий.
+func NewDeleterRepository(repo *ShortenerRepository) *DeleterRepository {
+	return &DeleterRepository{
+		repo: repo,
+	}
+}
+
+var _ ports.DeleterRepository = (*DeleterRepository)(nil)
+
+// Delete - помечает урлы как удаленные.
+func (r *DeleterRepository) Delete(_ context.Context, batch []*domain.DeleteData) error {
+	r.repo.MarkDeleted(batch)
+	return nil
+}
+
+// Restore - отменить удаление ссылок пользователем.
+func (r *DeleterRepository) Restore(_ context.Context, uid domain.UID, shortIDs []domain.ShortID, since time.Time) ([]domain.RestoreResult, error) {
+	return r.repo.RestoreWith(uid, shortIDs, since, nil)
+}
+
+// PurgeDeleted - окончательно удалить записи, удаленные раньше before.
+func (r *DeleterRepository) PurgeDeleted(ctx context.Context, before time.Time) (*domain.PurgeResult, error) {
+	return r.repo.PurgeDeleted(ctx, before)
+}
+
+// Deletable - оставить из батча только записи, которые принадлежат пользователю и еще не удалены.
+func (m *ShortenerRepository) Deletable(batch []*domain.DeleteData) []*domain.DeleteData {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	result := make([]*domain.DeleteData, 0, len(batch))
+	for _, v := range batch {
+		if v != nil && m.isDeletable(v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// MarkDeleted - удалить ссылки батча у владельцев и заполнить результат у каждой записи.
+// Запись перестает открываться, когда ее удалили все владельцы.
+func (m *ShortenerRepository) MarkDeleted(batch []*domain.DeleteData) {
+	_ = m.DeleteWith(batch, nil)
+}
+
+// DeleteWith - удалить ссылки батча у владельцев. persist вызывается под блокировкой репозитория
+// с записями, которые будут удалены, до изменения; если он вернул ошибку, то ничего не удаляется.
+func (m *ShortenerRepository) DeleteWith(batch []*domain.DeleteData, persist func([]*domain.DeleteData) error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if persist != nil {
+		deletable := make([]*domain.DeleteData, 0, len(batch))
+		seen := make(map[deleteKey]struct{}, len(batch))
+		for _, v := range batch {
+			if v == nil || !m.isDeletable(v) {
+				continue
+			}
+			key := deleteKey{uid: v.UID, shortID: v.ShortID}
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				deletable = append(deletable, v)
+			}
+		}
+		if len(deletable) > 0 {
+			if err := persist(deletable); err != nil {
+				return err
+			}
+		}
+	}
+	m.markDeleted(batch)
+	return nil
+}
+
+func (m *ShortenerRepository) markDeleted(batch []*domain.DeleteData) {
+	for _, v := range batch {
+		if v == nil {
+			continue
+		}
+		if !m.isDeletable(v) {
+			v.Status = m.notDeletedStatus(v)
+			continue
+		}
+		v.Status = domain.DeleteStatusDeleted
+		at := v.DeletedAt
+		if at.IsZero() {
+			at = time.Now()
+		}
+		shortID := domain.ShortID(v.ShortID)
+		uid := domain.UID(v.UID)
+		owners := m.owners[shortID]
+		owners[uid] = true
+		if _, ok := m.ownerDeletedAt[shortID]; !ok {
+			m.ownerDeletedAt[shortID] = make(map[domain.UID]time.Time)
+		}
+		m.ownerDeletedAt[shortID][uid] = at
+		if !hasActiveOwner(owners) {
+			m.deleted[shortID] = at
+		}
+	}
+}
+
+// RestoreWith - отменить удаление ссылок пользователем, удаленных не раньше since.
+// persist вызывается с идентификаторами, удаление которых будет отменено, до изменения;
+// если он вернул ошибку, то ничего не восстанавливается.
+func (m *ShortenerRepository) RestoreWith(uid domain.UID, shortIDs []domain.ShortID, since time.Time, persist func([]domain.ShortID) error) ([]domain.RestoreResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	results := make([]domain.RestoreResult, 0, len(shortIDs))
+	restoring := make([]domain.ShortID, 0, len(shortIDs))
+	claimed := make(map[domain.URL]domain.ShortID, len(shortIDs))
+	for _, shortID := range shortIDs {
+		status := m.restoreStatus(uid, shortID, since, claimed)
+		if status == domain.RestoreStatusRestored {
+			if _, ok := claimed[m.data[shortID]]; !ok {
+				claimed[m.data[shortID]] = shortID
+				restoring = append(restoring, shortID)
+			}
+		}
+		results = append(results, domain.RestoreResult{ShortID: shortID, Status: status})
+	}
+	if persist != nil && len(restoring) > 0 {
+		if err := persist(restoring); err != nil {
+			return nil, err
+		}
+	}
+	for _, shortID := range restoring {
+		url := m.data[shortID]
+		m.owners[shortID][uid] = false
+		delete(m.ownerDeletedAt[shortID], uid)
+		delete(m.deleted, shortID)
+		if m.ownership == domain.OwnershipSeparate {
+			if _, ok := m.userURLs[uid]; !ok {
+				m.userURLs[uid] = make(map[domain.URL]domain.ShortID)
+			}
+			m.userURLs[uid][url] = shortID
+		} else {
+			m.urlsToShortID[url] = shortID
+		}
+	}
+	return results, nil
+}
+
+// restoreStatus - можно ли отменить удаление ссылки, claimed - URL ссылок, восстанавливаемых в этом же запросе.
+func (m *ShortenerRepository) restoreStatus(uid domain.UID, shortID domain.ShortID, since time.Time, claimed map[domain.URL]domain.ShortID) domain.RestoreStatus {
+	deleted, ok := m.owners[shortID][uid]
+	if !ok || !deleted {
+		return domain.RestoreStatusNotFound
+	}
+	if !since.IsZero() && m.ownerDeletedAt[shortID][uid].Before(since) {
+		return domain.RestoreStatusNotFound
+	}
+	if m.isExpired(shortID, time.Now()) {
+		return domain.RestoreStatusNotFound
+	}
+	url := m.data[shortID]
+	if claimedID, ok := claimed[url]; ok && claimedID != shortID {
+		return domain.RestoreStatusConflict
+	}
+	if existID, exist := m.findShortID(uid, url); exist && existID != shortID {
+		return domain.RestoreStatusConflict
+	}
+	return domain.RestoreStatusRestored
+}
+
+// Purgeable - есть ли записи или владельцы, удаленные раньше before.
+func (m *ShortenerRepository) Purgeable(before time.Time) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, owners := range m.ownerDeletedAt {
+		for _, at := range owners {
+			if at.Before(before) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PurgeDeleted - окончательно удалить записи, которые удалили все владельцы раньше before,
+// и убрать владельцев, удаливших у себя ссылку раньше before.
+func (m *ShortenerRepository) PurgeDeleted(_ context.Context, before time.Time) (*domain.PurgeResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	result := &domain.PurgeResult{}
+	for shortID, at := range m.deleted {
+		if at.Before(before) {
+			result.Owners += int64(len(m.owners[shortID]))
+			m.removeRecord(shortID)
+			result.Records++
+		}
+	}
+	for shortID, owners := range m.ownerDeletedAt {
+		for uid, at := range owners {
+			if !at.Before(before) {
+				continue
+			}
+			m.unlinkOwner(shortID, m.data[shortID], uid)
+			delete(m.owners[shortID], uid)
+			delete(owners, uid)
+			result.Owners++
+		}
+	}
+	return result, nil
+}
+
+func (m *ShortenerRepository) isDeletable(data *domain.DeleteData) bool {
+	deleted, ok := m.owners[domain.ShortID(data.ShortID)][domain.UID(data.UID)]
+	return ok && !deleted
+}
+
+// notDeletedStatus - почему ссылка не удалена: ее нет или пользователь ей не владеет.
+func (m *ShortenerRepository) notDeletedStatus(data *domain.DeleteData) domain.DeleteStatus {
+	owners, ok := m.owners[domain.ShortID(data.ShortID)]
+	if !ok {
+		return domain.DeleteStatusNotFound
+	}
+	if _, ok := owners[domain.UID(data.UID)]; !ok {
+		return domain.DeleteStatusNotOwned
+	}
+	return domain.DeleteStatusNotFound
+}
+
+func hasActiveOwner(owners map[domain.UID]bool) bool {
+	for _, deleted := range owners {
+		if !deleted {
+			return true
+		}
+	}
+	return false
+}
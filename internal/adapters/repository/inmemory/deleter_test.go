@@ -0,0 +1,115 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelete(t *testing.T) {
+	repo := NewShortenerRepository()
+	repo.Add(context.Background(), "afASDFqwe", &domain.Record{
+		UID: domain.UID("uuid"),
+		URL: domain.URL("http://svirex.ru"),
+	})
+	repo.Add(context.Background(), "gxtye5gsdf", &domain.Record{
+		UID: domain.UID("other"),
+		URL: domain.URL("http://ya.ru"),
+	})
+	deleter := NewDeleterRepository(repo)
+	batch := []*domain.DeleteData{
+		{UID: "uuid", ShortID: "afASDFqwe"},
+		{UID: "uuid", ShortID: "gxtye5gsdf"},
+		{UID: "uuid", ShortID: "missing"},
+		nil,
+	}
+	err := deleter.Delete(context.Background(), batch)
+	require.NoError(t, err)
+	require.Equal(t, domain.DeleteStatusDeleted, batch[0].Status)
+	require.Equal(t, domain.DeleteStatusNotOwned, batch[1].Status)
+	require.Equal(t, domain.DeleteStatusNotFound, batch[2].Status)
+	again := []*domain.DeleteData{{UID: "uuid", ShortID: "afASDFqwe"}}
+	require.NoError(t, deleter.Delete(context.Background(), again))
+	require.Equal(t, domain.DeleteStatusNotFound, again[0].Status)
+
+	_, err = repo.Get(context.Background(), "afASDFqwe")
+	require.ErrorIs(t, err, ports.ErrDeleted)
+	url, err := repo.Get(context.Background(), "gxtye5gsdf")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://ya.ru"), url)
+	require.Empty(t, repo.Deletable([]*domain.DeleteData{{UID: "uuid", ShortID: "afASDFqwe"}}))
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	repo := NewShortenerRepository()
+	deleter := NewDeleterRepository(repo)
+	_, err := repo.Add(ctx, "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(ctx, "old", &domain.Record{UID: "alice", URL: "http://ya.ru"})
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, deleter.Delete(ctx, []*domain.DeleteData{
+		{UID: "alice", ShortID: "first"},
+		{UID: "alice", ShortID: "old", DeletedAt: now.Add(-2 * time.Hour)},
+	}))
+
+	results, err := deleter.Restore(ctx, "alice", []domain.ShortID{"first", "old", "missing"}, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []domain.RestoreResult{
+		{ShortID: "first", Status: domain.RestoreStatusRestored},
+		{ShortID: "old", Status: domain.RestoreStatusNotFound},
+		{ShortID: "missing", Status: domain.RestoreStatusNotFound},
+	}, results)
+	url, err := repo.Get(ctx, "first")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://svirex.ru"), url)
+
+	_, err = repo.Add(ctx, "second", &domain.Record{UID: "bob", URL: "http://ya.ru"})
+	require.NoError(t, err)
+	results, err = deleter.Restore(ctx, "alice", []domain.ShortID{"old"}, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, domain.RestoreStatusConflict, results[0].Status)
+	_, err = repo.Get(ctx, "old")
+	require.ErrorIs(t, err, ports.ErrDeleted)
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+	repo := NewShortenerRepository()
+	deleter := NewDeleterRepository(repo)
+	_, err := repo.Add(ctx, "old", &domain.Record{UID: "alice", URL: "http://ya.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(ctx, "shared", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(ctx, "shared", &domain.Record{UID: "bob", URL: "http://svirex.ru"})
+	require.ErrorIs(t, err, ports.ErrAlreadyExists)
+	_, err = repo.Add(ctx, "fresh", &domain.Record{UID: "bob", URL: "http://typo.ru"})
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, deleter.Delete(ctx, []*domain.DeleteData{
+		{UID: "alice", ShortID: "old", DeletedAt: now.Add(-2 * time.Hour)},
+		{UID: "bob", ShortID: "shared", DeletedAt: now.Add(-2 * time.Hour)},
+		{UID: "bob", ShortID: "fresh", DeletedAt: now},
+	}))
+
+	result, err := deleter.PurgeDeleted(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, &domain.PurgeResult{Records: 1, Owners: 2}, result)
+	_, err = repo.Get(ctx, "old")
+	require.ErrorIs(t, err, ports.ErrNotFound)
+	require.False(t, repo.CheckShortIDExists("old"))
+	_, err = repo.Get(ctx, "shared")
+	require.NoError(t, err)
+	_, err = repo.Get(ctx, "fresh")
+	require.ErrorIs(t, err, ports.ErrDeleted)
+	urls, err := repo.UserURLs(ctx, "bob")
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, domain.ShortID("fresh"), urls[0].ShortID)
+	require.False(t, repo.Purgeable(now.Add(-time.Hour)))
+}
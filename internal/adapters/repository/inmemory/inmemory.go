@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/Svirex/microurl/internal/core/ports"
@@ -11,28 +12,81 @@ import (
 
 // ShortenerRepository - репозиторий для хранения записей в памяти
 type ShortenerRepository struct {
-	data          map[domain.ShortID]domain.URL
+	ownership domain.URLOwnership
+	data      map[domain.ShortID]domain.URL
+	// urlsToShortID - действующая запись для URL при общем владении.
 	urlsToShortID map[domain.URL]domain.ShortID
-	uidToRecords  map[domain.UID][]domain.URLData
-	mutex         sync.Mutex
+	// userURLs - действующие записи пользователя по URL при раздельном владении.
+	userURLs     map[domain.UID]map[domain.URL]domain.ShortID
+	uidToRecords map[domain.UID][]domain.URLData
+	// owners - владельцы записи, true - владелец удалил у себя ссылку.
+	owners map[domain.ShortID]map[domain.UID]bool
+	// creators - пользователь, создавший запись, только он может ее изменять.
+	creators map[domain.ShortID]domain.UID
+	// ownerDeletedAt - когда владелец удалил у себя ссылку.
+	ownerDeletedAt map[domain.ShortID]map[domain.UID]time.Time
+	expiresAt      map[domain.ShortID]time.Time
+	clicks         map[domain.ShortID][]domain.Click
+	// revisions - прежние состояния измененных записей.
+	revisions map[domain.ShortID][]domain.URLRevision
+	// deleted - записи, которые удалили все владельцы, и момент удаления последним владельцем.
+	deleted map[domain.ShortID]time.Time
+	mutex   sync.Mutex
 }
 
 var _ ports.ShortenerRepository = (*ShortenerRepository)(nil)
 
+// Option - дополнительный параметр репозитория.
+type Option func(*ShortenerRepository)
+
+// WithOwnership - режим владения ссылками, по умолчанию domain.OwnershipShared.
+func WithOwnership(ownership domain.URLOwnership) Option {
+	return func(m *ShortenerRepository) {
+		m.ownership = ownership
+	}
+}
+
 // NewShortenerRepository - новый репозиторий.
-func NewShortenerRepository() *ShortenerRepository {
-	return &ShortenerRepository{
-		data:          make(map[domain.ShortID]domain.URL),
-		urlsToShortID: make(map[domain.URL]domain.ShortID),
-		uidToRecords:  make(map[domain.UID][]domain.URLData),
+func NewShortenerRepository(opts ...Option) *ShortenerRepository {
+	m := &ShortenerRepository{
+		ownership:      domain.OwnershipShared,
+		data:           make(map[domain.ShortID]domain.URL),
+		urlsToShortID:  make(map[domain.URL]domain.ShortID),
+		userURLs:       make(map[domain.UID]map[domain.URL]domain.ShortID),
+		uidToRecords:   make(map[domain.UID][]domain.URLData),
+		owners:         make(map[domain.ShortID]map[domain.UID]bool),
+		creators:       make(map[domain.ShortID]domain.UID),
+		ownerDeletedAt: make(map[domain.ShortID]map[domain.UID]time.Time),
+		expiresAt:      make(map[domain.ShortID]time.Time),
+		clicks:         make(map[domain.ShortID][]domain.Click),
+		revisions:      make(map[domain.ShortID][]domain.URLRevision),
+		deleted:        make(map[domain.ShortID]time.Time),
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
 // Add - добавить запись.
 func (m *ShortenerRepository) Add(_ context.Context, shortID domain.ShortID, data *domain.Record) (domain.ShortID, error) {
+	return m.AddWith(shortID, data, nil)
+}
+
+// AddWith - добавить запись. persist вызывается под блокировкой репозитория после всех проверок,
+// но до изменения, с новой записью или со связью пользователя с уже существующей;
+// если он вернул ошибку, то ничего не добавляется.
+func (m *ShortenerRepository) AddWith(shortID domain.ShortID, data *domain.Record, persist func([]domain.URLData) error) (domain.ShortID, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
-	return m.addNewOrGetExistShortID(shortID, data.URL, data.UID)
+	if persist != nil {
+		if change, ok := m.pendingChange(shortID, data.URL, data.UID, data.ExpiresAt); ok {
+			if err := persist([]domain.URLData{change}); err != nil {
+				return domain.ShortID(""), err
+			}
+		}
+	}
+	return m.addNewOrGetExistShortID(shortID, data.URL, data.UID, data.ExpiresAt)
 }
 
 // Get - получить урл.
@@ -43,27 +97,116 @@ func (m *ShortenerRepository) Get(_ context.Context, shortID domain.ShortID) (do
 	if !ok {
 		return domain.URL(""), fmt.Errorf("get url from map repository: %w", ports.ErrNotFound)
 	}
+	if _, deleted := m.deleted[shortID]; deleted {
+		return domain.URL(""), fmt.Errorf("get url from map repository: %w", ports.ErrDeleted)
+	}
+	if m.isExpired(shortID, time.Now()) {
+		return domain.URL(""), fmt.Errorf("get url from map repository: %w", ports.ErrExpired)
+	}
 	return url, nil
 }
 
 // Batch - добавить несоклько записей.
 func (m *ShortenerRepository) Batch(_ context.Context, uid domain.UID, data []domain.BatchRecord) ([]domain.BatchRecord, error) {
+	return m.BatchWith(uid, data, nil)
+}
+
+// BatchWith - добавить несколько записей. persist вызывается под блокировкой репозитория после
+// проверки батча, но до изменения, с новыми записями и новыми связями пользователя с существующими;
+// если он вернул ошибку, то ничего не добавляется.
+func (m *ShortenerRepository) BatchWith(uid domain.UID, data []domain.BatchRecord, persist func([]domain.URLData) error) ([]domain.BatchRecord, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
+	if err := m.checkBatchShortIDs(uid, data); err != nil {
+		return nil, fmt.Errorf("batch to map repository: %w", err)
+	}
+	if persist != nil {
+		changes := make([]domain.URLData, 0, len(data))
+		seen := make(map[domain.URL]struct{}, len(data))
+		for i := range data {
+			record := &data[i]
+			if _, exist := seen[record.URL]; exist {
+				continue
+			}
+			seen[record.URL] = struct{}{}
+			if change, ok := m.pendingChange(record.ShortID, record.URL, uid, record.ExpiresAt); ok {
+				changes = append(changes, change)
+			}
+		}
+		if len(changes) > 0 {
+			if err := persist(changes); err != nil {
+				return nil, err
+			}
+		}
+	}
 	for i := range data {
 		record := &data[i]
-		shortID, _ := m.addNewOrGetExistShortID(record.ShortID, record.URL, uid)
+		shortID, err := m.addNewOrGetExistShortID(record.ShortID, record.URL, uid, record.ExpiresAt)
 		record.ShortID = shortID
+		record.Status = domain.BatchStatusCreated
+		if err != nil {
+			record.Status = domain.BatchStatusExisting
+		}
 	}
 	return data, nil
 }
 
 // UserURLs - получить все урлы для пользователя.
 func (m *ShortenerRepository) UserURLs(ctx context.Context, uid domain.UID) ([]domain.URLData, error) {
-	if _, ok := m.uidToRecords[uid]; !ok {
-		return make([]domain.URLData, 0), nil
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	records := make([]domain.URLData, len(m.uidToRecords[uid]))
+	copy(records, m.uidToRecords[uid])
+	return records, nil
+}
+
+// Stats - получить количество урлов и пользователей.
+func (m *ShortenerRepository) Stats(_ context.Context) (*domain.Stats, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	stats := &domain.Stats{
+		URLs: int64(len(m.data)),
+	}
+	for _, records := range m.uidToRecords {
+		if len(records) > 0 {
+			stats.Users++
+		}
 	}
-	return m.uidToRecords[uid], nil
+	return stats, nil
+}
+
+// PurgeExpired - удалить записи с истекшим сроком действия.
+func (m *ShortenerRepository) PurgeExpired(_ context.Context, now time.Time) (int64, error) {
+	return m.PurgeExpiredWith(now, nil)
+}
+
+// PurgeExpiredWith - удалить записи с истекшим сроком действия. persist вызывается под блокировкой
+// репозитория, только если есть что удалять; если он вернул ошибку, то ничего не удаляется.
+func (m *ShortenerRepository) PurgeExpiredWith(now time.Time, persist func() error) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if persist != nil && m.hasExpired(now) {
+		if err := persist(); err != nil {
+			return 0, err
+		}
+	}
+	var count int64
+	for shortID := range m.expiresAt {
+		if m.isExpired(shortID, now) {
+			m.removeRecord(shortID)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *ShortenerRepository) hasExpired(now time.Time) bool {
+	for shortID := range m.expiresAt {
+		if m.isExpired(shortID, now) {
+			return true
+		}
+	}
+	return false
 }
 
 // Shutdown - выключить сервис.
@@ -71,29 +214,186 @@ func (m *ShortenerRepository) Shutdown() error {
 	return nil
 }
 
-// CheckExists - проверить, что урл есть в репозитории
-func (m *ShortenerRepository) CheckExists(url domain.URL) (domain.ShortID, bool) {
-	shortID, exist := m.urlsToShortID[url]
-	return shortID, exist
+// CheckExists - найти действующую запись урла, которую пользователь uid получит при сокращении:
+// общую для всех при общем владении или свою при раздельном.
+func (m *ShortenerRepository) CheckExists(uid domain.UID, url domain.URL) (domain.ShortID, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.findShortID(uid, url)
+}
+
+// IsOwner - пользователь владеет записью и не удалял ее у себя.
+func (m *ShortenerRepository) IsOwner(shortID domain.ShortID, uid domain.UID) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	deleted, ok := m.owners[shortID][uid]
+	return ok && !deleted
+}
+
+// CheckShortIDExists - проверить, что короткий идентификатор уже занят
+func (m *ShortenerRepository) CheckShortIDExists(shortID domain.ShortID) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.shortIDExists(shortID)
+}
+
+// CheckBatch - проверить, что короткие идентификаторы новых записей батча свободны
+func (m *ShortenerRepository) CheckBatch(uid domain.UID, data []domain.BatchRecord) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.checkBatchShortIDs(uid, data)
+}
+
+func (m *ShortenerRepository) shortIDExists(shortID domain.ShortID) bool {
+	_, exist := m.data[shortID]
+	return exist
+}
+
+func (m *ShortenerRepository) checkBatchShortIDs(uid domain.UID, data []domain.BatchRecord) error {
+	urls := make(map[domain.URL]struct{}, len(data))
+	shortIDs := make(map[domain.ShortID]struct{}, len(data))
+	for i := range data {
+		record := &data[i]
+		if _, exist := m.findShortID(uid, record.URL); exist {
+			continue
+		}
+		if _, exist := urls[record.URL]; exist {
+			continue
+		}
+		urls[record.URL] = struct{}{}
+		_, inBatch := shortIDs[record.ShortID]
+		if inBatch || m.shortIDExists(record.ShortID) {
+			return &ports.ShortIDExistsError{ShortID: record.ShortID}
+		}
+		shortIDs[record.ShortID] = struct{}{}
+	}
+	return nil
+}
+
+// addNewOrGetExistShortID - добавить запись или, если для пользователя уже есть действующая запись урла,
+// сделать его владельцем этой записи и вернуть ErrAlreadyExists.
+func (m *ShortenerRepository) addNewOrGetExistShortID(shortID domain.ShortID, url domain.URL, uid domain.UID, expiresAt *time.Time) (domain.ShortID, error) {
+	if existID, exist := m.findShortID(uid, url); exist {
+		m.linkOwner(existID, uid)
+		return existID, fmt.Errorf("add new or get exist short id: %w", ports.ErrAlreadyExists)
+	}
+	if m.shortIDExists(shortID) {
+		return shortID, fmt.Errorf("add new or get exist short id: %w", &ports.ShortIDExistsError{ShortID: shortID})
+	}
+	m.addNewRecord(shortID, url, uid, expiresAt)
+	return shortID, nil
+}
+
+// pendingChange - что изменит добавление урла пользователем: новая запись или связь пользователя
+// с существующей записью (без срока действия). false - ничего не изменится или добавление не удастся.
+func (m *ShortenerRepository) pendingChange(shortID domain.ShortID, url domain.URL, uid domain.UID, expiresAt *time.Time) (domain.URLData, bool) {
+	if existID, exist := m.findShortID(uid, url); exist {
+		if deleted, owner := m.owners[existID][uid]; owner && !deleted {
+			return domain.URLData{}, false
+		}
+		return domain.URLData{ShortID: existID, URL: url}, true
+	}
+	if m.shortIDExists(shortID) {
+		return domain.URLData{}, false
+	}
+	return domain.URLData{ShortID: shortID, URL: url, ExpiresAt: expiresAt}, true
 }
 
-func (m *ShortenerRepository) addNewOrGetExistShortID(shortID domain.ShortID, url domain.URL, uid domain.UID) (domain.ShortID, error) {
-	if mapShortID, exist := m.CheckExists(url); exist {
-		return mapShortID, fmt.Errorf("add new or get exist short id: %w", ports.ErrAlreadyExists)
+// findShortID - действующая запись урла для пользователя. Просроченная запись удаляется,
+// запись, удаленная всеми владельцами, не считается действующей.
+func (m *ShortenerRepository) findShortID(uid domain.UID, url domain.URL) (domain.ShortID, bool) {
+	var shortID domain.ShortID
+	var exist bool
+	if m.ownership == domain.OwnershipSeparate {
+		shortID, exist = m.userURLs[uid][url]
 	} else {
-		m.addNewRecord(shortID, url, uid)
-		return shortID, nil
+		shortID, exist = m.urlsToShortID[url]
 	}
+	if !exist {
+		return domain.ShortID(""), false
+	}
+	if m.isExpired(shortID, time.Now()) {
+		m.removeRecord(shortID)
+		return domain.ShortID(""), false
+	}
+	if _, deleted := m.deleted[shortID]; deleted {
+		return domain.ShortID(""), false
+	}
+	return shortID, true
 }
 
-func (m *ShortenerRepository) addNewRecord(shortID domain.ShortID, url domain.URL, uid domain.UID) {
+func (m *ShortenerRepository) addNewRecord(shortID domain.ShortID, url domain.URL, uid domain.UID, expiresAt *time.Time) {
 	m.data[shortID] = url
-	m.urlsToShortID[url] = shortID
-	if _, ok := m.uidToRecords[uid]; !ok {
-		m.uidToRecords[uid] = make([]domain.URLData, 0)
+	if m.ownership == domain.OwnershipSeparate {
+		if _, ok := m.userURLs[uid]; !ok {
+			m.userURLs[uid] = make(map[domain.URL]domain.ShortID)
+		}
+		m.userURLs[uid][url] = shortID
+	} else {
+		m.urlsToShortID[url] = shortID
+	}
+	if expiresAt != nil {
+		m.expiresAt[shortID] = *expiresAt
+	}
+	m.creators[shortID] = uid
+	m.linkOwner(shortID, uid)
+}
+
+// linkOwner - сделать пользователя владельцем записи, в том числе снова, если он удалял ее у себя.
+func (m *ShortenerRepository) linkOwner(shortID domain.ShortID, uid domain.UID) {
+	if _, ok := m.owners[shortID]; !ok {
+		m.owners[shortID] = make(map[domain.UID]bool)
+	}
+	if _, owner := m.owners[shortID][uid]; owner {
+		m.owners[shortID][uid] = false
+		delete(m.ownerDeletedAt[shortID], uid)
+		return
+	}
+	m.owners[shortID][uid] = false
+	var expiresAt *time.Time
+	if t, ok := m.expiresAt[shortID]; ok {
+		expiresAt = &t
 	}
 	m.uidToRecords[uid] = append(m.uidToRecords[uid], domain.URLData{
-		ShortID: shortID,
-		URL:     url,
+		ShortID:   shortID,
+		URL:       m.data[shortID],
+		ExpiresAt: expiresAt,
 	})
 }
+
+func (m *ShortenerRepository) isExpired(shortID domain.ShortID, now time.Time) bool {
+	expiresAt, ok := m.expiresAt[shortID]
+	return ok && !now.Before(expiresAt)
+}
+
+func (m *ShortenerRepository) removeRecord(shortID domain.ShortID) {
+	url := m.data[shortID]
+	if m.urlsToShortID[url] == shortID {
+		delete(m.urlsToShortID, url)
+	}
+	delete(m.data, shortID)
+	delete(m.expiresAt, shortID)
+	delete(m.clicks, shortID)
+	delete(m.revisions, shortID)
+	delete(m.deleted, shortID)
+	for uid := range m.owners[shortID] {
+		m.unlinkOwner(shortID, url, uid)
+	}
+	delete(m.owners, shortID)
+	delete(m.ownerDeletedAt, shortID)
+	delete(m.creators, shortID)
+}
+
+// unlinkOwner - убрать запись из индексов пользователя.
+func (m *ShortenerRepository) unlinkOwner(shortID domain.ShortID, url domain.URL, uid domain.UID) {
+	if m.userURLs[uid][url] == shortID {
+		delete(m.userURLs[uid], url)
+	}
+	records := m.uidToRecords[uid]
+	for i := range records {
+		if records[i].ShortID == shortID {
+			m.uidToRecords[uid] = append(records[:i], records[i+1:]...)
+			break
+		}
+	}
+}
@@ -3,6 +3,7 @@ package inmemory
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/Svirex/microurl/internal/core/ports"
@@ -14,6 +15,8 @@ func TestNewShortenerRepository(t *testing.T) {
 	require.Len(t, repo.data, 0)
 	require.Len(t, repo.urlsToShortID, 0)
 	require.Len(t, repo.uidToRecords, 0)
+	require.Len(t, repo.owners, 0)
+	require.Len(t, repo.expiresAt, 0)
 }
 
 func TestAddNew(t *testing.T) {
@@ -128,3 +131,241 @@ func TestBatchWithExist(t *testing.T) {
 	require.Equal(t, shortID, b[0].ShortID)
 	require.Equal(t, record.URL, b[0].URL)
 }
+
+func TestAddShortIDExists(t *testing.T) {
+	shortID := domain.ShortID("afASDFqwe")
+	repo := NewShortenerRepository()
+	_, err := repo.Add(context.Background(), shortID, &domain.Record{
+		UID: domain.UID("uuid"),
+		URL: domain.URL("http://svirex.ru"),
+	})
+	require.NoError(t, err)
+	_, err = repo.Add(context.Background(), shortID, &domain.Record{
+		UID: domain.UID("uuid"),
+		URL: domain.URL("http://ya.ru"),
+	})
+	require.ErrorIs(t, err, ports.ErrShortIDExists)
+	var existsErr *ports.ShortIDExistsError
+	require.ErrorAs(t, err, &existsErr)
+	require.Equal(t, shortID, existsErr.ShortID)
+	_, exists := repo.urlsToShortID["http://ya.ru"]
+	require.False(t, exists)
+}
+
+func TestBatchShortIDExists(t *testing.T) {
+	repo := NewShortenerRepository()
+	repo.Add(context.Background(), "alias", &domain.Record{
+		UID: domain.UID("uuid"),
+		URL: domain.URL("http://svirex.ru"),
+	})
+	batch := []domain.BatchRecord{
+		{
+			CorrID:  "1",
+			URL:     "http://ya.ru",
+			ShortID: "q4qwfd",
+		},
+		{
+			CorrID:  "2",
+			URL:     "http://google.ru",
+			ShortID: "alias",
+		},
+	}
+	_, err := repo.Batch(context.Background(), domain.UID(""), batch)
+	var existsErr *ports.ShortIDExistsError
+	require.ErrorAs(t, err, &existsErr)
+	require.Equal(t, domain.ShortID("alias"), existsErr.ShortID)
+	require.Len(t, repo.data, 1)
+}
+
+func TestGetExpired(t *testing.T) {
+	expiresAt := time.Now().Add(-time.Second)
+	repo := NewShortenerRepository()
+	repo.Add(context.Background(), "afASDFqwe", &domain.Record{
+		UID:       domain.UID("uuid"),
+		URL:       domain.URL("http://svirex.ru"),
+		ExpiresAt: &expiresAt,
+	})
+	_, err := repo.Get(context.Background(), "afASDFqwe")
+	require.ErrorIs(t, err, ports.ErrExpired)
+}
+
+func TestAddOverExpired(t *testing.T) {
+	expiresAt := time.Now().Add(-time.Second)
+	record := &domain.Record{
+		UID:       domain.UID("uuid"),
+		URL:       domain.URL("http://svirex.ru"),
+		ExpiresAt: &expiresAt,
+	}
+	repo := NewShortenerRepository()
+	repo.Add(context.Background(), "afASDFqwe", record)
+	record.ExpiresAt = nil
+	s, err := repo.Add(context.Background(), "gxtye5gsdf", record)
+	require.NoError(t, err)
+	require.Equal(t, domain.ShortID("gxtye5gsdf"), s)
+	url, err := repo.Get(context.Background(), s)
+	require.NoError(t, err)
+	require.Equal(t, record.URL, url)
+	_, err = repo.Get(context.Background(), "afASDFqwe")
+	require.ErrorIs(t, err, ports.ErrNotFound)
+	require.Len(t, repo.uidToRecords[record.UID], 1)
+}
+
+func TestPurgeExpired(t *testing.T) {
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	alive := now.Add(time.Minute)
+	repo := NewShortenerRepository()
+	repo.Add(context.Background(), "expired", &domain.Record{
+		UID:       domain.UID("uuid"),
+		URL:       domain.URL("http://svirex.ru"),
+		ExpiresAt: &expired,
+	})
+	repo.Add(context.Background(), "alive", &domain.Record{
+		UID:       domain.UID("uuid"),
+		URL:       domain.URL("http://ya.ru"),
+		ExpiresAt: &alive,
+	})
+	repo.Add(context.Background(), "forever", &domain.Record{
+		UID: domain.UID("uuid"),
+		URL: domain.URL("http://google.ru"),
+	})
+	count, err := repo.PurgeExpired(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+	require.Len(t, repo.data, 2)
+	require.Len(t, repo.urlsToShortID, 2)
+	require.Len(t, repo.uidToRecords["uuid"], 2)
+	_, exists := repo.data["expired"]
+	require.False(t, exists)
+}
+
+func TestSharedOwnership(t *testing.T) {
+	repo := NewShortenerRepository()
+	first, err := repo.Add(context.Background(), "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	second, err := repo.Add(context.Background(), "second", &domain.Record{UID: "bob", URL: "http://svirex.ru"})
+	require.ErrorIs(t, err, ports.ErrAlreadyExists)
+	require.Equal(t, first, second)
+
+	urls, err := repo.UserURLs(context.Background(), "bob")
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, first, urls[0].ShortID)
+
+	repo.MarkDeleted([]*domain.DeleteData{{UID: "alice", ShortID: string(first)}})
+	url, err := repo.Get(context.Background(), first)
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://svirex.ru"), url)
+
+	repo.MarkDeleted([]*domain.DeleteData{{UID: "bob", ShortID: string(first)}})
+	_, err = repo.Get(context.Background(), first)
+	require.ErrorIs(t, err, ports.ErrDeleted)
+
+	third, err := repo.Add(context.Background(), "third", &domain.Record{UID: "bob", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	require.Equal(t, domain.ShortID("third"), third)
+}
+
+func TestSeparateOwnership(t *testing.T) {
+	repo := NewShortenerRepository(WithOwnership(domain.OwnershipSeparate))
+	first, err := repo.Add(context.Background(), "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	second, err := repo.Add(context.Background(), "second", &domain.Record{UID: "bob", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	require.Equal(t, domain.ShortID("second"), second)
+	again, err := repo.Add(context.Background(), "third", &domain.Record{UID: "bob", URL: "http://svirex.ru"})
+	require.ErrorIs(t, err, ports.ErrAlreadyExists)
+	require.Equal(t, second, again)
+
+	repo.MarkDeleted([]*domain.DeleteData{{UID: "alice", ShortID: string(first)}})
+	_, err = repo.Get(context.Background(), first)
+	require.ErrorIs(t, err, ports.ErrDeleted)
+	_, err = repo.Get(context.Background(), second)
+	require.NoError(t, err)
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	repo := NewShortenerRepository()
+	_, err := repo.Add(ctx, "first", &domain.Record{UID: "alice", URL: "http://typo.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(ctx, "first", &domain.Record{UID: "bob", URL: "http://typo.ru"})
+	require.ErrorIs(t, err, ports.ErrAlreadyExists)
+	_, err = repo.Add(ctx, "second", &domain.Record{UID: "alice", URL: "http://ya.ru"})
+	require.NoError(t, err)
+
+	newURL := domain.URL("http://svirex.ru")
+	_, err = repo.Update(ctx, "mallory", "first", &domain.URLUpdate{URL: &newURL})
+	require.ErrorIs(t, err, ports.ErrNotFound)
+	_, err = repo.Update(ctx, "alice", "first", &domain.URLUpdate{URL: &newURL})
+	require.ErrorIs(t, err, ports.ErrSharedLink)
+	repo.MarkDeleted([]*domain.DeleteData{{UID: "bob", ShortID: "first"}})
+	taken := domain.URL("http://ya.ru")
+	_, err = repo.Update(ctx, "alice", "first", &domain.URLUpdate{URL: &taken})
+	require.ErrorIs(t, err, ports.ErrAlreadyExists)
+
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	changedAt := time.Now().UTC()
+	data, err := repo.Update(ctx, "alice", "first", &domain.URLUpdate{URL: &newURL, ExpiresAt: &expiresAt, UpdatedAt: changedAt})
+	require.NoError(t, err)
+	require.Equal(t, newURL, data.URL)
+	require.Equal(t, expiresAt, *data.ExpiresAt)
+
+	url, err := repo.Get(ctx, "first")
+	require.NoError(t, err)
+	require.Equal(t, newURL, url)
+	urls, err := repo.UserURLs(ctx, "alice")
+	require.NoError(t, err)
+	require.Equal(t, newURL, urls[0].URL)
+	_, err = repo.Update(ctx, "alice", "first", &domain.URLUpdate{ClearExpiry: true, UpdatedAt: changedAt.Add(time.Second)})
+	require.NoError(t, err)
+	revisions, err := repo.URLRevisions(ctx, "alice", "first")
+	require.NoError(t, err)
+	require.Equal(t, []domain.URLRevision{
+		{URL: "http://typo.ru", ChangedAt: changedAt},
+		{URL: newURL, ExpiresAt: &expiresAt, ChangedAt: changedAt.Add(time.Second)},
+	}, revisions)
+	_, err = repo.URLRevisions(ctx, "mallory", "first")
+	require.ErrorIs(t, err, ports.ErrNotFound)
+
+	id, err := repo.Add(ctx, "third", &domain.Record{UID: "carol", URL: newURL})
+	require.ErrorIs(t, err, ports.ErrAlreadyExists)
+	require.Equal(t, domain.ShortID("first"), id)
+	id, err = repo.Add(ctx, "third", &domain.Record{UID: "carol", URL: "http://typo.ru"})
+	require.NoError(t, err)
+	require.Equal(t, domain.ShortID("third"), id)
+}
+
+func TestOnlyCreatorUpdates(t *testing.T) {
+	ctx := context.Background()
+	repo := NewShortenerRepository()
+	_, err := repo.Add(ctx, "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(ctx, "second", &domain.Record{UID: "bob", URL: "http://svirex.ru"})
+	require.ErrorIs(t, err, ports.ErrAlreadyExists)
+
+	newURL := domain.URL("http://ya.ru")
+	_, err = repo.Update(ctx, "bob", "first", &domain.URLUpdate{URL: &newURL, UpdatedAt: time.Now()})
+	require.ErrorIs(t, err, ports.ErrNotCreator)
+	_, err = repo.URLRevisions(ctx, "bob", "first")
+	require.ErrorIs(t, err, ports.ErrNotCreator)
+	url, err := repo.Get(ctx, "first")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://svirex.ru"), url)
+
+	_, err = repo.Update(ctx, "alice", "first", &domain.URLUpdate{URL: &newURL, UpdatedAt: time.Now()})
+	require.ErrorIs(t, err, ports.ErrSharedLink)
+	url, err = repo.Get(ctx, "first")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://svirex.ru"), url)
+	urls, err := repo.UserURLs(ctx, "bob")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://svirex.ru"), urls[0].URL)
+
+	repo.MarkDeleted([]*domain.DeleteData{{UID: "bob", ShortID: "first"}})
+	_, err = repo.Update(ctx, "alice", "first", &domain.URLUpdate{URL: &newURL, UpdatedAt: time.Now()})
+	require.NoError(t, err)
+	revisions, err := repo.URLRevisions(ctx, "alice", "first")
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+}
@@ -0,0 +1,62 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// KeyPoolRepository - хранилище ключей в памяти.
+type KeyPoolRepository struct {
+	records *ShortenerRepository
+	keys    []domain.ShortID
+	mutex   sync.Mutex
+}
+
+var _ ports.KeyPoolRepository = (*KeyPoolRepository)(nil)
+
+// NewKeyPoolRepository - новое хранилище ключей для записей records.
+func NewKeyPoolRepository(records *ShortenerRepository) *KeyPoolRepository {
+	return &KeyPoolRepository{
+		records: records,
+	}
+}
+
+// TakeKeys - забрать до n ключей.
+func (r *KeyPoolRepository) TakeKeys(_ context.Context, n int) ([]domain.ShortID, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	n = min(n, len(r.keys))
+	keys := make([]domain.ShortID, n)
+	copy(keys, r.keys[:n])
+	r.keys = r.keys[n:]
+	return keys, nil
+}
+
+// PutKeys - положить ключи, которые еще не заняты записями.
+func (r *KeyPoolRepository) PutKeys(_ context.Context, keys []domain.ShortID) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.keys = r.records.FreeKeys(r.keys, keys)
+	return nil
+}
+
+// FreeKeys - дописать к stored ключи из keys, которые не заняты записями и которых еще нет в stored.
+func (m *ShortenerRepository) FreeKeys(stored []domain.ShortID, keys []domain.ShortID) []domain.ShortID {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	seen := make(map[domain.ShortID]struct{}, len(stored)+len(keys))
+	for _, key := range stored {
+		seen[key] = struct{}{}
+	}
+	for _, key := range keys {
+		if _, exist := seen[key]; exist || m.shortIDExists(key) {
+			continue
+		}
+		seen[key] = struct{}{}
+		stored = append(stored, key)
+	}
+	return stored
+}
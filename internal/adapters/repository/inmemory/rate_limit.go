@@ -0,0 +1,66 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// rateLimitSweepSize - при таком числе корзин удаляются полностью пополненные.
+const rateLimitSweepSize = 10000
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// fullAt - когда корзина пополнится полностью по своему ограничению.
+	fullAt time.Time
+}
+
+// RateLimitStore - корзины токенов в памяти.
+type RateLimitStore struct {
+	buckets map[string]*bucket
+	mutex   sync.Mutex
+}
+
+var _ ports.RateLimitStore = (*RateLimitStore)(nil)
+
+// NewRateLimitStore - новое хранилище.
+func NewRateLimitStore() *RateLimitStore {
+	return &RateLimitStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Take - забрать токен из корзины.
+func (s *RateLimitStore) Take(_ context.Context, key string, limit domain.RateLimit, now time.Time) (bool, time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= rateLimitSweepSize {
+			s.sweep(now)
+		}
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+	tokens, allowed, retryAfter := limit.Take(b.tokens, now.Sub(b.updatedAt))
+	b.tokens = tokens
+	if now.After(b.updatedAt) {
+		b.updatedAt = now
+	}
+	b.fullAt = limit.FullAt(b.tokens, b.updatedAt)
+	return allowed, retryAfter, nil
+}
+
+// sweep - удалить корзины, которые уже пополнились полностью, они ничем не отличаются от новых.
+// Каждая корзина проверяется по моменту пополнения для своего ограничения.
+func (s *RateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitSweepUsesBucketLimit(t *testing.T) {
+	store := NewRateLimitStore()
+	now := time.Now()
+	slow := domain.RateLimit{Rate: 0.1, Burst: 1}
+	fast := domain.RateLimit{Rate: 10, Burst: 1}
+	_, _, err := store.Take(context.Background(), "slow", slow, now)
+	require.NoError(t, err)
+	_, _, err = store.Take(context.Background(), "fast", fast, now)
+	require.NoError(t, err)
+
+	store.sweep(now.Add(time.Second))
+	require.Contains(t, store.buckets, "slow")
+	require.NotContains(t, store.buckets, "fast")
+
+	allowed, _, err := store.Take(context.Background(), "slow", slow, now.Add(time.Second))
+	require.NoError(t, err)
+	require.False(t, allowed)
+
+	store.sweep(now.Add(10 * time.Second))
+	require.Empty(t, store.buckets)
+}
@@ -0,0 +1,142 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// Update - изменить ссылку пользователя.
+func (m *ShortenerRepository) Update(_ context.Context, uid domain.UID, shortID domain.ShortID, update *domain.URLUpdate) (*domain.URLData, error) {
+	data, err := m.UpdateWith(uid, shortID, update, nil)
+	if err != nil {
+		return nil, fmt.Errorf("update url in map repository: %w", err)
+	}
+	return data, nil
+}
+
+// UpdateWith - изменить ссылку пользователя. persist вызывается с новым состоянием ссылки
+// после всех проверок, но до изменения; если он вернул ошибку, то ссылка не меняется.
+// Изменять ссылку может только ее создатель, остальные владельцы получают ErrNotCreator.
+// Пока у ссылки есть другие действующие владельцы, ее изменение возвращает ErrSharedLink.
+func (m *ShortenerRepository) UpdateWith(uid domain.UID, shortID domain.ShortID, update *domain.URLUpdate, persist func(*domain.URLData) error) (*domain.URLData, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if err := m.checkCreator(shortID, uid); err != nil {
+		return nil, err
+	}
+	for owner, deleted := range m.owners[shortID] {
+		if owner != uid && !deleted {
+			return nil, ports.ErrSharedLink
+		}
+	}
+	if m.isExpired(shortID, time.Now()) {
+		return nil, ports.ErrExpired
+	}
+	oldURL := m.data[shortID]
+	var oldExpiresAt *time.Time
+	if t, ok := m.expiresAt[shortID]; ok {
+		oldExpiresAt = &t
+	}
+	result := &domain.URLData{
+		URL:       oldURL,
+		ShortID:   shortID,
+		ExpiresAt: oldExpiresAt,
+	}
+	if update.URL != nil {
+		result.URL = *update.URL
+	}
+	switch {
+	case update.ClearExpiry:
+		result.ExpiresAt = nil
+	case update.ExpiresAt != nil:
+		t := *update.ExpiresAt
+		result.ExpiresAt = &t
+	}
+	if result.URL != oldURL {
+		if existID, exist := m.findShortID(uid, result.URL); exist && existID != shortID {
+			return nil, ports.ErrAlreadyExists
+		}
+	}
+	if persist != nil {
+		if err := persist(result); err != nil {
+			return nil, err
+		}
+	}
+	m.revisions[shortID] = append(m.revisions[shortID], domain.URLRevision{
+		URL:       oldURL,
+		ExpiresAt: oldExpiresAt,
+		ChangedAt: update.UpdatedAt,
+	})
+	m.reindexURL(shortID, oldURL, result.URL)
+	m.data[shortID] = result.URL
+	delete(m.expiresAt, shortID)
+	if result.ExpiresAt != nil {
+		m.expiresAt[shortID] = *result.ExpiresAt
+	}
+	for owner := range m.owners[shortID] {
+		records := m.uidToRecords[owner]
+		for i := range records {
+			if records[i].ShortID == shortID {
+				records[i].URL = result.URL
+				records[i].ExpiresAt = result.ExpiresAt
+			}
+		}
+	}
+	return result, nil
+}
+
+// URLRevisions - история изменений ссылки, доступна только ее создателю.
+func (m *ShortenerRepository) URLRevisions(_ context.Context, uid domain.UID, shortID domain.ShortID) ([]domain.URLRevision, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if err := m.checkCreator(shortID, uid); err != nil {
+		return nil, fmt.Errorf("url revisions from map repository: %w", err)
+	}
+	revisions := make([]domain.URLRevision, len(m.revisions[shortID]))
+	copy(revisions, m.revisions[shortID])
+	return revisions, nil
+}
+
+// checkCreator - пользователь владеет действующей записью (иначе ErrNotFound) и создал ее (иначе ErrNotCreator).
+func (m *ShortenerRepository) checkCreator(shortID domain.ShortID, uid domain.UID) error {
+	if !m.isActiveOwner(shortID, uid) {
+		return ports.ErrNotFound
+	}
+	if m.creators[shortID] != uid {
+		return ports.ErrNotCreator
+	}
+	return nil
+}
+
+// isActiveOwner - запись существует, не удалена всеми владельцами, а пользователь не удалял ее у себя.
+func (m *ShortenerRepository) isActiveOwner(shortID domain.ShortID, uid domain.UID) bool {
+	if _, ok := m.data[shortID]; !ok {
+		return false
+	}
+	if _, deleted := m.deleted[shortID]; deleted {
+		return false
+	}
+	deleted, ok := m.owners[shortID][uid]
+	return ok && !deleted
+}
+
+// reindexURL - перенести поиск действующей записи со старого урла на новый.
+func (m *ShortenerRepository) reindexURL(shortID domain.ShortID, oldURL, newURL domain.URL) {
+	if oldURL == newURL {
+		return
+	}
+	if m.urlsToShortID[oldURL] == shortID {
+		delete(m.urlsToShortID, oldURL)
+		m.urlsToShortID[newURL] = shortID
+	}
+	for owner := range m.owners[shortID] {
+		if m.userURLs[owner][oldURL] == shortID {
+			delete(m.userURLs[owner], oldURL)
+			m.userURLs[owner][newURL] = shortID
+		}
+	}
+}
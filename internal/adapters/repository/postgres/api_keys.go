@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// APIKeyRepository - API ключи в таблице api_keys.
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+var _ ports.APIKeyRepository = (*APIKeyRepository)(nil)
+
+// NewAPIKeyRepository - новое хранилище.
+func NewAPIKeyRepository(db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{
+		db: db,
+	}
+}
+
+// Add - сохранить ключ.
+func (r *APIKeyRepository) Add(ctx context.Context, key *domain.APIKey) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO api_keys (id, uid, name, hash, scopes, created_at, revoked_at)
+							VALUES ($1, $2, $3, $4, $5, $6, $7);`,
+		key.ID, string(key.UID), key.Name, key.Hash, scopesToStrings(key.Scopes), key.CreatedAt, key.RevokedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ports.ErrAlreadyExists
+		}
+		return fmt.Errorf("postgres api keys, add: %w", err)
+	}
+	return nil
+}
+
+// Get - получить ключ по ID.
+func (r *APIKeyRepository) Get(ctx context.Context, id string) (*domain.APIKey, error) {
+	row := r.db.QueryRow(ctx, `SELECT id, uid, name, hash, scopes, created_at, revoked_at
+							FROM api_keys WHERE id = $1;`, id)
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ports.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres api keys, get: %w", err)
+	}
+	return key, nil
+}
+
+// UserKeys - ключи пользователя в порядке выпуска.
+func (r *APIKeyRepository) UserKeys(ctx context.Context, uid domain.UID) ([]domain.APIKey, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, uid, name, hash, scopes, created_at, revoked_at
+							FROM api_keys WHERE uid = $1 ORDER BY created_at;`, string(uid))
+	if err != nil {
+		return nil, fmt.Errorf("postgres api keys, user keys: %w", err)
+	}
+	defer rows.Close()
+	var keys []domain.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("postgres api keys, user keys, scan: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("postgres api keys, user keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke - отозвать ключ пользователя.
+func (r *APIKeyRepository) Revoke(ctx context.Context, uid domain.UID, id string, at time.Time) error {
+	tag, err := r.db.Exec(ctx, `UPDATE api_keys SET revoked_at = $3
+							WHERE id = $1 AND uid = $2 AND revoked_at IS NULL;`, id, string(uid), at)
+	if err != nil {
+		return fmt.Errorf("postgres api keys, revoke: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ports.ErrNotFound
+	}
+	return nil
+}
+
+func scanAPIKey(row pgx.Row) (*domain.APIKey, error) {
+	var key domain.APIKey
+	var uid string
+	var scopes []string
+	err := row.Scan(&key.ID, &uid, &key.Name, &key.Hash, &scopes, &key.CreatedAt, &key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	key.UID = domain.UID(uid)
+	for _, scope := range scopes {
+		key.Scopes = append(key.Scopes, domain.Scope(scope))
+	}
+	return &key, nil
+}
+
+func scopesToStrings(scopes []domain.Scope) []string {
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		result = append(result, string(scope))
+	}
+	return result
+}
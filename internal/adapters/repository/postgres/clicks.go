@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ClickRepository - репозиторий переходов по ссылкам.
+type ClickRepository struct {
+	db     *pgxpool.Pool
+	logger ports.Logger
+}
+
+// NewClickRepository - новый репозиторий.
+func NewClickRepository(db *pgxpool.Pool, logger ports.Logger) *ClickRepository {
+	return &ClickRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+var _ ports.ClickRepository = (*ClickRepository)(nil)
+
+// SaveClicks - сохранить переходы по ссылкам.
+func (r *ClickRepository) SaveClicks(ctx context.Context, batch []*domain.Click) error {
+	rows := make([][]any, 0, len(batch))
+	for _, click := range batch {
+		if click != nil {
+			rows = append(rows, []any{click.ShortID, click.ClickedAt, click.Referrer, click.UserAgent, click.IP})
+		}
+	}
+	_, err := r.db.CopyFrom(ctx,
+		pgx.Identifier{"clicks"},
+		[]string{"short_id", "clicked_at", "referrer", "user_agent", "ip"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("click repository, save clicks: %w", err)
+	}
+	return nil
+}
+
+// ClickStats - статистика переходов по ссылке пользователя.
+func (r *ClickRepository) ClickStats(ctx context.Context, uid domain.UID, shortID domain.ShortID) (*domain.LinkStats, error) {
+	var owned bool
+	err := r.db.QueryRow(ctx, `SELECT true FROM records
+							   JOIN users ON records.id=users.record_id
+							   WHERE records.short_id=$1 AND users.uid=$2 LIMIT 1;`, shortID, uid).Scan(&owned)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("click repository, click stats: %w", ports.ErrNotFound)
+		}
+		return nil, fmt.Errorf("click repository, click stats, check owner: %w", err)
+	}
+	stats := &domain.LinkStats{}
+	err = r.db.QueryRow(ctx, `SELECT count(*), count(DISTINCT ip) FROM clicks
+							  WHERE short_id=$1;`, shortID).Scan(&stats.TotalClicks, &stats.UniqueVisitors)
+	if err != nil {
+		return nil, fmt.Errorf("click repository, click stats, select totals: %w", err)
+	}
+	rows, err := r.db.Query(ctx, `SELECT (clicked_at AT TIME ZONE 'UTC')::date AS day, count(*) FROM clicks
+								  WHERE short_id=$1
+								  GROUP BY day ORDER BY day;`, shortID)
+	if err != nil {
+		return nil, fmt.Errorf("click repository, click stats, query daily: %w", err)
+	}
+	stats.Daily, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.DailyClicks, error) {
+		var day time.Time
+		var d domain.DailyClicks
+		err := row.Scan(&day, &d.Clicks)
+		d.Date = day.Format(domain.DateLayout)
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("click repository, click stats, collect daily: %w", err)
+	}
+	return stats, nil
+}
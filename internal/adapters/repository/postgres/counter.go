@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Counter - счетчик на основе последовательности short_id_seq.
+type Counter struct {
+	db *pgxpool.Pool
+}
+
+var _ ports.Counter = (*Counter)(nil)
+
+// NewCounter - новый счетчик.
+func NewCounter(db *pgxpool.Pool) *Counter {
+	return &Counter{
+		db: db,
+	}
+}
+
+// Next - следующее значение последовательности.
+func (c *Counter) Next(ctx context.Context) (uint64, error) {
+	var value int64
+	err := c.db.QueryRow(ctx, "SELECT nextval('short_id_seq');").Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("postgres counter, nextval: %w", err)
+	}
+	return uint64(value), nil
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DeadLetterRepository - ссылки, удаление которых не удалось записать, в таблице deletion_dead_letters.
+type DeadLetterRepository struct {
+	db *pgxpool.Pool
+}
+
+var _ ports.DeadLetterRepository = (*DeadLetterRepository)(nil)
+
+// NewDeadLetterRepository - новое хранилище.
+func NewDeadLetterRepository(db *pgxpool.Pool) *DeadLetterRepository {
+	return &DeadLetterRepository{
+		db: db,
+	}
+}
+
+// Add - сохранить ссылки.
+func (r *DeadLetterRepository) Add(ctx context.Context, letters []domain.DeadLetter) error {
+	batch := &pgx.Batch{}
+	for _, letter := range letters {
+		batch.Queue(`INSERT INTO deletion_dead_letters (job_id, uid, short_id, error, attempts, failed_at)
+					 VALUES ($1, $2, $3, $4, $5, $6);`,
+			letter.JobID, string(letter.UID), string(letter.ShortID), letter.Error, letter.Attempts, letter.FailedAt)
+	}
+	err := r.db.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return fmt.Errorf("postgres dead letters, add: %w", err)
+	}
+	return nil
+}
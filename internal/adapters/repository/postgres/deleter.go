@@ -2,78 +2,232 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // DeleterRepository - репозиторий.
 type DeleterRepository struct {
-	db     *pgxpool.Pool
-	logger ports.Logger
+	db        *pgxpool.Pool
+	logger    ports.Logger
+	ownership domain.URLOwnership
 }
 
-// NewDeleterRepository - новый репозиторий
-func NewDeleterRepository(db *pgxpool.Pool, logger ports.Logger) *DeleterRepository {
+// NewDeleterRepository - новый репозиторий, ownership - режим владения ссылками.
+func NewDeleterRepository(db *pgxpool.Pool, logger ports.Logger, ownership domain.URLOwnership) *DeleterRepository {
 	return &DeleterRepository{
-		db:     db,
-		logger: logger,
+		db:        db,
+		logger:    logger,
+		ownership: ownership,
 	}
 }
 
 var _ ports.DeleterRepository = (*DeleterRepository)(nil)
 
-// Delete - помечает урлы как удаленные
+// deleteKey - ссылка пользователя, удаленная в батче.
+type deleteKey struct {
+	uid     string
+	shortID string
+}
+
+// Delete - удаляет ссылки у владельцев. Запись помечается удаленной, когда ее удалили все владельцы.
+// Для неудаленных ссылок статус not_owned ставится, если запись есть, но пользователь не среди ее владельцев.
 func (r *DeleterRepository) Delete(ctx context.Context, batch []*domain.DeleteData) error {
-	uids := make([]string, 0)
-	shortIDs := make([]string, 0)
+	uids := make([]string, 0, len(batch))
+	shortIDs := make([]string, 0, len(batch))
 	for _, v := range batch {
 		if v != nil {
 			uids = append(uids, v.UID)
 			shortIDs = append(shortIDs, v.ShortID)
 		}
 	}
-	i := 1
-	uidsPlacement := ""
-	first := true
-	for ; i <= len(uids); i++ {
-		if first {
-			uidsPlacement += fmt.Sprintf("$%d", i)
-			first = false
-		} else {
-			uidsPlacement += fmt.Sprintf(",$%d", i)
+	trx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("deleter repository, delete, start trx: %w", err)
+	}
+	defer trx.Rollback(ctx)
+	rows, err := trx.Query(ctx, `UPDATE users SET is_deleted=true, deleted_at=now()
+								 FROM records, unnest($1::text[], $2::text[]) AS d(uid, short_id)
+								 WHERE users.record_id=records.id AND users.uid::text=d.uid
+								 AND records.short_id=d.short_id AND NOT users.is_deleted
+								 RETURNING users.record_id, d.uid, d.short_id;`, uids, shortIDs)
+	if err != nil {
+		return fmt.Errorf("deleter repository, delete owners: %w", err)
+	}
+	var recordIDs []int
+	deleted := make(map[deleteKey]struct{})
+	var recordID int
+	var key deleteKey
+	_, err = pgx.ForEachRow(rows, []any{&recordID, &key.uid, &key.shortID}, func() error {
+		recordIDs = append(recordIDs, recordID)
+		deleted[key] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("deleter repository, collect deleted owners: %w", err)
+	}
+	_, err = trx.Exec(ctx, `UPDATE records SET is_deleted=true, deleted_at=now()
+							WHERE id = ANY($1) AND NOT EXISTS (
+								SELECT 1 FROM users WHERE users.record_id=records.id AND NOT users.is_deleted
+							);`, recordIDs)
+	if err != nil {
+		return fmt.Errorf("deleter repository, delete records without owners: %w", err)
+	}
+	rows, err = trx.Query(ctx, `SELECT d.uid, d.short_id
+								FROM unnest($1::text[], $2::text[]) AS d(uid, short_id)
+								JOIN records ON records.short_id=d.short_id
+								WHERE NOT EXISTS (
+									SELECT 1 FROM users WHERE users.record_id=records.id AND users.uid::text=d.uid
+								);`, uids, shortIDs)
+	if err != nil {
+		return fmt.Errorf("deleter repository, delete, select not owned: %w", err)
+	}
+	notOwned := make(map[deleteKey]struct{})
+	_, err = pgx.ForEachRow(rows, []any{&key.uid, &key.shortID}, func() error {
+		notOwned[key] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("deleter repository, collect not owned: %w", err)
+	}
+	err = trx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("deleter repository, delete, commit trx: %w", err)
+	}
+	for _, v := range batch {
+		if v == nil {
+			continue
+		}
+		k := deleteKey{uid: v.UID, shortID: v.ShortID}
+		v.Status = domain.DeleteStatusNotFound
+		if _, ok := deleted[k]; ok {
+			v.Status = domain.DeleteStatusDeleted
+		} else if _, ok := notOwned[k]; ok {
+			v.Status = domain.DeleteStatusNotOwned
 		}
+	}
+	return nil
+}
 
+// Restore - отменить удаление ссылок. Сначала берутся advisory-блокировки урлов всех ссылок,
+// как при добавлении, чтобы восстановленная запись не оказалась второй действующей записью урла.
+func (r *DeleterRepository) Restore(ctx context.Context, uid domain.UID, shortIDs []domain.ShortID, since time.Time) ([]domain.RestoreResult, error) {
+	trx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("deleter repository, restore, start trx: %w", err)
+	}
+	defer trx.Rollback(ctx)
+	rows, err := trx.Query(ctx, "SELECT url FROM records WHERE short_id = ANY($1);", shortIDs)
+	if err != nil {
+		return nil, fmt.Errorf("deleter repository, restore, select urls: %w", err)
+	}
+	urls, err := pgx.CollectRows(rows, pgx.RowTo[domain.URL])
+	if err != nil {
+		return nil, fmt.Errorf("deleter repository, restore, collect urls: %w", err)
+	}
+	keys := make([]string, 0, len(urls))
+	for _, url := range urls {
+		keys = append(keys, urlLockKey(r.ownership, uid, url))
+	}
+	err = lockURLs(ctx, trx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("deleter repository, restore: %w", err)
 	}
-	first = true
-	shortIDsPlacement := ""
-	for ; i <= 2*len(shortIDs); i++ {
-		if first {
-			shortIDsPlacement += fmt.Sprintf("$%d", i)
-			first = false
-		} else {
-			shortIDsPlacement += fmt.Sprintf(",$%d", i)
+	results := make([]domain.RestoreResult, 0, len(shortIDs))
+	for _, shortID := range shortIDs {
+		status, err := r.restore(ctx, trx, uid, shortID, since)
+		if err != nil {
+			return nil, fmt.Errorf("deleter repository, restore: %w", err)
 		}
+		results = append(results, domain.RestoreResult{ShortID: shortID, Status: status})
+	}
+	err = trx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("deleter repository, restore, commit trx: %w", err)
+	}
+	return results, nil
+}
+
+func (r *DeleterRepository) restore(ctx context.Context, trx pgx.Tx, uid domain.UID, shortID domain.ShortID, since time.Time) (domain.RestoreStatus, error) {
+	var recordID int64
+	var url domain.URL
+	var expiresAt *time.Time
+	err := trx.QueryRow(ctx, `SELECT records.id, records.url, records.expires_at FROM records
+							  JOIN users ON records.id = users.record_id
+							  WHERE records.short_id = $1 AND users.uid::text = $2::text
+								AND users.is_deleted AND users.deleted_at >= $3
+							  FOR UPDATE OF users;`, shortID, uid, since).Scan(&recordID, &url, &expiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.RestoreStatusNotFound, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("select deleted record: %w", err)
+	}
+	if expiresAt != nil && !time.Now().Before(*expiresAt) {
+		return domain.RestoreStatusNotFound, nil
+	}
+	err = purgeExpiredURLs(ctx, trx, []string{string(url)})
+	if err != nil {
+		return "", err
+	}
+	taken, err := urlTaken(ctx, trx, r.ownership, uid, recordID, url)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return domain.RestoreStatusConflict, nil
 	}
-	values := make([]interface{}, 0, 2*len(uids))
-	for _, v := range uids {
-		values = append(values, v)
+	_, err = trx.Exec(ctx, `UPDATE users SET is_deleted=false, deleted_at=NULL
+							WHERE record_id = $1 AND uid::text = $2::text;`, recordID, uid)
+	if err != nil {
+		return "", fmt.Errorf("restore owner: %w", err)
 	}
-	for _, v := range shortIDs {
-		values = append(values, v)
+	_, err = trx.Exec(ctx, "UPDATE records SET is_deleted=false, deleted_at=NULL WHERE id = $1;", recordID)
+	if err != nil {
+		return "", fmt.Errorf("restore record: %w", err)
 	}
+	return domain.RestoreStatusRestored, nil
+}
 
-	_, err := r.db.Exec(ctx, fmt.Sprintf(`UPDATE records SET is_deleted=true
-				FROM (
-					SELECT records.id FROM records
-					JOIN users ON records.id=users.record_id
-					WHERE users.uid IN (%s) AND records.short_id IN (%s)
-				) as d
-				WHERE records.id=d.id;`, uidsPlacement, shortIDsPlacement), values...)
+// PurgeDeleted - окончательно удалить записи, которые удалили все владельцы раньше before, вместе с их владельцами,
+// переходами и историей изменений, а также строки владельцев, удаливших у себя ссылку раньше before.
+func (r *DeleterRepository) PurgeDeleted(ctx context.Context, before time.Time) (*domain.PurgeResult, error) {
+	trx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return fmt.Errorf("deleter repository, delete: %w", err)
+		return nil, fmt.Errorf("deleter repository, purge deleted, start trx: %w", err)
 	}
-	return nil
+	defer trx.Rollback(ctx)
+	result := &domain.PurgeResult{}
+	tag, err := trx.Exec(ctx, `DELETE FROM users USING records
+							   WHERE users.record_id = records.id AND records.is_deleted AND records.deleted_at < $1;`, before)
+	if err != nil {
+		return nil, fmt.Errorf("deleter repository, purge deleted, delete owners of records: %w", err)
+	}
+	result.Owners += tag.RowsAffected()
+	_, err = trx.Exec(ctx, `DELETE FROM clicks USING records
+							WHERE clicks.short_id = records.short_id AND records.is_deleted AND records.deleted_at < $1;`, before)
+	if err != nil {
+		return nil, fmt.Errorf("deleter repository, purge deleted, delete clicks: %w", err)
+	}
+	tag, err = trx.Exec(ctx, "DELETE FROM records WHERE is_deleted AND deleted_at < $1;", before)
+	if err != nil {
+		return nil, fmt.Errorf("deleter repository, purge deleted, delete records: %w", err)
+	}
+	result.Records = tag.RowsAffected()
+	tag, err = trx.Exec(ctx, "DELETE FROM users WHERE is_deleted AND deleted_at < $1;", before)
+	if err != nil {
+		return nil, fmt.Errorf("deleter repository, purge deleted, delete owners: %w", err)
+	}
+	result.Owners += tag.RowsAffected()
+	err = trx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("deleter repository, purge deleted, commit trx: %w", err)
+	}
+	return result, nil
 }
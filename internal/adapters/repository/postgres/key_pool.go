@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// KeyPoolRepository - хранилище ключей в таблице key_pool.
+type KeyPoolRepository struct {
+	db *pgxpool.Pool
+}
+
+var _ ports.KeyPoolRepository = (*KeyPoolRepository)(nil)
+
+// NewKeyPoolRepository - новое хранилище.
+func NewKeyPoolRepository(db *pgxpool.Pool) *KeyPoolRepository {
+	return &KeyPoolRepository{
+		db: db,
+	}
+}
+
+// TakeKeys - забрать до n ключей, конкурентные экземпляры сервиса получают разные ключи.
+func (repo *KeyPoolRepository) TakeKeys(ctx context.Context, n int) ([]domain.ShortID, error) {
+	rows, err := repo.db.Query(ctx, `DELETE FROM key_pool WHERE short_id IN (
+										SELECT short_id FROM key_pool LIMIT $1 FOR UPDATE SKIP LOCKED
+									 ) RETURNING short_id;`, n)
+	if err != nil {
+		return nil, fmt.Errorf("postgres key pool, take keys, query: %w", err)
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[domain.ShortID])
+	if err != nil {
+		return nil, fmt.Errorf("postgres key pool, take keys, collect rows: %w", err)
+	}
+	return keys, nil
+}
+
+// PutKeys - положить ключи, которые еще не заняты записями.
+func (repo *KeyPoolRepository) PutKeys(ctx context.Context, keys []domain.ShortID) error {
+	shortIDs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		shortIDs = append(shortIDs, string(key))
+	}
+	_, err := repo.db.Exec(ctx, `INSERT INTO key_pool (short_id)
+								 SELECT key FROM unnest($1::text[]) AS key
+								 WHERE NOT EXISTS (SELECT 1 FROM records WHERE records.short_id=key)
+								 ON CONFLICT DO NOTHING;`, shortIDs)
+	if err != nil {
+		return fmt.Errorf("postgres key pool, put keys: %w", err)
+	}
+	return nil
+}
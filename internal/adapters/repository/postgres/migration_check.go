@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MigrationCheck - проверка, что последняя миграция БД применилась без ошибок.
+type MigrationCheck struct {
+	db *pgxpool.Pool
+}
+
+var _ ports.HealthChecker = (*MigrationCheck)(nil)
+
+// NewMigrationCheck - новая проверка.
+func NewMigrationCheck(db *pgxpool.Pool) *MigrationCheck {
+	return &MigrationCheck{db: db}
+}
+
+// Name - имя проверки.
+func (c *MigrationCheck) Name() string {
+	return "migrations"
+}
+
+// Check - читаем версию миграций из таблицы golang-migrate.
+func (c *MigrationCheck) Check(ctx context.Context) error {
+	var version int64
+	var dirty bool
+	err := c.db.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1;").Scan(&version, &dirty)
+	if err != nil {
+		return fmt.Errorf("read migration version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/Svirex/microurl/internal/core/ports"
@@ -13,23 +14,67 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// shortIDConstraint - имя ограничения уникальности короткого идентификатора.
+const shortIDConstraint = "records_short_id_key"
+
+// existingSharedQuery - действующая запись урла, общая для всех пользователей.
+const existingSharedQuery = `SELECT id, short_id FROM records
+	WHERE url = $1::text AND NOT COALESCE(is_deleted, false)
+	ORDER BY id LIMIT 1`
+
+// existingSeparateQuery - действующая запись урла, которой владеет пользователь.
+const existingSeparateQuery = `SELECT records.id, records.short_id FROM records
+	JOIN users ON records.id = users.record_id
+	WHERE records.url = $1::text AND users.uid::text = $4::text AND NOT COALESCE(records.is_deleted, false)
+	ORDER BY records.id LIMIT 1`
+
+// addRecordQuery - взять действующую запись урла или вставить новую и сделать пользователя ее владельцем.
+// Параметры: url, short_id, expires_at, uid. Возвращает короткий идентификатор и признак новой записи.
+const addRecordQuery = `WITH existing AS (%s),
+inserted AS (
+	INSERT INTO records (url, short_id, expires_at, creator_uid)
+	SELECT $1::text, $2::varchar, $3::timestamptz, NULLIF($4::text, '')::uuid WHERE NOT EXISTS (SELECT 1 FROM existing)
+	RETURNING id, short_id
+),
+record AS (
+	SELECT id, short_id, false AS created FROM existing
+	UNION ALL
+	SELECT id, short_id, true AS created FROM inserted
+),
+owner AS (
+	INSERT INTO users (uid, record_id)
+	SELECT o.uid::uuid, record.id FROM record, (SELECT NULLIF($4::text, '') AS uid) o
+	WHERE o.uid IS NOT NULL
+	ON CONFLICT (uid, record_id) DO UPDATE SET is_deleted = false
+)
+SELECT short_id, created FROM record;`
+
 // PostgresRepository - репозиторий.
 type PostgresRepository struct {
-	db     *pgxpool.Pool
-	logger ports.Logger
+	db        *pgxpool.Pool
+	logger    ports.Logger
+	ownership domain.URLOwnership
+	addQuery  string
 }
 
-// NewPostgresRepository - новый репозиторий.
-func NewPostgresRepository(db *pgxpool.Pool, logger ports.Logger) *PostgresRepository {
+// NewPostgresRepository - новый репозиторий, ownership - режим владения ссылками.
+func NewPostgresRepository(db *pgxpool.Pool, logger ports.Logger, ownership domain.URLOwnership) *PostgresRepository {
+	existing := existingSharedQuery
+	if ownership == domain.OwnershipSeparate {
+		existing = existingSeparateQuery
+	}
 	return &PostgresRepository{
-		db:     db,
-		logger: logger,
+		db:        db,
+		logger:    logger,
+		ownership: ownership,
+		addQuery:  fmt.Sprintf(addRecordQuery, existing),
 	}
 }
 
 var _ ports.ShortenerRepository = (*PostgresRepository)(nil)
 
-// Add - добавить запись.
+// Add - добавить запись. Если для пользователя уже есть действующая запись урла,
+// то пользователь становится ее владельцем и возвращается ErrAlreadyExists.
 func (repo *PostgresRepository) Add(ctx context.Context, shortID domain.ShortID, data *domain.Record) (domain.ShortID, error) {
 	trx, err := repo.db.BeginTx(ctx, pgx.TxOptions{})
 
@@ -37,37 +82,41 @@ func (repo *PostgresRepository) Add(ctx context.Context, shortID domain.ShortID,
 		return shortID, fmt.Errorf("postgres repository, add, start transaction: %w", err)
 	}
 	defer trx.Rollback(ctx)
-	var id int
-	err = trx.QueryRow(ctx, `INSERT INTO records (url, short_id) 
-							 VALUES ($1, $2) RETURNING id;`, data.URL, shortID).Scan(&id)
+	err = purgeExpiredURLs(ctx, trx, []string{string(data.URL)})
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			var shortID domain.ShortID
-			err = repo.db.QueryRow(ctx, "SELECT short_id FROM records WHERE url=$1;", data.URL).Scan(&shortID)
-			if err != nil {
-				return shortID, fmt.Errorf("postgres repository, add, select short id: %w", err)
-			}
-			return shortID, ports.ErrAlreadyExists
-		}
-		return shortID, fmt.Errorf("postgres repository, add, insert url and short id: %w", err)
+		return shortID, fmt.Errorf("postgres repository, add: %w", err)
+	}
+	err = lockURLs(ctx, trx, []string{repo.lockKey(data.UID, data.URL)})
+	if err != nil {
+		return shortID, fmt.Errorf("postgres repository, add: %w", err)
 	}
-	_, err = trx.Exec(ctx, `INSERT INTO users (uid, record_id)
-							VALUES ($1, $2)`, data.UID, id)
+	var id domain.ShortID
+	var created bool
+	err = trx.QueryRow(ctx, repo.addQuery, data.URL, shortID, data.ExpiresAt, data.UID).Scan(&id, &created)
 	if err != nil {
-		return shortID, fmt.Errorf("postgres repository, add, insert into users: %w", err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == shortIDConstraint {
+			return shortID, fmt.Errorf("postgres repository, add: %w", &ports.ShortIDExistsError{ShortID: shortID})
+		}
+		return shortID, fmt.Errorf("postgres repository, add, insert record and owner: %w", err)
 	}
 	err = trx.Commit(ctx)
 	if err != nil {
 		return shortID, fmt.Errorf("postgres repository, add, commit trx: %w", err)
 	}
-	return shortID, nil
+	if !created {
+		return id, ports.ErrAlreadyExists
+	}
+	return id, nil
 }
 
 // Get - получить урл.
 func (repo *PostgresRepository) Get(ctx context.Context, shortID domain.ShortID) (domain.URL, error) {
 	var url domain.URL
-	err := repo.db.QueryRow(ctx, "SELECT url FROM records WHERE short_id=$1 AND is_deleted=false;", shortID).Scan(&url)
+	var expiresAt *time.Time
+	var isDeleted bool
+	err := repo.db.QueryRow(ctx, `SELECT url, expires_at, COALESCE(is_deleted, false) FROM records
+								  WHERE short_id=$1;`, shortID).Scan(&url, &expiresAt, &isDeleted)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			repo.logger.Errorf("postgres repository, get, not found: %v", err)
@@ -75,16 +124,24 @@ func (repo *PostgresRepository) Get(ctx context.Context, shortID domain.ShortID)
 		}
 		return url, fmt.Errorf("postgres repository, get, select url: %w", err)
 	}
+	if isDeleted {
+		return domain.URL(""), ports.ErrDeleted
+	}
+	if expiresAt != nil && !time.Now().Before(*expiresAt) {
+		return domain.URL(""), ports.ErrExpired
+	}
 	return url, nil
 }
 
 // Batch - добавить несоклько записей.
 func (repo *PostgresRepository) Batch(ctx context.Context, uid domain.UID, data []domain.BatchRecord) ([]domain.BatchRecord, error) {
-	query := `INSERT INTO records (url, short_id) VALUES ($1, $2) ON CONFLICT (url) DO UPDATE 
-			  SET short_id=records.short_id RETURNING short_id;`
 	batch := &pgx.Batch{}
+	urls := make([]string, 0, len(data))
+	keys := make([]string, 0, len(data))
 	for i := range data {
-		batch.Queue(query, data[i].URL, data[i].ShortID)
+		batch.Queue(repo.addQuery, data[i].URL, data[i].ShortID, data[i].ExpiresAt, uid)
+		urls = append(urls, string(data[i].URL))
+		keys = append(keys, repo.lockKey(uid, data[i].URL))
 	}
 
 	trx, err := repo.db.BeginTx(ctx, pgx.TxOptions{})
@@ -93,11 +150,31 @@ func (repo *PostgresRepository) Batch(ctx context.Context, uid domain.UID, data
 	}
 	defer trx.Rollback(ctx)
 
+	err = purgeExpiredURLs(ctx, trx, urls)
+	if err != nil {
+		return nil, fmt.Errorf("postgres repository, batch: %w", err)
+	}
+	err = lockURLs(ctx, trx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("postgres repository, batch: %w", err)
+	}
+
 	results := trx.SendBatch(ctx, batch)
 	// defer results.Close()
 	for i := range data {
-		err = results.QueryRow().Scan(&data[i].ShortID)
+		shortID := data[i].ShortID
+		var created bool
+		err = results.QueryRow().Scan(&data[i].ShortID, &created)
+		data[i].Status = domain.BatchStatusExisting
+		if created {
+			data[i].Status = domain.BatchStatusCreated
+		}
 		if err != nil {
+			results.Close()
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == shortIDConstraint {
+				return nil, fmt.Errorf("postgres repository, batch: %w", &ports.ShortIDExistsError{ShortID: shortID})
+			}
 			return nil, fmt.Errorf("postgres repository, batch, scan send batch result: %w", err)
 		}
 	}
@@ -111,7 +188,7 @@ func (repo *PostgresRepository) Batch(ctx context.Context, uid domain.UID, data
 
 // UserURLs - получить все урлы для пользователя.
 func (repo *PostgresRepository) UserURLs(ctx context.Context, uid domain.UID) ([]domain.URLData, error) {
-	rows, err := repo.db.Query(ctx, `SELECT url, short_id FROM records
+	rows, err := repo.db.Query(ctx, `SELECT url, short_id, expires_at FROM records
 									 JOIN users ON records.id=users.record_id
 									 WHERE users.uid=$1;`, uid)
 	if err != nil {
@@ -119,7 +196,7 @@ func (repo *PostgresRepository) UserURLs(ctx context.Context, uid domain.UID) ([
 	}
 	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.URLData, error) {
 		var r domain.URLData
-		err := row.Scan(&r.URL, &r.ShortID)
+		err := row.Scan(&r.URL, &r.ShortID, &r.ExpiresAt)
 		return r, err
 	})
 	if err != nil {
@@ -128,6 +205,93 @@ func (repo *PostgresRepository) UserURLs(ctx context.Context, uid domain.UID) ([
 	return result, nil
 }
 
+// Stats - получить количество урлов и пользователей.
+func (repo *PostgresRepository) Stats(ctx context.Context) (*domain.Stats, error) {
+	stats := &domain.Stats{}
+	err := repo.db.QueryRow(ctx, `SELECT (SELECT COUNT(*) FROM records),
+										 (SELECT COUNT(DISTINCT uid) FROM users);`).Scan(&stats.URLs, &stats.Users)
+	if err != nil {
+		return nil, fmt.Errorf("postgres repository, stats: %w", err)
+	}
+	return stats, nil
+}
+
+// PurgeExpired - удалить записи с истекшим сроком действия.
+func (repo *PostgresRepository) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	trx, err := repo.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("postgres repository, purge expired, start trx: %w", err)
+	}
+	defer trx.Rollback(ctx)
+	_, err = trx.Exec(ctx, `DELETE FROM users WHERE record_id IN (
+								SELECT id FROM records WHERE expires_at <= $1
+							);`, now)
+	if err != nil {
+		return 0, fmt.Errorf("postgres repository, purge expired, delete from users: %w", err)
+	}
+	_, err = trx.Exec(ctx, `DELETE FROM clicks WHERE short_id IN (
+								SELECT short_id FROM records WHERE expires_at <= $1
+							);`, now)
+	if err != nil {
+		return 0, fmt.Errorf("postgres repository, purge expired, delete from clicks: %w", err)
+	}
+	tag, err := trx.Exec(ctx, "DELETE FROM records WHERE expires_at <= $1;", now)
+	if err != nil {
+		return 0, fmt.Errorf("postgres repository, purge expired, delete from records: %w", err)
+	}
+	err = trx.Commit(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("postgres repository, purge expired, commit trx: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// lockKey - ключ блокировки, под которой ищется действующая запись урла:
+// урл при общем владении, пользователь и урл при раздельном.
+func (repo *PostgresRepository) lockKey(uid domain.UID, url domain.URL) string {
+	return urlLockKey(repo.ownership, uid, url)
+}
+
+// urlLockKey - ключ advisory-блокировки урла: общий при общем владении, свой у пользователя при раздельном.
+func urlLockKey(ownership domain.URLOwnership, uid domain.UID, url domain.URL) string {
+	if ownership == domain.OwnershipSeparate {
+		return string(uid) + " " + string(url)
+	}
+	return string(url)
+}
+
+// lockURLs - взять транзакционные advisory-блокировки по ключам, чтобы параллельные запросы
+// не создали две действующие записи одного урла. Ключи сортируются, чтобы избежать взаимной блокировки.
+func lockURLs(ctx context.Context, trx pgx.Tx, keys []string) error {
+	_, err := trx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext(key))
+							 FROM unnest($1::text[]) AS key ORDER BY key;`, keys)
+	if err != nil {
+		return fmt.Errorf("lock urls: %w", err)
+	}
+	return nil
+}
+
+// purgeExpiredURLs - удалить просроченные записи для урлов, чтобы их можно было сократить заново.
+func purgeExpiredURLs(ctx context.Context, trx pgx.Tx, urls []string) error {
+	_, err := trx.Exec(ctx, `DELETE FROM users WHERE record_id IN (
+								SELECT id FROM records WHERE url = ANY($1) AND expires_at <= now()
+							);`, urls)
+	if err != nil {
+		return fmt.Errorf("purge expired urls, delete from users: %w", err)
+	}
+	_, err = trx.Exec(ctx, `DELETE FROM clicks WHERE short_id IN (
+								SELECT short_id FROM records WHERE url = ANY($1) AND expires_at <= now()
+							);`, urls)
+	if err != nil {
+		return fmt.Errorf("purge expired urls, delete from clicks: %w", err)
+	}
+	_, err = trx.Exec(ctx, "DELETE FROM records WHERE url = ANY($1) AND expires_at <= now();", urls)
+	if err != nil {
+		return fmt.Errorf("purge expired urls, delete from records: %w", err)
+	}
+	return nil
+}
+
 // Shutdown - выключить сервис.
 func (repo *PostgresRepository) Shutdown() error {
 	return nil
@@ -13,7 +13,7 @@ import (
 )
 
 func setupBenchmarkTest() (*PostgresRepository, func()) {
-	repo := NewPostgresRepository(db.GetPool(), db.GetLogger())
+	repo := NewPostgresRepository(db.GetPool(), db.GetLogger(), domain.OwnershipShared)
 
 	// tear down later
 	return repo, func() {
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/Svirex/microurl/internal/core/ports"
@@ -28,7 +29,7 @@ func TestMain(m *testing.M) {
 }
 
 func setupTest(t *testing.T) (*PostgresRepository, func()) {
-	repo := NewPostgresRepository(db.GetPool(), db.GetLogger())
+	repo := NewPostgresRepository(db.GetPool(), db.GetLogger(), domain.OwnershipShared)
 
 	// tear down later
 	return repo, func() {
@@ -102,3 +103,62 @@ func TestGetGood(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, data.URL, url)
 }
+
+func TestOnlyCreatorUpdates(t *testing.T) {
+	repo, tearDown := setupTest(t)
+	defer tearDown()
+
+	ctx := context.Background()
+	alice := domain.UID(uuid.New().String())
+	bob := domain.UID(uuid.New().String())
+	shortID, err := repo.Add(ctx, "first", &domain.Record{UID: alice, URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(ctx, "second", &domain.Record{UID: bob, URL: "http://svirex.ru"})
+	require.ErrorIs(t, err, ports.ErrAlreadyExists)
+
+	newURL := domain.URL("http://ya.ru")
+	_, err = repo.Update(ctx, bob, shortID, &domain.URLUpdate{URL: &newURL, UpdatedAt: time.Now()})
+	require.ErrorIs(t, err, ports.ErrNotCreator)
+	_, err = repo.URLRevisions(ctx, bob, shortID)
+	require.ErrorIs(t, err, ports.ErrNotCreator)
+
+	_, err = repo.Update(ctx, alice, shortID, &domain.URLUpdate{URL: &newURL, UpdatedAt: time.Now()})
+	require.ErrorIs(t, err, ports.ErrSharedLink)
+	url, err := repo.Get(ctx, shortID)
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://svirex.ru"), url)
+	urls, err := repo.UserURLs(ctx, bob)
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://svirex.ru"), urls[0].URL)
+
+	deleter := NewDeleterRepository(db.GetPool(), db.GetLogger(), domain.OwnershipShared)
+	require.NoError(t, deleter.Delete(ctx, []*domain.DeleteData{{UID: string(bob), ShortID: string(shortID)}}))
+	_, err = repo.Update(ctx, alice, shortID, &domain.URLUpdate{URL: &newURL, UpdatedAt: time.Now()})
+	require.NoError(t, err)
+	revisions, err := repo.URLRevisions(ctx, alice, shortID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+}
+
+func TestDeleteStatuses(t *testing.T) {
+	repo, tearDown := setupTest(t)
+	defer tearDown()
+
+	ctx := context.Background()
+	alice := uuid.New().String()
+	bob := uuid.New().String()
+	_, err := repo.Add(ctx, "mine", &domain.Record{UID: domain.UID(alice), URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(ctx, "theirs", &domain.Record{UID: domain.UID(bob), URL: "http://ya.ru"})
+	require.NoError(t, err)
+
+	batch := []*domain.DeleteData{
+		{UID: alice, ShortID: "mine"},
+		{UID: alice, ShortID: "theirs"},
+		{UID: alice, ShortID: "missing"},
+	}
+	require.NoError(t, NewDeleterRepository(db.GetPool(), db.GetLogger(), domain.OwnershipShared).Delete(ctx, batch))
+	require.Equal(t, domain.DeleteStatusDeleted, batch[0].Status)
+	require.Equal(t, domain.DeleteStatusNotOwned, batch[1].Status)
+	require.Equal(t, domain.DeleteStatusNotFound, batch[2].Status)
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// rateLimitCleanupInterval - как часто удалять из таблицы полностью пополненные корзины.
+const rateLimitCleanupInterval = time.Minute
+
+// RateLimitStore - корзины токенов в таблице rate_limits, общие для всех экземпляров сервиса.
+// Раз в rateLimitCleanupInterval из таблицы удаляются корзины, которые уже пополнились полностью.
+type RateLimitStore struct {
+	db          *pgxpool.Pool
+	logger      ports.Logger
+	lastCleanup atomic.Int64
+}
+
+var _ ports.RateLimitStore = (*RateLimitStore)(nil)
+
+// NewRateLimitStore - новое хранилище.
+func NewRateLimitStore(db *pgxpool.Pool, logger ports.Logger) *RateLimitStore {
+	return &RateLimitStore{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Take - забрать токен из корзины, строка корзины блокируется на время транзакции.
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit domain.RateLimit, now time.Time) (bool, time.Duration, error) {
+	trx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, 0, fmt.Errorf("postgres rate limit, start trx: %w", err)
+	}
+	defer trx.Rollback(ctx)
+	var tokens float64
+	var updatedAt time.Time
+	err = trx.QueryRow(ctx, `INSERT INTO rate_limits (key, tokens, updated_at, full_at) VALUES ($1, $2, $3, $3)
+							 ON CONFLICT (key) DO UPDATE SET key=EXCLUDED.key
+							 RETURNING tokens, updated_at;`, key, float64(limit.Burst), now).Scan(&tokens, &updatedAt)
+	if err != nil {
+		return false, 0, fmt.Errorf("postgres rate limit, lock bucket: %w", err)
+	}
+	tokens, allowed, retryAfter := limit.Take(tokens, now.Sub(updatedAt))
+	if updatedAt.After(now) {
+		now = updatedAt
+	}
+	_, err = trx.Exec(ctx, "UPDATE rate_limits SET tokens=$2, updated_at=$3, full_at=$4 WHERE key=$1;",
+		key, tokens, now, limit.FullAt(tokens, now))
+	if err != nil {
+		return false, 0, fmt.Errorf("postgres rate limit, update bucket: %w", err)
+	}
+	err = trx.Commit(ctx)
+	if err != nil {
+		return false, 0, fmt.Errorf("postgres rate limit, commit trx: %w", err)
+	}
+	s.maybeCleanup(now)
+	return allowed, retryAfter, nil
+}
+
+// maybeCleanup - запустить удаление пополненных корзин, если с прошлого удаления прошло rateLimitCleanupInterval.
+func (s *RateLimitStore) maybeCleanup(now time.Time) {
+	last := s.lastCleanup.Load()
+	if now.UnixNano()-last < int64(rateLimitCleanupInterval) || !s.lastCleanup.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), rateLimitCleanupInterval)
+		defer cancel()
+		count, err := s.Cleanup(ctx, now)
+		if err != nil {
+			s.logger.Errorln("postgres rate limit, cleanup", "err", err)
+			return
+		}
+		s.logger.Debugln("postgres rate limit, cleanup", "deleted", count)
+	}()
+}
+
+// Cleanup - удалить корзины, полностью пополненные к моменту now. Корзины, занятые запросами, пропускаются.
+func (s *RateLimitStore) Cleanup(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM rate_limits WHERE key IN (
+									SELECT key FROM rate_limits WHERE full_at <= $1 FOR UPDATE SKIP LOCKED
+								 );`, now)
+	if err != nil {
+		return 0, fmt.Errorf("postgres rate limit, cleanup: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/jackc/pgx/v5"
+)
+
+// ownedRecordQuery - действующая запись, которой владеет пользователь, и ее создатель.
+const ownedRecordQuery = `SELECT records.id, records.url, records.expires_at, records.creator_uid::text FROM records
+	JOIN users ON records.id = users.record_id
+	WHERE records.short_id = $1 AND users.uid::text = $2::text
+		AND NOT users.is_deleted AND NOT COALESCE(records.is_deleted, false)`
+
+// Update - изменить ссылку. Запись блокируется на время транзакции, при смене урла берутся
+// те же advisory-блокировки, что и при добавлении, чтобы не появилось двух действующих записей одного урла.
+// Изменять ссылку может только ее создатель, остальные владельцы получают ErrNotCreator.
+// Пока у ссылки есть другие действующие владельцы, ее изменение возвращает ErrSharedLink.
+func (repo *PostgresRepository) Update(ctx context.Context, uid domain.UID, shortID domain.ShortID, update *domain.URLUpdate) (*domain.URLData, error) {
+	trx, err := repo.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("postgres repository, update, start trx: %w", err)
+	}
+	defer trx.Rollback(ctx)
+	var recordID int64
+	var oldURL domain.URL
+	var oldExpiresAt *time.Time
+	var creator *string
+	err = trx.QueryRow(ctx, ownedRecordQuery+" FOR UPDATE OF records;", shortID, uid).Scan(&recordID, &oldURL, &oldExpiresAt, &creator)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ports.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres repository, update, select record: %w", err)
+	}
+	if creator == nil || *creator != string(uid) {
+		return nil, ports.ErrNotCreator
+	}
+	var shared bool
+	err = trx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users
+							WHERE record_id = $1 AND uid::text <> $2::text AND NOT is_deleted);`, recordID, uid).Scan(&shared)
+	if err != nil {
+		return nil, fmt.Errorf("postgres repository, update, select other owners: %w", err)
+	}
+	if shared {
+		return nil, ports.ErrSharedLink
+	}
+	if oldExpiresAt != nil && !time.Now().Before(*oldExpiresAt) {
+		return nil, ports.ErrExpired
+	}
+	result := &domain.URLData{
+		URL:       oldURL,
+		ShortID:   shortID,
+		ExpiresAt: oldExpiresAt,
+	}
+	if update.URL != nil {
+		result.URL = *update.URL
+	}
+	switch {
+	case update.ClearExpiry:
+		result.ExpiresAt = nil
+	case update.ExpiresAt != nil:
+		result.ExpiresAt = update.ExpiresAt
+	}
+	if result.URL != oldURL {
+		err = repo.checkURLFree(ctx, trx, uid, recordID, oldURL, result.URL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = trx.Exec(ctx, `INSERT INTO url_revisions (record_id, url, expires_at, changed_at)
+							VALUES ($1, $2, $3, $4);`, recordID, oldURL, oldExpiresAt, update.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("postgres repository, update, insert revision: %w", err)
+	}
+	_, err = trx.Exec(ctx, "UPDATE records SET url = $2, expires_at = $3 WHERE id = $1;", recordID, result.URL, result.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("postgres repository, update, update record: %w", err)
+	}
+	err = trx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("postgres repository, update, commit trx: %w", err)
+	}
+	return result, nil
+}
+
+// checkURLFree - у пользователя нет другой действующей записи нового урла, иначе ErrAlreadyExists.
+func (repo *PostgresRepository) checkURLFree(ctx context.Context, trx pgx.Tx, uid domain.UID, recordID int64, oldURL, newURL domain.URL) error {
+	err := purgeExpiredURLs(ctx, trx, []string{string(newURL)})
+	if err != nil {
+		return fmt.Errorf("postgres repository, update: %w", err)
+	}
+	err = lockURLs(ctx, trx, []string{repo.lockKey(uid, oldURL), repo.lockKey(uid, newURL)})
+	if err != nil {
+		return fmt.Errorf("postgres repository, update: %w", err)
+	}
+	exists, err := urlTaken(ctx, trx, repo.ownership, uid, recordID, newURL)
+	if err != nil {
+		return fmt.Errorf("postgres repository, update: %w", err)
+	}
+	if exists {
+		return ports.ErrAlreadyExists
+	}
+	return nil
+}
+
+// urlTaken - у пользователя есть действующая запись урла, кроме записи recordID.
+func urlTaken(ctx context.Context, trx pgx.Tx, ownership domain.URLOwnership, uid domain.UID, recordID int64, url domain.URL) (bool, error) {
+	var exists bool
+	var err error
+	if ownership == domain.OwnershipSeparate {
+		err = trx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM records JOIN users ON records.id = users.record_id
+								WHERE records.url = $1 AND records.id <> $2 AND users.uid::text = $3::text
+									AND NOT COALESCE(records.is_deleted, false));`, url, recordID, uid).Scan(&exists)
+	} else {
+		err = trx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM records
+								WHERE url = $1 AND id <> $2 AND NOT COALESCE(is_deleted, false));`, url, recordID).Scan(&exists)
+	}
+	if err != nil {
+		return false, fmt.Errorf("select existing url: %w", err)
+	}
+	return exists, nil
+}
+
+// URLRevisions - история изменений ссылки, доступна только ее создателю.
+func (repo *PostgresRepository) URLRevisions(ctx context.Context, uid domain.UID, shortID domain.ShortID) ([]domain.URLRevision, error) {
+	var recordID int64
+	var url domain.URL
+	var expiresAt *time.Time
+	var creator *string
+	err := repo.db.QueryRow(ctx, ownedRecordQuery+";", shortID, uid).Scan(&recordID, &url, &expiresAt, &creator)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ports.ErrNotFound
+		}
+		return nil, fmt.Errorf("postgres repository, url revisions, select record: %w", err)
+	}
+	if creator == nil || *creator != string(uid) {
+		return nil, ports.ErrNotCreator
+	}
+	rows, err := repo.db.Query(ctx, `SELECT url, expires_at, changed_at FROM url_revisions
+									 WHERE record_id = $1 ORDER BY id;`, recordID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres repository, url revisions, query: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.URLRevision, error) {
+		var r domain.URLRevision
+		err := row.Scan(&r.URL, &r.ExpiresAt, &r.ChangedAt)
+		return r, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("postgres repository, url revisions, collect rows: %w", err)
+	}
+	return result, nil
+}
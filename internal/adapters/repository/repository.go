@@ -11,6 +11,7 @@ import (
 	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
 	repo "github.com/Svirex/microurl/internal/adapters/repository/postgres"
 	"github.com/Svirex/microurl/internal/config"
+	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/Svirex/microurl/internal/core/ports"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -21,7 +22,7 @@ import (
 // NewRepository - новый репозиторий на основе переданных параметров.
 func NewRepository(ctx context.Context, cfg *config.Config, db *pgxpool.Pool, logger ports.Logger) (ports.ShortenerRepository, error) {
 	if cfg.PostgresDSN != "" {
-		repository := repo.NewPostgresRepository(db, logger)
+		repository := repo.NewPostgresRepository(db, logger, domain.URLOwnership(cfg.URLOwnership))
 		migrationUp(db, logger, cfg.MigrationsPath)
 		return repository, nil
 	}
@@ -30,10 +31,12 @@ func NewRepository(ctx context.Context, cfg *config.Config, db *pgxpool.Pool, lo
 		if err != nil {
 			return nil, fmt.Errorf("new repository, open file: %w", err)
 		}
-		m := inmemory.NewShortenerRepository()
+		m := inmemory.NewShortenerRepository(inmemory.WithOwnership(domain.URLOwnership(cfg.URLOwnership)))
 		r := filebackup.NewFileBackupReader(f)
-		err = r.Restore(ctx, m)
-		if err != nil {
+		err = r.Restore(ctx, m, inmemory.NewDeleterRepository(m))
+		if errors.Is(err, filebackup.ErrSkippedRecords) {
+			logger.Warnln("file backup is inconsistent, some records are skipped", "path", cfg.FileStoragePath, "err", err)
+		} else if err != nil {
 			return nil, fmt.Errorf("new repository, restore file backup: %w", err)
 		}
 		f.Close()
@@ -45,7 +48,111 @@ func NewRepository(ctx context.Context, cfg *config.Config, db *pgxpool.Pool, lo
 		w := filebackup.NewFileBackupWriter(f)
 		return file.NewShortenerRepository(m, w), nil
 	}
-	return inmemory.NewShortenerRepository(), nil
+	return inmemory.NewShortenerRepository(inmemory.WithOwnership(domain.URLOwnership(cfg.URLOwnership))), nil
+}
+
+// NewDeleterRepository - репозиторий удаления записей для выбранного хранилища.
+func NewDeleterRepository(cfg *config.Config, db *pgxpool.Pool, repository ports.ShortenerRepository, logger ports.Logger) (ports.DeleterRepository, error) {
+	if cfg.PostgresDSN != "" {
+		return repo.NewDeleterRepository(db, logger, domain.URLOwnership(cfg.URLOwnership)), nil
+	}
+	switch r := repository.(type) {
+	case *file.ShortenerRepository:
+		return file.NewDeleterRepository(r), nil
+	case *inmemory.ShortenerRepository:
+		return inmemory.NewDeleterRepository(r), nil
+	}
+	return nil, fmt.Errorf("new deleter repository, unsupported repository %T", repository)
+}
+
+// NewClickRepository - репозиторий переходов по ссылкам для выбранного хранилища.
+// Для файлового хранилища и хранилища в памяти переходы хранятся в памяти вместе с записями,
+// файловое хранилище дописывает их в файл.
+func NewClickRepository(cfg *config.Config, db *pgxpool.Pool, repository ports.ShortenerRepository, logger ports.Logger) (ports.ClickRepository, error) {
+	if cfg.PostgresDSN != "" {
+		return repo.NewClickRepository(db, logger), nil
+	}
+	clickRepository, ok := repository.(ports.ClickRepository)
+	if !ok {
+		return nil, fmt.Errorf("new click repository, unsupported repository %T", repository)
+	}
+	return clickRepository, nil
+}
+
+// NewCounter - счетчик для генератора коротких идентификаторов для выбранного хранилища.
+func NewCounter(cfg *config.Config, db *pgxpool.Pool) (ports.Counter, error) {
+	if cfg.PostgresDSN != "" {
+		return repo.NewCounter(db), nil
+	}
+	if cfg.FileStoragePath != "" {
+		counter, err := file.NewCounter(cfg.FileStoragePath + ".counter")
+		if err != nil {
+			return nil, fmt.Errorf("new counter: %w", err)
+		}
+		return counter, nil
+	}
+	return inmemory.NewCounter(), nil
+}
+
+// NewKeyPoolRepository - хранилище пула коротких идентификаторов для выбранного хранилища.
+// Ключи, занятые записями repository, в пул не попадают.
+func NewKeyPoolRepository(cfg *config.Config, db *pgxpool.Pool, repository ports.ShortenerRepository) (ports.KeyPoolRepository, error) {
+	if cfg.PostgresDSN != "" {
+		return repo.NewKeyPoolRepository(db), nil
+	}
+	switch r := repository.(type) {
+	case *file.ShortenerRepository:
+		return file.NewKeyPoolRepository(cfg.FileStoragePath+".keys", r), nil
+	case *inmemory.ShortenerRepository:
+		return inmemory.NewKeyPoolRepository(r), nil
+	}
+	return nil, fmt.Errorf("new key pool repository, unsupported repository %T", repository)
+}
+
+// NewHealthChecks - проверки готовности хранилища: версия миграций для БД или запись в файл бэкапа.
+func NewHealthChecks(cfg *config.Config, db *pgxpool.Pool) []ports.HealthChecker {
+	if cfg.PostgresDSN != "" {
+		return []ports.HealthChecker{repo.NewMigrationCheck(db)}
+	}
+	if cfg.FileStoragePath != "" {
+		return []ports.HealthChecker{filebackup.NewWritableCheck(cfg.FileStoragePath)}
+	}
+	return nil
+}
+
+// NewRateLimitStore - хранилище корзин ограничения частоты запросов: общее в БД или в памяти экземпляра.
+func NewRateLimitStore(cfg *config.Config, db *pgxpool.Pool, logger ports.Logger) ports.RateLimitStore {
+	if cfg.PostgresDSN != "" {
+		return repo.NewRateLimitStore(db, logger)
+	}
+	return inmemory.NewRateLimitStore()
+}
+
+// NewAPIKeyRepository - хранилище API ключей: таблица в БД, файл рядом с файлом бэкапа или память.
+func NewAPIKeyRepository(cfg *config.Config, db *pgxpool.Pool) (ports.APIKeyRepository, error) {
+	if cfg.PostgresDSN != "" {
+		return repo.NewAPIKeyRepository(db), nil
+	}
+	if cfg.FileStoragePath != "" {
+		repository, err := file.NewAPIKeyRepository(cfg.FileStoragePath + ".apikeys")
+		if err != nil {
+			return nil, fmt.Errorf("new api key repository: %w", err)
+		}
+		return repository, nil
+	}
+	return inmemory.NewAPIKeyRepository(), nil
+}
+
+// NewDeadLetterRepository - хранилище ссылок, удаление которых не удалось записать:
+// таблица в БД, файл рядом с файлом бэкапа или память.
+func NewDeadLetterRepository(cfg *config.Config, db *pgxpool.Pool) ports.DeadLetterRepository {
+	if cfg.PostgresDSN != "" {
+		return repo.NewDeadLetterRepository(db)
+	}
+	if cfg.FileStoragePath != "" {
+		return file.NewDeadLetterRepository(cfg.FileStoragePath + ".deadletters")
+	}
+	return inmemory.NewDeadLetterRepository()
 }
 
 func migrationUp(dbpool *pgxpool.Pool, logger ports.Logger, migrationsPath string) {
@@ -0,0 +1,144 @@
+// Пакет tlscert - самоподписанный сертификат для запуска сервиса по HTTPS при разработке.
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	certFileName = "cert.pem"
+	keyFileName  = "key.pem"
+
+	// validFor - срок действия сгенерированного сертификата.
+	validFor = 365 * 24 * time.Hour
+	// renewBefore - за сколько до истечения срока действия сертификат генерируется заново.
+	renewBefore = 24 * time.Hour
+)
+
+// SelfSigned - вернуть пути к самоподписанному сертификату и ключу в директории cacheDir.
+// Если сертификата нет, он не подходит для hosts или скоро истекает, то генерируется новый.
+func SelfSigned(cacheDir string, hosts []string) (certFile string, keyFile string, err error) {
+	hosts = prepareHosts(hosts)
+	certFile = filepath.Join(cacheDir, certFileName)
+	keyFile = filepath.Join(cacheDir, keyFileName)
+	if isValid(certFile, keyFile, hosts, time.Now()) {
+		return certFile, keyFile, nil
+	}
+	certPEM, keyPEM, err := generate(hosts, time.Now())
+	if err != nil {
+		return "", "", fmt.Errorf("self signed cert, generate: %w", err)
+	}
+	err = os.MkdirAll(cacheDir, 0o700)
+	if err != nil {
+		return "", "", fmt.Errorf("self signed cert, create cache dir: %w", err)
+	}
+	err = os.WriteFile(keyFile, keyPEM, 0o600)
+	if err != nil {
+		return "", "", fmt.Errorf("self signed cert, write key: %w", err)
+	}
+	err = os.WriteFile(certFile, certPEM, 0o644)
+	if err != nil {
+		return "", "", fmt.Errorf("self signed cert, write cert: %w", err)
+	}
+	return certFile, keyFile, nil
+}
+
+// DefaultCacheDir - директория для хранения сгенерированного сертификата.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "microurl", "tls")
+}
+
+// prepareHosts - убрать пустые хосты, например для адреса ":8080", по умолчанию localhost.
+func prepareHosts(hosts []string) []string {
+	result := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if host != "" {
+			result = append(result, host)
+		}
+	}
+	if len(result) == 0 {
+		result = append(result, "localhost")
+	}
+	return result
+}
+
+func isValid(certFile, keyFile string, hosts []string, now time.Time) bool {
+	if _, err := os.Stat(keyFile); err != nil {
+		return false
+	}
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return false
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	if now.Add(renewBefore).After(cert.NotAfter) {
+		return false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func generate(hosts []string, now time.Time) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate serial number: %w", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"microurl"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal key: %w", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
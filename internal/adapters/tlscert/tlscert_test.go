@@ -0,0 +1,33 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfSignedCached(t *testing.T) {
+	dir := t.TempDir()
+	hosts := []string{"localhost", "127.0.0.1"}
+
+	certFile, keyFile, err := SelfSigned(dir, hosts)
+	require.NoError(t, err)
+	_, err = tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	first, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+
+	_, _, err = SelfSigned(dir, hosts)
+	require.NoError(t, err)
+	second, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+
+	_, _, err = SelfSigned(dir, []string{"svirex.ru"})
+	require.NoError(t, err)
+	third, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	require.NotEqual(t, first, third)
+}
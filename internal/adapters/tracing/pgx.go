@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PgxTracer - трассировка SQL запросов pgx: отдельный спан на каждый запрос, батч и COPY.
+type PgxTracer struct{}
+
+var (
+	_ pgx.QueryTracer    = (*PgxTracer)(nil)
+	_ pgx.BatchTracer    = (*PgxTracer)(nil)
+	_ pgx.CopyFromTracer = (*PgxTracer)(nil)
+)
+
+// NewPgxTracer - новый трассировщик, подключается через pgxpool.Config.ConnConfig.Tracer.
+func NewPgxTracer() *PgxTracer {
+	return &PgxTracer{}
+}
+
+// TraceQueryStart - начало запроса.
+func (t *PgxTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := sqlOperation(data.SQL)
+	ctx, _ = startSpan(ctx, operation, semconv.DBOperationName(operation), semconv.DBQueryText(data.SQL))
+	return ctx
+}
+
+// TraceQueryEnd - конец запроса.
+func (t *PgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.CommandTag.RowsAffected(), data.Err)
+}
+
+// TraceBatchStart - начало батча.
+func (t *PgxTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, _ = startSpan(ctx, "BATCH", attribute.Int("db.batch.size", data.Batch.Len()))
+	return ctx
+}
+
+// TraceBatchQuery - запрос внутри батча.
+func (t *PgxTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	operation := sqlOperation(data.SQL)
+	_, span := startSpan(ctx, operation, semconv.DBOperationName(operation), semconv.DBQueryText(data.SQL))
+	endSpan(span, data.CommandTag.RowsAffected(), data.Err)
+}
+
+// TraceBatchEnd - конец батча.
+func (t *PgxTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endSpan(trace.SpanFromContext(ctx), -1, data.Err)
+}
+
+// TraceCopyFromStart - начало COPY.
+func (t *PgxTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	ctx, _ = startSpan(ctx, "COPY", semconv.DBOperationName("COPY"), semconv.DBCollectionName(data.TableName.Sanitize()))
+	return ctx
+}
+
+// TraceCopyFromEnd - конец COPY.
+func (t *PgxTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.CommandTag.RowsAffected(), data.Err)
+}
+
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBSystemPostgreSQL)
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func endSpan(span trace.Span, rowsAffected int64, err error) {
+	if rowsAffected >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows_affected", rowsAffected))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// sqlOperation - первое слово запроса, например SELECT или INSERT.
+func sqlOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(strings.TrimRight(fields[0], ";"))
+}
@@ -0,0 +1,51 @@
+// Пакет tracing - трассировка OpenTelemetry: настройка экспорта по OTLP, HTTP middleware и трассировка SQL.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// instrumentationName - имя инструментирования для трассировщиков пакета.
+const instrumentationName = "github.com/Svirex/microurl/internal/adapters/tracing"
+
+// Setup - настроить глобальный провайдер трассировки с экспортом по OTLP/gRPC на endpoint.
+// endpoint - <host>:<port> (без TLS) или URL, где схема http или https определяет, используется ли TLS.
+// Если endpoint пустой, то спаны не экспортируются, но контекст трассировки все равно передается дальше.
+// Возвращает функцию, которая отправляет оставшиеся спаны и останавливает провайдер.
+func Setup(ctx context.Context, endpoint string, serviceName string, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure()}
+	if strings.Contains(endpoint, "://") {
+		opts = []otlptracegrpc.Option{otlptracegrpc.WithEndpointURL(endpoint)}
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("tracing setup, create otlp exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing setup, resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// collector - коллектор OTLP в памяти процесса.
+type collector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	mutex sync.Mutex
+	spans []string
+}
+
+func (c *collector) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, resourceSpans := range req.GetResourceSpans() {
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			for _, span := range scopeSpans.GetSpans() {
+				c.spans = append(c.spans, span.GetName())
+			}
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func TestSetupExportsSpans(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	col := &collector{}
+	coltracepb.RegisterTraceServiceServer(server, col)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	provider := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(provider)
+
+	shutdown, err := Setup(context.Background(), listener.Addr().String(), "microurl-test", "test")
+	require.NoError(t, err)
+	_, span := otel.Tracer("test").Start(context.Background(), "test span")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	col.mutex.Lock()
+	defer col.mutex.Unlock()
+	require.Equal(t, []string{"test span"}, col.spans)
+}
+
+func TestSQLOperation(t *testing.T) {
+	require.Equal(t, "SELECT", sqlOperation("  select url FROM records;"))
+	require.Equal(t, "BEGIN", sqlOperation("begin;"))
+	require.Equal(t, "SQL", sqlOperation(""))
+}
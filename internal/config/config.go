@@ -1,120 +1,612 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/caarlos0/env/v10"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gopkg.in/yaml.v3"
 )
 
+// Генераторы коротких идентификаторов.
+const (
+	// GeneratorRandom - случайные строки из букв
+	GeneratorRandom = "random"
+	// GeneratorCounter - перемешанный ключом счетчик в base62
+	GeneratorCounter = "counter"
+)
+
+// Значения атрибута SameSite для cookie с JWT.
+const (
+	// SameSiteLax - cookie отправляется при переходах на сайт
+	SameSiteLax = "lax"
+	// SameSiteStrict - cookie отправляется только с запросами с того же сайта
+	SameSiteStrict = "strict"
+	// SameSiteNone - cookie отправляется всегда, требует Secure
+	SameSiteNone = "none"
+)
+
+// redacted - значение, которым заменяются секреты при выводе конфига.
+const redacted = "***"
+
 // Config - конфиг приложения
 type Config struct {
 	// Addr - адрес, по которому будет запущенно приложение
-	Addr string `env:"SERVER_ADDRESS"`
+	Addr string `env:"SERVER_ADDRESS" yaml:"server_address"`
 	// BaseURL - адрес, который будет использоваться для сокращенной ссылки
-	BaseURL string `env:"BASE_URL"`
+	BaseURL string `env:"BASE_URL" yaml:"base_url"`
 	// FileStoragePath - путь к файлу для сохранения и загрузки записей
-	FileStoragePath string `env:"FILE_STORAGE_PATH"`
+	FileStoragePath string `env:"FILE_STORAGE_PATH" yaml:"file_storage_path"`
 	// PostgresDSN - параметры для подключения к БД Postgres
-	PostgresDSN string `env:"DATABASE_DSN"`
+	PostgresDSN string `env:"DATABASE_DSN" yaml:"database_dsn"`
 	// MigrationsPath - путь до директории с файлами миграций БД
-	MigrationsPath string `env:"MIGRATIONS_PATH"`
+	MigrationsPath string `env:"MIGRATIONS_PATH" yaml:"migrations_path"`
 	// SecretKey - секретный ключ для создания JWT токена
-	SecretKey string `env:"SECRET_KEY"`
-}
+	SecretKey string `env:"SECRET_KEY" yaml:"secret_key"`
+	// SecretKeys - дополнительные ключи подписи JWT в виде kid:secret, SecretKey имеет kid default
+	SecretKeys []string `env:"SECRET_KEYS" envSeparator:"," yaml:"secret_keys"`
+	// SecretKeyID - kid ключа, которым подписываются новые JWT, остальные ключи только проверяют токены
+	SecretKeyID string `env:"SECRET_KEY_ID" yaml:"secret_key_id"`
+	// JWTTTL - срок действия JWT, 0 - бессрочный
+	JWTTTL time.Duration `env:"JWT_TTL" yaml:"jwt_ttl"`
+	// JWTRefresh - возраст JWT, после которого он перевыпускается при очередном запросе, 0 отключает продление
+	JWTRefresh time.Duration `env:"JWT_REFRESH" yaml:"jwt_refresh"`
+	// CookieDomain - атрибут Domain cookie с JWT
+	CookieDomain string `env:"COOKIE_DOMAIN" yaml:"cookie_domain"`
+	// CookieSecure - атрибут Secure cookie с JWT, при HTTPS включается всегда
+	CookieSecure bool `env:"COOKIE_SECURE" yaml:"cookie_secure"`
+	// CookieSameSite - атрибут SameSite cookie с JWT: lax, strict или none
+	CookieSameSite string `env:"COOKIE_SAME_SITE" yaml:"cookie_same_site"`
+	// ExpiredPurgeInterval - период удаления записей с истекшим сроком действия
+	ExpiredPurgeInterval time.Duration `env:"EXPIRED_PURGE_INTERVAL" yaml:"expired_purge_interval"`
+	// DeletedGracePeriod - срок, в течение которого можно отменить удаление ссылки, после него ссылка удаляется окончательно
+	DeletedGracePeriod time.Duration `env:"DELETED_GRACE_PERIOD" yaml:"deleted_grace_period"`
+	// DeletedPurgeInterval - период окончательного удаления ссылок, у которых истек срок восстановления
+	DeletedPurgeInterval time.Duration `env:"DELETED_PURGE_INTERVAL" yaml:"deleted_purge_interval"`
+	// DeleteMaxAttempts - число попыток записать батч удаления, после них ссылки батча попадают в dead letter
+	DeleteMaxAttempts int `env:"DELETE_MAX_ATTEMPTS" yaml:"delete_max_attempts"`
+	// DeleteRetryBackoff - пауза после первой неудачной записи батча удаления, удваивается с каждой попыткой
+	DeleteRetryBackoff time.Duration `env:"DELETE_RETRY_BACKOFF" yaml:"delete_retry_backoff"`
+	// TrustedProxies - список CIDR прокси, которым доверяем заголовки X-Forwarded-For и X-Real-IP
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:"," yaml:"trusted_proxies"`
+	// URLSchemes - схемы, разрешенные в сокращаемых URL
+	URLSchemes []string `env:"URL_SCHEMES" envSeparator:"," yaml:"url_schemes"`
+	// URLFragment - политика обработки фрагмента в сокращаемых URL: keep, strip или reject
+	URLFragment string `env:"URL_FRAGMENT" yaml:"url_fragment"`
+	// URLOwnership - режим владения ссылками: shared - один короткий идентификатор на URL для всех пользователей,
+	// separate - у каждого пользователя свой
+	URLOwnership string `env:"URL_OWNERSHIP" yaml:"url_ownership"`
+	// DomainPolicyFile - путь к файлу правил allow/deny для доменов сокращаемых ссылок
+	DomainPolicyFile string `env:"DOMAIN_POLICY_FILE" yaml:"domain_policy_file"`
+	// NotFoundPage - путь к HTML-странице для несуществующих коротких ссылок
+	NotFoundPage string `env:"NOT_FOUND_PAGE" yaml:"not_found_page"`
+	// GRPCAddr - адрес gRPC сервера, пустая строка отключает gRPC
+	GRPCAddr string `env:"GRPC_ADDRESS" yaml:"grpc_address"`
+	// ShutdownDrainDelay - пауза между переводом /readyz в 503 и остановкой серверов при завершении,
+	// чтобы балансировщик успел убрать экземпляр из ротации
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" yaml:"shutdown_drain_delay"`
+	// TrustedSubnet - CIDR подсети, из которой доступна внутренняя статистика
+	TrustedSubnet string `env:"TRUSTED_SUBNET" yaml:"trusted_subnet"`
+	// EnableHTTPS - запустить сервер по HTTPS
+	EnableHTTPS bool `env:"ENABLE_HTTPS" yaml:"enable_https"`
+	// TLSCertFile - путь к файлу сертификата, если не задан, то генерируется самоподписанный сертификат
+	TLSCertFile string `env:"TLS_CERT_FILE" yaml:"tls_cert_file"`
+	// TLSKeyFile - путь к файлу приватного ключа сертификата
+	TLSKeyFile string `env:"TLS_KEY_FILE" yaml:"tls_key_file"`
+	// ShortIDMaxAttempts - число попыток сгенерировать свободный короткий идентификатор
+	ShortIDMaxAttempts int `env:"SHORT_ID_MAX_ATTEMPTS" yaml:"short_id_max_attempts"`
+	// ShortIDGenerator - генератор коротких идентификаторов: random или counter
+	ShortIDGenerator string `env:"SHORT_ID_GENERATOR" yaml:"short_id_generator"`
+	// ShortIDKey - ключ перестановки для генератора counter, если не задан, то используется SecretKey
+	ShortIDKey string `env:"SHORT_ID_KEY" yaml:"short_id_key"`
+	// KeyPoolSize - число заранее подготовленных ключей, загружаемых в память за одно пополнение, 0 отключает пул
+	KeyPoolSize int `env:"KEY_POOL_SIZE" yaml:"key_pool_size"`
+	// KeyPoolWatermark - пул пополняется, когда в памяти остается не больше этого числа ключей
+	KeyPoolWatermark int `env:"KEY_POOL_WATERMARK" yaml:"key_pool_watermark"`
 
-// ParseEnv - парсим переменные окружения
-func ParseEnv() (*Config, error) {
-	cfg := &Config{}
-	if err := env.Parse(cfg); err != nil {
-		return nil, fmt.Errorf("parse enviroment variables: %w", err)
-	}
-	return cfg, nil
+	// WriteRateLimit - число запросов в секунду на создание и удаление ссылок с одного IP и от одного пользователя, 0 отключает ограничение
+	WriteRateLimit float64 `env:"WRITE_RATE_LIMIT" yaml:"write_rate_limit"`
+	// WriteRateBurst - максимальная пачка запросов на создание и удаление ссылок
+	WriteRateBurst int `env:"WRITE_RATE_BURST" yaml:"write_rate_burst"`
+	// RedirectRateLimit - число редиректов в секунду с одного IP и для одного пользователя, 0 отключает ограничение
+	RedirectRateLimit float64 `env:"REDIRECT_RATE_LIMIT" yaml:"redirect_rate_limit"`
+	// RedirectRateBurst - максимальная пачка редиректов
+	RedirectRateBurst int `env:"REDIRECT_RATE_BURST" yaml:"redirect_rate_burst"`
+
+	// OTLPEndpoint - адрес коллектора OpenTelemetry (OTLP/gRPC) в виде <host>:<port> или URL http(s)://<host>[:<port>],
+	// если не задан, то спаны не экспортируются
+	OTLPEndpoint string `env:"OTEL_EXPORTER_OTLP_ENDPOINT" yaml:"otlp_endpoint"`
+
+	// ConfigPath - путь к файлу конфига в формате JSON или YAML
+	ConfigPath string `env:"CONFIG" yaml:"-"`
+	// PrintConfig - вывести итоговый конфиг и завершить работу
+	PrintConfig bool `yaml:"-"`
 }
 
-// ParseFlags - парсим флаги командной строки
-func ParseFlags() (*Config, error) {
-	cfg := &Config{}
+// Default - конфиг со значениями по умолчанию
+func Default() (*Config, error) {
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("getwd: %w", err)
 	}
-	flag.StringVar(&cfg.Addr, "a", "localhost:8080", "<host>:<port>")
-	flag.StringVar(&cfg.BaseURL, "b", "", "base URL")
-	flag.StringVar(&cfg.FileStoragePath, "f", "/tmp/short-url-db.json", "file for save records")
-	flag.StringVar(&cfg.PostgresDSN, "d", "", "postgres DSN")
-	flag.StringVar(&cfg.MigrationsPath, "m", path.Join(strings.Replace(currentDir, "cmd/shortener", "", 1), "migrations"), "path to db migrations")
-	flag.StringVar(&cfg.SecretKey, "k", "fake_secret_key", "secret key for auth")
-	flag.Parse()
-	return cfg, nil
+	return &Config{
+		Addr:                 "localhost:8080",
+		FileStoragePath:      "/tmp/short-url-db.json",
+		MigrationsPath:       path.Join(strings.Replace(currentDir, "cmd/shortener", "", 1), "migrations"),
+		SecretKey:            "fake_secret_key",
+		SecretKeyID:          "default",
+		JWTTTL:               30 * 24 * time.Hour,
+		JWTRefresh:           24 * time.Hour,
+		CookieSameSite:       SameSiteLax,
+		ExpiredPurgeInterval: time.Minute,
+		DeletedGracePeriod:   7 * 24 * time.Hour,
+		DeletedPurgeInterval: time.Hour,
+		DeleteMaxAttempts:    5,
+		DeleteRetryBackoff:   time.Second,
+		ShutdownDrainDelay:   5 * time.Second,
+		ShortIDMaxAttempts:   5,
+		ShortIDGenerator:     GeneratorRandom,
+		KeyPoolWatermark:     100,
+		URLSchemes:           append([]string(nil), domain.DefaultURLSchemes...),
+		URLFragment:          string(domain.FragmentKeep),
+		URLOwnership:         string(domain.OwnershipShared),
+	}, nil
 }
 
-// Parse - парсим конфиг
+// Parse - парсим конфиг. Приоритет источников: флаги, переменные окружения, файл конфига, значения по умолчанию.
 func Parse() (*Config, error) {
-	envCfg, err := ParseEnv()
+	return parse(flag.CommandLine, os.Args[1:])
+}
+
+func parse(flags *flag.FlagSet, args []string) (*Config, error) {
+	defaults, err := Default()
 	if err != nil {
 		return nil, fmt.Errorf("parse error: %w", err)
 	}
-	flagConfig, err := ParseFlags()
+	flagConfig := *defaults
+	registerFlags(flags, &flagConfig)
+	err = flags.Parse(args)
+	if err != nil {
+		return nil, fmt.Errorf("parse flags: %w", err)
+	}
+
+	cfg := defaults
+	configPath := flagConfig.ConfigPath
+	if configPath == "" {
+		configPath = os.Getenv("CONFIG")
+	}
+	if configPath != "" {
+		err = LoadFile(configPath, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("parse error: %w", err)
+		}
+	}
+	err = env.Parse(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("parse enviroment variables: %w", err)
+	}
+	err = applyFlags(flags, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("parse error: %w", err)
 	}
-	cfg := mergeConf(envCfg, flagConfig)
+
 	prepareConfig(cfg)
+	err = cfg.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 	return cfg, nil
 }
 
-func prepareAddr(addr string) string {
-	if strings.HasPrefix(addr, "http://") {
-		return addr[7:]
+// registerFlags - регистрируем флаги командной строки, значения по умолчанию берем из cfg
+func registerFlags(flags *flag.FlagSet, cfg *Config) {
+	flags.StringVar(&cfg.ConfigPath, "c", cfg.ConfigPath, "path to json or yaml config file")
+	flags.BoolVar(&cfg.PrintConfig, "print-config", cfg.PrintConfig, "print effective config with redacted secrets and exit")
+	flags.StringVar(&cfg.Addr, "a", cfg.Addr, "<host>:<port>")
+	flags.StringVar(&cfg.BaseURL, "b", cfg.BaseURL, "base URL")
+	flags.StringVar(&cfg.FileStoragePath, "f", cfg.FileStoragePath, "file for save records")
+	flags.StringVar(&cfg.PostgresDSN, "d", cfg.PostgresDSN, "postgres DSN")
+	flags.StringVar(&cfg.MigrationsPath, "m", cfg.MigrationsPath, "path to db migrations")
+	flags.StringVar(&cfg.SecretKey, "k", cfg.SecretKey, "secret key for auth")
+	flags.Var((*stringList)(&cfg.SecretKeys), "secret-keys", "comma separated kid:secret jwt signing keys")
+	flags.StringVar(&cfg.SecretKeyID, "secret-key-id", cfg.SecretKeyID, "kid of key for signing new jwt, default is -k key")
+	flags.DurationVar(&cfg.JWTTTL, "jwt-ttl", cfg.JWTTTL, "jwt lifetime, 0 disables expiration")
+	flags.DurationVar(&cfg.JWTRefresh, "jwt-refresh", cfg.JWTRefresh, "reissue jwt older than this, 0 disables sliding refresh")
+	flags.StringVar(&cfg.CookieDomain, "cookie-domain", cfg.CookieDomain, "domain attribute of auth cookie")
+	flags.BoolVar(&cfg.CookieSecure, "cookie-secure", cfg.CookieSecure, "set secure attribute of auth cookie")
+	flags.StringVar(&cfg.CookieSameSite, "cookie-same-site", cfg.CookieSameSite, "samesite attribute of auth cookie: lax, strict or none")
+	flags.DurationVar(&cfg.ExpiredPurgeInterval, "purge-interval", cfg.ExpiredPurgeInterval, "interval for purging expired records")
+	flags.DurationVar(&cfg.DeletedGracePeriod, "deleted-grace", cfg.DeletedGracePeriod, "period during which deleted records can be restored")
+	flags.DurationVar(&cfg.DeletedPurgeInterval, "deleted-purge-interval", cfg.DeletedPurgeInterval, "interval for purging deleted records after grace period")
+	flags.IntVar(&cfg.DeleteMaxAttempts, "delete-attempts", cfg.DeleteMaxAttempts, "attempts to write a deletion batch before moving it to dead letters")
+	flags.DurationVar(&cfg.DeleteRetryBackoff, "delete-backoff", cfg.DeleteRetryBackoff, "initial pause between deletion batch attempts, doubled after each attempt")
+	flags.StringVar(&cfg.NotFoundPage, "not-found-page", cfg.NotFoundPage, "path to html page for unknown short urls")
+	flags.StringVar(&cfg.TrustedSubnet, "t", cfg.TrustedSubnet, "CIDR of trusted subnet for internal stats")
+	flags.BoolVar(&cfg.EnableHTTPS, "s", cfg.EnableHTTPS, "enable https")
+	flags.StringVar(&cfg.TLSCertFile, "tls-cert", cfg.TLSCertFile, "path to tls certificate")
+	flags.StringVar(&cfg.TLSKeyFile, "tls-key", cfg.TLSKeyFile, "path to tls private key")
+	flags.IntVar(&cfg.ShortIDMaxAttempts, "short-id-attempts", cfg.ShortIDMaxAttempts, "max attempts to generate unused short id")
+	flags.StringVar(&cfg.ShortIDGenerator, "short-id-generator", cfg.ShortIDGenerator, "short id generator: random or counter")
+	flags.StringVar(&cfg.ShortIDKey, "short-id-key", cfg.ShortIDKey, "secret key for counter short id permutation")
+	flags.IntVar(&cfg.KeyPoolSize, "key-pool-size", cfg.KeyPoolSize, "size of pre-generated short id batch, 0 disables key pool")
+	flags.IntVar(&cfg.KeyPoolWatermark, "key-pool-watermark", cfg.KeyPoolWatermark, "refill key pool when it has no more keys than this")
+	flags.Float64Var(&cfg.WriteRateLimit, "write-rate", cfg.WriteRateLimit, "write requests per second per client, 0 disables limit")
+	flags.IntVar(&cfg.WriteRateBurst, "write-burst", cfg.WriteRateBurst, "burst of write requests per client")
+	flags.Float64Var(&cfg.RedirectRateLimit, "redirect-rate", cfg.RedirectRateLimit, "redirects per second per client, 0 disables limit")
+	flags.IntVar(&cfg.RedirectRateBurst, "redirect-burst", cfg.RedirectRateBurst, "burst of redirects per client")
+	flags.StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", cfg.OTLPEndpoint, "<host>:<port> or http(s) URL of OTLP gRPC collector for traces")
+	flags.StringVar(&cfg.GRPCAddr, "grpc-addr", cfg.GRPCAddr, "<host>:<port> for grpc server, empty disables grpc")
+	flags.DurationVar(&cfg.ShutdownDrainDelay, "shutdown-drain", cfg.ShutdownDrainDelay, "pause between failing readiness and stopping servers on shutdown")
+	flags.Var((*stringList)(&cfg.TrustedProxies), "trusted-proxies", "comma separated CIDRs of trusted proxies")
+	flags.Var((*stringList)(&cfg.URLSchemes), "url-schemes", "comma separated schemes allowed in shortened urls")
+	flags.StringVar(&cfg.URLOwnership, "url-ownership", cfg.URLOwnership, "url ownership: shared or separate short ids for users")
+	flags.StringVar(&cfg.DomainPolicyFile, "domain-policy", cfg.DomainPolicyFile, "path to allow/deny domain rules file")
+	flags.StringVar(&cfg.URLFragment, "url-fragment", cfg.URLFragment, "url fragment policy: keep, strip or reject")
+}
+
+// applyFlags - переносим в cfg только явно заданные флаги
+func applyFlags(flags *flag.FlagSet, cfg *Config) error {
+	target := flag.NewFlagSet("apply", flag.ContinueOnError)
+	registerFlags(target, cfg)
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if target.Lookup(f.Name) == nil {
+			return
+		}
+		if setErr := target.Set(f.Name, f.Value.String()); setErr != nil {
+			err = errors.Join(err, fmt.Errorf("flag -%s: %w", f.Name, setErr))
+		}
+	})
+	return err
+}
+
+// LoadFile - загрузить конфиг из JSON или YAML файла поверх значений в cfg.
+// Неизвестные ключи считаются ошибкой.
+func LoadFile(filePath string, cfg *Config) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
 	}
-	return addr
+	// JSON является подмножеством YAML, поэтому оба формата читаем одним декодером
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(cfg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", filePath, err)
+	}
+	return nil
 }
 
-func prepareBaseURL(baseURL, addr string) string {
-	if baseURL != "" {
-		if !strings.HasPrefix(baseURL, "http://") {
-			return "http://" + baseURL
+// Validate - проверить значения конфига
+func (cfg *Config) Validate() error {
+	var errs []error
+	if err := validateAddr(cfg.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("server_address: %w", err))
+	}
+	if cfg.GRPCAddr != "" {
+		if err := validateAddr(cfg.GRPCAddr); err != nil {
+			errs = append(errs, fmt.Errorf("grpc_address: %w", err))
 		}
-		return baseURL
 	}
-	return "http://" + addr
+	if cfg.OTLPEndpoint != "" {
+		if err := validateOTLPEndpoint(cfg.OTLPEndpoint); err != nil {
+			errs = append(errs, fmt.Errorf("otlp_endpoint: %w", err))
+		}
+	}
+	if cfg.PostgresDSN != "" {
+		if _, err := pgconn.ParseConfig(cfg.PostgresDSN); err != nil {
+			errs = append(errs, fmt.Errorf("database_dsn: invalid postgres DSN: %w", err))
+		}
+	}
+	errs = append(errs, cfg.validateSecretKeys()...)
+	if cfg.JWTTTL < 0 || cfg.JWTRefresh < 0 {
+		errs = append(errs, errors.New("jwt_ttl and jwt_refresh must not be negative"))
+	}
+	switch cfg.CookieSameSite {
+	case SameSiteLax, SameSiteStrict:
+	case SameSiteNone:
+		if !cfg.CookieSecure && !cfg.EnableHTTPS {
+			errs = append(errs, errors.New("cookie_same_site: none requires cookie_secure or enable_https"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("cookie_same_site: unknown value %q, expected %s, %s or %s", cfg.CookieSameSite, SameSiteLax, SameSiteStrict, SameSiteNone))
+	}
+	if cfg.ExpiredPurgeInterval <= 0 {
+		errs = append(errs, fmt.Errorf("expired_purge_interval: must be positive, got %s", cfg.ExpiredPurgeInterval))
+	}
+	if cfg.DeletedGracePeriod < 0 {
+		errs = append(errs, fmt.Errorf("deleted_grace_period: must not be negative, got %s", cfg.DeletedGracePeriod))
+	}
+	if cfg.DeletedPurgeInterval <= 0 {
+		errs = append(errs, fmt.Errorf("deleted_purge_interval: must be positive, got %s", cfg.DeletedPurgeInterval))
+	}
+	if cfg.DeleteMaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("delete_max_attempts: must be at least 1, got %d", cfg.DeleteMaxAttempts))
+	}
+	if cfg.ShutdownDrainDelay < 0 {
+		errs = append(errs, fmt.Errorf("shutdown_drain_delay: must not be negative, got %s", cfg.ShutdownDrainDelay))
+	}
+	if cfg.DeleteRetryBackoff <= 0 {
+		errs = append(errs, fmt.Errorf("delete_retry_backoff: must be positive, got %s", cfg.DeleteRetryBackoff))
+	}
+	if cfg.ShortIDMaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("short_id_max_attempts: must be at least 1, got %d", cfg.ShortIDMaxAttempts))
+	}
+	if cfg.ShortIDGenerator != GeneratorRandom && cfg.ShortIDGenerator != GeneratorCounter {
+		errs = append(errs, fmt.Errorf("short_id_generator: unknown generator %q, expected %s or %s", cfg.ShortIDGenerator, GeneratorRandom, GeneratorCounter))
+	}
+	if len(cfg.URLSchemes) == 0 {
+		errs = append(errs, errors.New("url_schemes: must not be empty"))
+	}
+	if fragment := domain.URLFragmentPolicy(cfg.URLFragment); fragment != domain.FragmentKeep && fragment != domain.FragmentStrip && fragment != domain.FragmentReject {
+		errs = append(errs, fmt.Errorf("url_fragment: unknown policy %q, expected %s, %s or %s", cfg.URLFragment, domain.FragmentKeep, domain.FragmentStrip, domain.FragmentReject))
+	}
+	if ownership := domain.URLOwnership(cfg.URLOwnership); ownership != domain.OwnershipShared && ownership != domain.OwnershipSeparate {
+		errs = append(errs, fmt.Errorf("url_ownership: unknown mode %q, expected %s or %s", cfg.URLOwnership, domain.OwnershipShared, domain.OwnershipSeparate))
+	}
+	if cfg.KeyPoolSize < 0 || cfg.KeyPoolWatermark < 0 {
+		errs = append(errs, errors.New("key_pool_size and key_pool_watermark must not be negative"))
+	}
+	if cfg.WriteRateLimit < 0 || cfg.WriteRateBurst < 0 || cfg.RedirectRateLimit < 0 || cfg.RedirectRateBurst < 0 {
+		errs = append(errs, errors.New("rate limits and bursts must not be negative"))
+	}
+	if cfg.WriteRateLimit > 0 && cfg.WriteRateBurst == 0 {
+		errs = append(errs, errors.New("write_rate_burst: must be set when write_rate_limit is enabled"))
+	}
+	if cfg.RedirectRateLimit > 0 && cfg.RedirectRateBurst == 0 {
+		errs = append(errs, errors.New("redirect_rate_burst: must be set when redirect_rate_limit is enabled"))
+	}
+	if cfg.TrustedSubnet != "" {
+		if _, _, err := net.ParseCIDR(cfg.TrustedSubnet); err != nil {
+			errs = append(errs, fmt.Errorf("trusted_subnet: %w", err))
+		}
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		errs = append(errs, errors.New("tls_cert_file and tls_key_file must be set together"))
+	}
+	return errors.Join(errs...)
 }
 
-func prepareConfig(cfg *Config) {
-	cfg.Addr = prepareAddr(cfg.Addr)
-	cfg.BaseURL = prepareBaseURL(cfg.BaseURL, cfg.Addr)
+// validateSecretKeys - ключи подписи JWT заданы как kid:secret без повторов, ключ SecretKeyID существует
+func (cfg *Config) validateSecretKeys() []error {
+	var errs []error
+	kids := make(map[string]bool, len(cfg.SecretKeys)+1)
+	if cfg.SecretKey != "" {
+		kids["default"] = true
+	}
+	for _, entry := range cfg.SecretKeys {
+		kid, secret, ok := strings.Cut(entry, ":")
+		if !ok || kid == "" || secret == "" {
+			errs = append(errs, errors.New("secret_keys: entries must be kid:secret"))
+			continue
+		}
+		if kids[kid] {
+			errs = append(errs, fmt.Errorf("secret_keys: duplicate kid %q", kid))
+		}
+		kids[kid] = true
+	}
+	if !kids[cfg.SecretKeyID] {
+		errs = append(errs, fmt.Errorf("secret_key_id: unknown kid %q", cfg.SecretKeyID))
+	}
+	return errs
+}
+
+// Print - вывести конфиг в формате YAML, секреты скрываются
+func (cfg *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	defer encoder.Close()
+	err := encoder.Encode(cfg.Redacted())
+	if err != nil {
+		return fmt.Errorf("print config: %w", err)
+	}
+	return nil
+}
+
+// Redacted - копия конфига со скрытыми секретами
+func (cfg *Config) Redacted() *Config {
+	result := *cfg
+	if result.SecretKey != "" {
+		result.SecretKey = redacted
+	}
+	if len(result.SecretKeys) > 0 {
+		result.SecretKeys = make([]string, 0, len(cfg.SecretKeys))
+		for _, entry := range cfg.SecretKeys {
+			kid, _, _ := strings.Cut(entry, ":")
+			result.SecretKeys = append(result.SecretKeys, kid+":"+redacted)
+		}
+	}
+	if result.ShortIDKey != "" {
+		result.ShortIDKey = redacted
+	}
+	result.PostgresDSN = redactDSN(result.PostgresDSN)
+	return &result
+}
+
+// redactDSN - маскирует все пароли в DSN: в userinfo и параметре password
+// URL-формы, а также в ключе password формы key=value (в том числе в кавычках).
+// Если DSN не удаётся разобрать, он скрывается целиком.
+func redactDSN(dsn string) string {
+	if dsn == "" {
+		return dsn
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return redactURLDSN(dsn)
+	}
+	return redactKeywordDSN(dsn)
+}
+
+func redactURLDSN(dsn string) string {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return redacted
+	}
+	userinfo := ""
+	if parsed.User != nil {
+		userinfo = url.User(parsed.User.Username()).String()
+		if _, ok := parsed.User.Password(); ok {
+			userinfo += ":" + redacted
+		}
+		userinfo += "@"
+		parsed.User = nil
+	}
+	if parsed.RawQuery != "" {
+		params := strings.Split(parsed.RawQuery, "&")
+		for i, param := range params {
+			key, _, _ := strings.Cut(param, "=")
+			if name, err := url.QueryUnescape(key); err != nil || name == "password" {
+				params[i] = key + "=" + redacted
+			}
+		}
+		parsed.RawQuery = strings.Join(params, "&")
+	}
+	scheme, rest, _ := strings.Cut(parsed.String(), "://")
+	return scheme + "://" + userinfo + rest
+}
+
+func redactKeywordDSN(dsn string) string {
+	var result strings.Builder
+	i := 0
+	skipSpaces := func() {
+		for i < len(dsn) && isDSNSpace(dsn[i]) {
+			result.WriteByte(dsn[i])
+			i++
+		}
+	}
+	for {
+		skipSpaces()
+		if i == len(dsn) {
+			return result.String()
+		}
+		keyStart := i
+		for i < len(dsn) && dsn[i] != '=' && !isDSNSpace(dsn[i]) {
+			i++
+		}
+		key := dsn[keyStart:i]
+		result.WriteString(key)
+		skipSpaces()
+		if i == len(dsn) || dsn[i] != '=' {
+			return redacted
+		}
+		result.WriteByte('=')
+		i++
+		skipSpaces()
+		valueStart := i
+		if i < len(dsn) && dsn[i] == '\'' {
+			i++
+			for i < len(dsn) && dsn[i] != '\'' {
+				if dsn[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(dsn) {
+				return redacted
+			}
+			i++
+		} else {
+			for i < len(dsn) && !isDSNSpace(dsn[i]) {
+				if dsn[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i > len(dsn) {
+				return redacted
+			}
+		}
+		if key == "password" {
+			result.WriteString(redacted)
+		} else {
+			result.WriteString(dsn[valueStart:i])
+		}
+	}
+}
+
+func isDSNSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
 }
 
-func mergeConf(envCfg *Config, flagConfig *Config) *Config {
-	cfg := &Config{
-		Addr:            envCfg.Addr,
-		BaseURL:         envCfg.BaseURL,
-		FileStoragePath: envCfg.FileStoragePath,
-		PostgresDSN:     envCfg.PostgresDSN,
-		MigrationsPath:  envCfg.MigrationsPath,
-		SecretKey:       envCfg.SecretKey,
+// validateOTLPEndpoint - адрес коллектора в виде <host>:<port> или URL со схемой http или https,
+// как в стандартной переменной OTEL_EXPORTER_OTLP_ENDPOINT.
+func validateOTLPEndpoint(endpoint string) error {
+	if !strings.Contains(endpoint, "://") {
+		return validateAddr(endpoint)
 	}
-	if cfg.Addr == "" {
-		cfg.Addr = flagConfig.Addr
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint URL %q: %w", endpoint, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("invalid scheme %q in endpoint %q, expected http or https", parsed.Scheme, endpoint)
+	}
+	if parsed.Hostname() == "" {
+		return fmt.Errorf("no host in endpoint %q", endpoint)
+	}
+	if port := parsed.Port(); port != "" {
+		return validateAddr(parsed.Host)
 	}
-	if cfg.BaseURL == "" {
-		cfg.BaseURL = flagConfig.BaseURL
+	return nil
+}
+
+func validateAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q, expected <host>:<port>", addr)
 	}
-	if cfg.FileStoragePath == "" {
-		cfg.FileStoragePath = flagConfig.FileStoragePath
+	number, err := strconv.Atoi(port)
+	if err != nil || number < 0 || number > 65535 {
+		return fmt.Errorf("invalid port %q in address %q", port, addr)
 	}
-	if cfg.PostgresDSN == "" {
-		cfg.PostgresDSN = flagConfig.PostgresDSN
+	return nil
+}
+
+// stringList - флаг со списком значений через запятую
+type stringList []string
+
+func (list *stringList) String() string {
+	if list == nil {
+		return ""
 	}
-	if cfg.MigrationsPath == "" {
-		cfg.MigrationsPath = flagConfig.MigrationsPath
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = strings.Split(value, ",")
+	return nil
+}
+
+func prepareAddr(addr string) string {
+	addr = strings.TrimPrefix(addr, "http://")
+	return strings.TrimPrefix(addr, "https://")
+}
+
+func prepareBaseURL(baseURL, addr string, enableHTTPS bool) string {
+	scheme := "http://"
+	if enableHTTPS {
+		scheme = "https://"
 	}
-	if cfg.SecretKey == "" {
-		cfg.SecretKey = flagConfig.SecretKey
+	if baseURL != "" {
+		if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+			return scheme + baseURL
+		}
+		return baseURL
 	}
-	return cfg
+	return scheme + addr
+}
+
+func prepareConfig(cfg *Config) {
+	cfg.Addr = prepareAddr(cfg.Addr)
+	cfg.BaseURL = prepareBaseURL(cfg.BaseURL, cfg.Addr, cfg.EnableHTTPS)
 }
@@ -1,6 +1,8 @@
 // Пакет domain описывает основные сущности, используемые в проекте.
 package domain
 
+import "time"
+
 // ShortID - тип для короткого идентификатора.
 type ShortID string
 
@@ -13,37 +15,326 @@ type URL string
 // UID - тип для uid пользователя.
 type UID string
 
+// URLOwnership - как пользователи, сократившие один и тот же URL, делят короткий идентификатор.
+type URLOwnership string
+
+const (
+	// OwnershipShared - один короткий идентификатор на URL, каждый сокративший пользователь становится его владельцем.
+	OwnershipShared URLOwnership = "shared"
+	// OwnershipSeparate - у каждого пользователя свой короткий идентификатор для URL.
+	OwnershipSeparate URLOwnership = "separate"
+)
+
+// URLFragmentPolicy - что делать с фрагментом (#...) в сокращаемом URL.
+type URLFragmentPolicy string
+
+const (
+	// FragmentKeep - сохранять фрагмент.
+	FragmentKeep URLFragmentPolicy = "keep"
+	// FragmentStrip - отбрасывать фрагмент.
+	FragmentStrip URLFragmentPolicy = "strip"
+	// FragmentReject - отклонять URL с фрагментом.
+	FragmentReject URLFragmentPolicy = "reject"
+)
+
+// DefaultURLSchemes - схемы, разрешенные в сокращаемых URL по умолчанию.
+var DefaultURLSchemes = []string{"http", "https"}
+
 // Record определяет тип для записи к БД.
 type Record struct {
-	UID UID
-	URL URL
+	UID         UID
+	URL         URL
+	CustomAlias ShortID
+	// ExpiresAt - момент, после которого ссылка перестает работать. nil - бессрочная ссылка.
+	ExpiresAt *time.Time
+	// TTL - время жизни ссылки в секундах, альтернатива ExpiresAt.
+	TTL int64
 }
 
 // URLData - тип записи реального URL и сокращенного URL.
 type URLData struct {
-	URL      URL     `json:"original_url"`
-	ShortID  ShortID `json:"-"`
-	ShortURL URL     `json:"short_url"`
+	URL       URL        `json:"original_url"`
+	ShortID   ShortID    `json:"-"`
+	ShortURL  URL        `json:"short_url"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// URLUpdate - изменение ссылки владельцем. Пустые поля оставляют прежнее значение.
+type URLUpdate struct {
+	// URL - новый адрес назначения.
+	URL *URL
+	// ExpiresAt - новый момент истечения срока действия.
+	ExpiresAt *time.Time
+	// TTL - новое время жизни в секундах от момента изменения, альтернатива ExpiresAt.
+	TTL int64
+	// ClearExpiry - сделать ссылку бессрочной.
+	ClearExpiry bool
+	// UpdatedAt - момент изменения, попадает в историю.
+	UpdatedAt time.Time
+}
+
+// URLRevision - состояние ссылки до очередного изменения.
+type URLRevision struct {
+	URL       URL        `json:"original_url"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ChangedAt time.Time  `json:"changed_at"`
 }
 
 // BatchRecord - тип записи при добавления записей батчей.
 type BatchRecord struct {
-	CorrID   string  `json:"correlation_id"`
-	URL      URL     `json:"original_url"`
-	ShortID  ShortID `json:"-"`
-	ShortURL URL     `json:"short_url"`
+	CorrID      string     `json:"correlation_id"`
+	URL         URL        `json:"original_url"`
+	CustomAlias ShortID    `json:"custom_alias,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	TTL         int64      `json:"ttl,omitempty"`
+	ShortID     ShortID    `json:"-"`
+	ShortURL    URL        `json:"short_url"`
+	// Status - создана новая запись или пользователь получил уже существующую.
+	Status BatchStatus `json:"status,omitempty"`
 }
 
+// BatchStatus - результат добавления записи батча.
+type BatchStatus string
+
+const (
+	// BatchStatusCreated - создана новая запись.
+	BatchStatusCreated BatchStatus = "created"
+	// BatchStatusExisting - для URL уже была действующая запись, пользователь стал ее владельцем.
+	BatchStatusExisting BatchStatus = "existing"
+)
+
 // BackupRecord - тип для хранения данных в файле.
 type BackupRecord struct {
-	UUID    string  `json:"uuid"`
-	ShortID ShortID `json:"short_url"`
-	URL     URL     `json:"original_url"`
-	UID     UID     `json:"uid,omitempty"`
+	UUID      string     `json:"uuid"`
+	ShortID   ShortID    `json:"short_url"`
+	URL       URL        `json:"original_url"`
+	UID       UID        `json:"uid,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// IsDeleted - запись-надгробие: ссылка ShortID пользователя UID помечена удаленной.
+	IsDeleted bool `json:"is_deleted,omitempty"`
+	// UpdatedAt - запись изменения: пользователь UID изменил ссылку ShortID, URL и ExpiresAt - новые значения.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// DeletedAt - момент удаления для записи-надгробия.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// IsRestored - запись восстановления: пользователь UID отменил удаление ссылки ShortID.
+	IsRestored bool `json:"is_restored,omitempty"`
+	// PurgedBefore - запись очистки: окончательно удалены ссылки, удаленные раньше этого момента.
+	PurgedBefore *time.Time `json:"purged_before,omitempty"`
+	// ExpiredBefore - запись очистки: удалены ссылки, срок действия которых истек к этому моменту.
+	ExpiredBefore *time.Time `json:"expired_before,omitempty"`
+	// Click - запись перехода по ссылке ShortID.
+	Click *Click `json:"click,omitempty"`
 }
 
 // DeleteData - данные для пометки URL как удаленного.
 type DeleteData struct {
 	UID     string
 	ShortID string
+	// DeletedAt - момент удаления, нулевое значение - текущий момент.
+	DeletedAt time.Time
+	// JobID - задача удаления, в которую входит запись.
+	JobID string
+	// Status - результат удаления, заполняется репозиторием после успешной записи батча.
+	Status DeleteStatus
+}
+
+// DeleteStatus - результат удаления одной ссылки.
+type DeleteStatus string
+
+const (
+	// DeleteStatusPending - ссылка ожидает удаления.
+	DeleteStatusPending DeleteStatus = "pending"
+	// DeleteStatusDeleted - ссылка удалена у пользователя.
+	DeleteStatusDeleted DeleteStatus = "deleted"
+	// DeleteStatusNotFound - ссылки нет или она уже удалена пользователем.
+	DeleteStatusNotFound DeleteStatus = "not_found"
+	// DeleteStatusNotOwned - ссылка есть, но пользователь ей не владеет.
+	DeleteStatusNotOwned DeleteStatus = "not_owned"
+	// DeleteStatusFailed - батч с ссылкой не удалось записать за отведенные попытки, ссылка попала в dead letter.
+	DeleteStatusFailed DeleteStatus = "failed"
+)
+
+// DeletionJobStatus - состояние задачи удаления.
+type DeletionJobStatus string
+
+const (
+	// DeletionJobPending - часть ссылок задачи еще ожидает удаления.
+	DeletionJobPending DeletionJobStatus = "pending"
+	// DeletionJobDone - все ссылки задачи обработаны.
+	DeletionJobDone DeletionJobStatus = "done"
+	// DeletionJobFailed - часть ссылок задачи удалить не удалось.
+	DeletionJobFailed DeletionJobStatus = "failed"
+)
+
+// DeletionItem - ссылка задачи удаления и ее результат.
+type DeletionItem struct {
+	ShortID ShortID      `json:"short_id"`
+	Status  DeleteStatus `json:"status"`
+}
+
+// DeletionJob - задача удаления ссылок пользователя.
+type DeletionJob struct {
+	ID         string            `json:"id"`
+	UID        UID               `json:"-"`
+	Status     DeletionJobStatus `json:"status"`
+	Items      []DeletionItem    `json:"items"`
+	CreatedAt  time.Time         `json:"created_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+// DeadLetter - ссылка, удаление которой не удалось записать за отведенные попытки.
+type DeadLetter struct {
+	JobID    string    `json:"job_id"`
+	UID      UID       `json:"uid"`
+	ShortID  ShortID   `json:"short_id"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// RestoreStatus - результат восстановления удаленной ссылки.
+type RestoreStatus string
+
+const (
+	// RestoreStatusRestored - удаление отменено.
+	RestoreStatusRestored RestoreStatus = "restored"
+	// RestoreStatusNotFound - пользователь не удалял ссылку или срок восстановления истек.
+	RestoreStatusNotFound RestoreStatus = "not_found"
+	// RestoreStatusConflict - для URL уже есть другая действующая запись.
+	RestoreStatusConflict RestoreStatus = "conflict"
+)
+
+// RestoreResult - результат восстановления одной ссылки.
+type RestoreResult struct {
+	ShortID ShortID       `json:"short_id"`
+	Status  RestoreStatus `json:"status"`
+}
+
+// PurgeResult - число окончательно удаленных записей и строк владельцев.
+type PurgeResult struct {
+	Records int64
+	Owners  int64
+}
+
+// Click - данные о переходе по сокращенной ссылке.
+type Click struct {
+	ShortID   ShortID   `json:"short_id"`
+	ClickedAt time.Time `json:"clicked_at"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+}
+
+// DateLayout - формат даты в статистике переходов.
+const DateLayout = "2006-01-02"
+
+// DailyClicks - число переходов за день.
+type DailyClicks struct {
+	Date   string `json:"date"`
+	Clicks int64  `json:"clicks"`
+}
+
+// Stats - статистика сервиса.
+type Stats struct {
+	URLs  int64 `json:"urls"`
+	Users int64 `json:"users"`
+}
+
+// LinkStats - статистика переходов по сокращенной ссылке.
+type LinkStats struct {
+	TotalClicks    int64         `json:"total_clicks"`
+	UniqueVisitors int64         `json:"unique_visitors"`
+	Daily          []DailyClicks `json:"daily"`
+}
+
+// Статусы готовности сервиса и отдельных проверок.
+const (
+	HealthStatusOK           = "ok"
+	HealthStatusFail         = "fail"
+	HealthStatusShuttingDown = "shutting_down"
+)
+
+// CheckResult - результат одной проверки готовности.
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// HealthReport - отчет о готовности сервиса.
+type HealthReport struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// RateLimit - параметры token bucket: Rate токенов в секунду, не больше Burst токенов в корзине.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled - ограничение задано.
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Take - пополнить корзину за elapsed и забрать один токен.
+// Возвращает оставшиеся токены, разрешен ли запрос и через сколько появится следующий токен.
+func (l RateLimit) Take(tokens float64, elapsed time.Duration) (float64, bool, time.Duration) {
+	if elapsed > 0 {
+		tokens += elapsed.Seconds() * l.Rate
+	}
+	if tokens > float64(l.Burst) {
+		tokens = float64(l.Burst)
+	}
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+	return tokens, false, time.Duration((1 - tokens) / l.Rate * float64(time.Second))
+}
+
+// FullAt - когда корзина, в которой на момент at осталось tokens токенов, пополнится полностью.
+// После этого момента корзина ничем не отличается от новой и ее можно удалить.
+func (l RateLimit) FullAt(tokens float64, at time.Time) time.Time {
+	if tokens >= float64(l.Burst) {
+		return at
+	}
+	return at.Add(time.Duration((float64(l.Burst) - tokens) / l.Rate * float64(time.Second)))
+}
+
+// Scope - право API ключа.
+type Scope string
+
+const (
+	// ScopeRead - чтение ссылок пользователя и статистики.
+	ScopeRead Scope = "read"
+	// ScopeShorten - сокращение ссылок.
+	ScopeShorten Scope = "shorten"
+	// ScopeDelete - удаление ссылок пользователя.
+	ScopeDelete Scope = "delete"
+)
+
+// APIKey - API ключ пользователя. Секрет ключа не хранится, только его хеш.
+type APIKey struct {
+	ID        string     `json:"id"`
+	UID       UID        `json:"uid"`
+	Name      string     `json:"name,omitempty"`
+	Hash      string     `json:"hash"`
+	Scopes    []Scope    `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyPrefix - префикс токена API ключа, токен имеет вид mu_<id>.<secret>.
+const APIKeyPrefix = "mu_"
+
+// HasScope - у ключа есть право scope.
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
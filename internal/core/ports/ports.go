@@ -3,6 +3,8 @@ package ports
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/Svirex/microurl/internal/core/domain"
 )
@@ -13,6 +15,73 @@ var ErrAlreadyExists = errors.New("already exists")
 // ErrNotFound - ошибка "не найдено"
 var ErrNotFound = errors.New("not found")
 
+// ErrShortIDExists - ошибка "короткий идентификатор уже занят"
+var ErrShortIDExists = errors.New("short id already exists")
+
+// ErrAliasAlreadyExists - ошибка "пользовательский алиас уже занят"
+var ErrAliasAlreadyExists = errors.New("alias already exists")
+
+// ErrInvalidAlias - ошибка "некорректный пользовательский алиас"
+var ErrInvalidAlias = errors.New("invalid alias")
+
+// ErrNotCreator - ошибка "изменять ссылку может только ее создатель"
+var ErrNotCreator = errors.New("not link creator")
+
+// ErrSharedLink - ошибка "ссылкой пользуются другие владельцы, ее нельзя изменить"
+var ErrSharedLink = errors.New("link is shared with other users")
+
+// ErrDeleted - ошибка "ссылка удалена"
+var ErrDeleted = errors.New("deleted")
+
+// ErrExpired - ошибка "срок действия ссылки истек"
+var ErrExpired = errors.New("expired")
+
+// ErrInvalidExpiry - ошибка "некорректный срок действия ссылки"
+var ErrInvalidExpiry = errors.New("invalid expiry")
+
+// ErrInvalidURL - ошибка "некорректный URL"
+var ErrInvalidURL = errors.New("invalid url")
+
+// ErrForbiddenDomain - ошибка "домен запрещен политикой"
+var ErrForbiddenDomain = errors.New("forbidden domain")
+
+// ErrInvalidAPIKey - ошибка "API ключ не существует, отозван или подделан"
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// ErrInvalidScope - ошибка "неизвестное или пустое право API ключа"
+var ErrInvalidScope = errors.New("invalid scope")
+
+// URLValidationError - URL не прошел проверку, Reason - причина.
+type URLValidationError struct {
+	URL    string
+	Reason string
+}
+
+// Error - текст ошибки.
+func (e *URLValidationError) Error() string {
+	return fmt.Sprintf("invalid url %q: %s", e.URL, e.Reason)
+}
+
+// Unwrap - ошибка сводится к ErrInvalidURL.
+func (e *URLValidationError) Unwrap() error {
+	return ErrInvalidURL
+}
+
+// ShortIDExistsError - ошибка с указанием занятого короткого идентификатора.
+type ShortIDExistsError struct {
+	ShortID domain.ShortID
+}
+
+// Error - текст ошибки.
+func (e *ShortIDExistsError) Error() string {
+	return fmt.Sprintf("short id %q already exists", e.ShortID)
+}
+
+// Unwrap - ошибка сводится к ErrShortIDExists.
+func (e *ShortIDExistsError) Unwrap() error {
+	return ErrShortIDExists
+}
+
 // ShortenerService - интерфейс сервиса сокращения ссылок.
 type ShortenerService interface {
 	// Add - добавить запись и вернуть сокращенный URL.
@@ -27,6 +96,15 @@ type ShortenerService interface {
 	// UserURLs - получить все записи для определенного пользователя
 	UserURLs(ctx context.Context, uid domain.UID) ([]domain.URLData, error)
 
+	// Update - изменить ссылку пользователя и вернуть ее новое состояние.
+	Update(ctx context.Context, uid domain.UID, shortID domain.ShortID, update *domain.URLUpdate) (*domain.URLData, error)
+
+	// URLRevisions - история изменений ссылки пользователя.
+	URLRevisions(ctx context.Context, uid domain.UID, shortID domain.ShortID) ([]domain.URLRevision, error)
+
+	// Stats - получить количество сокращенных URL и пользователей.
+	Stats(ctx context.Context) (*domain.Stats, error)
+
 	// Shutdown - завершить сервис
 	Shutdown() error
 }
@@ -44,21 +122,53 @@ type DBCheckerService interface {
 type ShortenerRepository interface {
 	// Add - добавить короткие идентификатор и URL для пользователя в БД.
 	// Если такой URL уже есть, то вернуть соответствующий ему идентификатор.
+	// Если занят короткий идентификатор, то вернуть *ShortIDExistsError.
 	Add(ctx context.Context, shortID domain.ShortID, data *domain.Record) (domain.ShortID, error)
 
 	// Get - получить оригинальный URL по идентификатору.
+	// Если идентификатора нет, то вернуть ErrNotFound, если ссылка удалена - ErrDeleted,
+	// если срок действия ссылки истек - ErrExpired.
 	Get(ctx context.Context, shortID domain.ShortID) (domain.URL, error)
 
 	// Batch - записать в БД батч записей.
+	// Если занят короткий идентификатор одной из записей, то вернуть *ShortIDExistsError.
 	Batch(ctx context.Context, uid domain.UID, data []domain.BatchRecord) ([]domain.BatchRecord, error)
 
 	// UserURLs - вернуть все записи для пользователя
 	UserURLs(ctx context.Context, uid domain.UID) ([]domain.URLData, error)
 
+	// Update - изменить адрес назначения и срок действия ссылки, прежнее состояние сохраняется в истории.
+	// Если ссылки нет, она удалена или пользователь ей не владеет, то вернуть ErrNotFound,
+	// если срок действия истек - ErrExpired, если у пользователя уже есть действующая запись
+	// нового URL - ErrAlreadyExists.
+	Update(ctx context.Context, uid domain.UID, shortID domain.ShortID, update *domain.URLUpdate) (*domain.URLData, error)
+
+	// URLRevisions - прежние состояния ссылки от старых к новым.
+	// Если пользователь не владеет ссылкой, то вернуть ErrNotFound.
+	URLRevisions(ctx context.Context, uid domain.UID, shortID domain.ShortID) ([]domain.URLRevision, error)
+
+	// Stats - вернуть количество сокращенных URL и уникальных пользователей
+	Stats(ctx context.Context) (*domain.Stats, error)
+
+	ExpiredRepository
+
 	// Shutdown - выключить сервис
 	Shutdown() error
 }
 
+// ExpiredRepository - репозиторий, который удаляет записи с истекшим сроком действия.
+type ExpiredRepository interface {
+	// PurgeExpired - удалить записи, срок действия которых истек к моменту now.
+	// Возвращает число удаленных записей.
+	PurgeExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// ReaperService - интерфейс сервиса, который периодически удаляет просроченные записи.
+type ReaperService interface {
+	Run() error
+	Shutdown() error
+}
+
 // BackupWriter - интерфейс для сохранения записей в файл.
 type BackupWriter interface {
 	Write(ctx context.Context, record *domain.BackupRecord) error
@@ -68,24 +178,155 @@ type BackupWriter interface {
 type BackupReader interface {
 	Next() bool
 	Read(ctx context.Context) (*domain.BackupRecord, error)
-	Restore(ctx context.Context, repo ShortenerRepository) error
+	Restore(ctx context.Context, repo ShortenerRepository, deleter DeleterRepository) error
 }
 
 // StringGenerator - интерфейс генератора рандомных строк.
 type StringGenerator interface {
-	Generate(ctx context.Context, size uint) string
+	Generate(ctx context.Context, size uint) (string, error)
+}
+
+// KeyPoolRepository - хранилище заранее сгенерированных коротких идентификаторов.
+type KeyPoolRepository interface {
+	// TakeKeys - забрать из хранилища до n ключей, забранные ключи из хранилища удаляются.
+	TakeKeys(ctx context.Context, n int) ([]domain.ShortID, error)
+
+	// PutKeys - положить ключи в хранилище. Ключи, уже занятые записями или уже лежащие в хранилище, отбрасываются.
+	PutKeys(ctx context.Context, keys []domain.ShortID) error
+}
+
+// KeyPoolService - генератор, который выдает короткие идентификаторы из заранее подготовленного пула.
+type KeyPoolService interface {
+	StringGenerator
+
+	// Run - запуск фонового пополнения пула.
+	Run() error
+
+	// Shutdown - остановка сервиса, неиспользованные ключи возвращаются в хранилище.
+	Shutdown() error
+}
+
+// Counter - монотонный счетчик для генерации коротких идентификаторов.
+type Counter interface {
+	// Next - следующее значение счетчика, значения не повторяются в том числе после перезапуска.
+	Next(ctx context.Context) (uint64, error)
 }
 
 // DeleterService - интерфейс сервиса, который помечает URL удаленными.
 type DeleterService interface {
-	Process(ctx context.Context, uid string, shortIDs []string)
+	// Process - поставить ссылки пользователя в очередь удаления и вернуть ID задачи.
+	Process(ctx context.Context, uid string, shortIDs []string) (string, error)
+	// Job - состояние задачи удаления пользователя, если ее нет или она чужая, то вернуть ErrNotFound.
+	Job(ctx context.Context, uid domain.UID, id string) (*domain.DeletionJob, error)
 	Run() error
 	Shutdown() error
 }
 
 // DeleterRepository - репозиторий, который выполняет удаление записей в БД.
 type DeleterRepository interface {
+	// Delete - удалить ссылки батча у пользователей. При успехе у каждой записи заполняется Status:
+	// domain.DeleteStatusDeleted, domain.DeleteStatusNotOwned, если ссылка есть, но пользователь ей не владеет,
+	// или domain.DeleteStatusNotFound, если ссылки нет или пользователь ее уже удалил.
 	Delete(ctx context.Context, batch []*domain.DeleteData) error
+
+	// Restore - отменить удаление ссылок пользователем, удаленных не раньше since.
+	// Нулевой since снимает ограничение. Если у пользователя уже есть другая действующая
+	// запись URL, то ссылка не восстанавливается со статусом domain.RestoreStatusConflict.
+	Restore(ctx context.Context, uid domain.UID, shortIDs []domain.ShortID, since time.Time) ([]domain.RestoreResult, error)
+
+	// PurgeDeleted - окончательно удалить записи, которые удалили все владельцы раньше before,
+	// и владельцев, удаливших ссылку у себя раньше before.
+	PurgeDeleted(ctx context.Context, before time.Time) (*domain.PurgeResult, error)
+}
+
+// DeadLetterRepository - хранилище ссылок, удаление которых не удалось записать.
+type DeadLetterRepository interface {
+	Add(ctx context.Context, letters []domain.DeadLetter) error
+}
+
+// TrashService - сервис восстановления удаленных ссылок в течение срока хранения
+// и периодической очистки удаленных ссылок после него.
+type TrashService interface {
+	Restore(ctx context.Context, uid domain.UID, shortIDs []domain.ShortID) ([]domain.RestoreResult, error)
+	Run() error
+	Shutdown() error
+}
+
+// ClickRecorder - интерфейс сервиса, который асинхронно сохраняет переходы по ссылкам.
+type ClickRecorder interface {
+	Record(ctx context.Context, click *domain.Click)
+	Run() error
+	Shutdown() error
+}
+
+// ClickStatsService - интерфейс сервиса статистики переходов.
+type ClickStatsService interface {
+	// LinkStats - статистика переходов по ссылке пользователя.
+	// Если ссылка не принадлежит пользователю, то вернуть ErrNotFound.
+	LinkStats(ctx context.Context, uid domain.UID, shortID domain.ShortID) (*domain.LinkStats, error)
+}
+
+// ClickRepository - репозиторий переходов по ссылкам.
+type ClickRepository interface {
+	// SaveClicks - сохранить батч переходов.
+	SaveClicks(ctx context.Context, batch []*domain.Click) error
+	// ClickStats - статистика переходов по ссылке пользователя.
+	// Если ссылка не принадлежит пользователю, то вернуть ErrNotFound.
+	ClickStats(ctx context.Context, uid domain.UID, shortID domain.ShortID) (*domain.LinkStats, error)
+}
+
+// HealthChecker - проверка готовности одной зависимости сервиса.
+type HealthChecker interface {
+	// Name - имя проверки в отчете.
+	Name() string
+
+	// Check - выполнить проверку, nil - зависимость готова.
+	Check(ctx context.Context) error
+}
+
+// HealthService - сервис проверки готовности.
+type HealthService interface {
+	// Ready - выполнить все проверки и собрать отчет.
+	Ready(ctx context.Context) *domain.HealthReport
+
+	// Shutdown - пометить сервис неготовым на время остановки.
+	Shutdown() error
+}
+
+// APIKeyRepository - хранилище API ключей.
+type APIKeyRepository interface {
+	// Add - сохранить ключ, если ключ с таким ID уже есть, то вернуть ErrAlreadyExists.
+	Add(ctx context.Context, key *domain.APIKey) error
+	// Get - получить ключ по ID, если его нет, то вернуть ErrNotFound.
+	Get(ctx context.Context, id string) (*domain.APIKey, error)
+	// UserKeys - все ключи пользователя, в том числе отозванные.
+	UserKeys(ctx context.Context, uid domain.UID) ([]domain.APIKey, error)
+	// Revoke - отозвать ключ пользователя, если у пользователя нет такого действующего ключа, то вернуть ErrNotFound.
+	Revoke(ctx context.Context, uid domain.UID, id string, at time.Time) error
+}
+
+// APIKeyService - выпуск и проверка API ключей.
+type APIKeyService interface {
+	// Issue - выпустить ключ, возвращается сам ключ и токен, который показывается только один раз.
+	Issue(ctx context.Context, uid domain.UID, name string, scopes []domain.Scope) (*domain.APIKey, string, error)
+	// Authenticate - найти действующий ключ по токену, иначе вернуть ErrInvalidAPIKey.
+	Authenticate(ctx context.Context, token string) (*domain.APIKey, error)
+	// UserKeys - все ключи пользователя.
+	UserKeys(ctx context.Context, uid domain.UID) ([]domain.APIKey, error)
+	// Revoke - отозвать ключ пользователя.
+	Revoke(ctx context.Context, uid domain.UID, id string) error
+}
+
+// DomainPolicy - правила, на какие домены можно сокращать ссылки.
+type DomainPolicy interface {
+	// Check - проверить хост, если он запрещен, то вернуть ErrForbiddenDomain.
+	Check(host string) error
+}
+
+// RateLimitStore - хранилище корзин токенов для ограничения частоты запросов.
+type RateLimitStore interface {
+	// Take - забрать токен из корзины key. Если токенов нет, то вернуть false и время до появления токена.
+	Take(ctx context.Context, key string, limit domain.RateLimit, now time.Time) (bool, time.Duration, error)
 }
 
 // DBCheck - интерфейс проверки коннекта к БД.
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+const (
+	minAliasLength = 4
+	maxAliasLength = 32
+)
+
+// reservedAliases - алиасы, совпадающие с маршрутами сервиса.
+var reservedAliases = map[string]struct{}{
+	"api":     {},
+	"ping":    {},
+	"metrics": {},
+	"healthz": {},
+	"readyz":  {},
+}
+
+// ValidateAlias - проверить пользовательский алиас: длину, допустимые символы и зарезервированные слова.
+func ValidateAlias(alias domain.ShortID) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return fmt.Errorf("alias length must be from %d to %d: %w", minAliasLength, maxAliasLength, ports.ErrInvalidAlias)
+	}
+	for _, c := range alias {
+		if !isAliasChar(c) {
+			return fmt.Errorf("alias contains forbidden char %q: %w", c, ports.ErrInvalidAlias)
+		}
+	}
+	if _, reserved := reservedAliases[strings.ToLower(string(alias))]; reserved {
+		return fmt.Errorf("alias %q is reserved: %w", alias, ports.ErrInvalidAlias)
+	}
+	return nil
+}
+
+func isAliasChar(c rune) bool {
+	return c >= 'a' && c <= 'z' ||
+		c >= 'A' && c <= 'Z' ||
+		c >= '0' && c <= '9' ||
+		c == '-' || c == '_'
+}
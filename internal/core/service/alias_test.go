@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		alias domain.ShortID
+		valid bool
+	}{
+		{alias: "my-link_2024", valid: true},
+		{alias: "abcd", valid: true},
+		{alias: "abc", valid: false},
+		{alias: "abcdefghijklmnopqrstuvwxyz0123456", valid: false},
+		{alias: "with space", valid: false},
+		{alias: "slash/inside", valid: false},
+		{alias: "ping", valid: false},
+		{alias: "PING", valid: false},
+	}
+	for _, test := range tests {
+		err := ValidateAlias(test.alias)
+		if test.valid {
+			require.NoError(t, err, test.alias)
+		} else {
+			require.ErrorIs(t, err, ports.ErrInvalidAlias, test.alias)
+		}
+	}
+}
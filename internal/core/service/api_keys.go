@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+const (
+	apiKeyIDSize     = 8
+	apiKeySecretSize = 32
+)
+
+// APIKeyService - выпуск, проверка и отзыв API ключей.
+type APIKeyService struct {
+	repo ports.APIKeyRepository
+}
+
+var _ ports.APIKeyService = (*APIKeyService)(nil)
+
+// NewAPIKeyService - новый сервис.
+func NewAPIKeyService(repo ports.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		repo: repo,
+	}
+}
+
+// Issue - выпустить ключ. Токен возвращается только здесь, в хранилище попадает его хеш.
+func (s *APIKeyService) Issue(ctx context.Context, uid domain.UID, name string, scopes []domain.Scope) (*domain.APIKey, string, error) {
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", fmt.Errorf("api key service, issue: %w", err)
+	}
+	id, err := randomHex(apiKeyIDSize)
+	if err != nil {
+		return nil, "", fmt.Errorf("api key service, issue: %w", err)
+	}
+	secret := make([]byte, apiKeySecretSize)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return nil, "", fmt.Errorf("api key service, issue: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	key := &domain.APIKey{
+		ID:        id,
+		UID:       uid,
+		Name:      name,
+		Hash:      hashSecret(encoded),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	err = s.repo.Add(ctx, key)
+	if err != nil {
+		return nil, "", fmt.Errorf("api key service, issue: %w", err)
+	}
+	return key, domain.APIKeyPrefix + id + "." + encoded, nil
+}
+
+// Authenticate - найти действующий ключ по токену.
+func (s *APIKeyService) Authenticate(ctx context.Context, token string) (*domain.APIKey, error) {
+	rest, ok := strings.CutPrefix(token, domain.APIKeyPrefix)
+	if !ok {
+		return nil, ports.ErrInvalidAPIKey
+	}
+	id, secret, ok := strings.Cut(rest, ".")
+	if !ok || id == "" || secret == "" {
+		return nil, ports.ErrInvalidAPIKey
+	}
+	key, err := s.repo.Get(ctx, id)
+	if errors.Is(err, ports.ErrNotFound) {
+		return nil, ports.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("api key service, authenticate: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.Hash)) != 1 {
+		return nil, ports.ErrInvalidAPIKey
+	}
+	if key.RevokedAt != nil {
+		return nil, ports.ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+// UserKeys - все ключи пользователя.
+func (s *APIKeyService) UserKeys(ctx context.Context, uid domain.UID) ([]domain.APIKey, error) {
+	keys, err := s.repo.UserKeys(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("api key service, user keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke - отозвать ключ пользователя.
+func (s *APIKeyService) Revoke(ctx context.Context, uid domain.UID, id string) error {
+	err := s.repo.Revoke(ctx, uid, id, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("api key service, revoke: %w", err)
+	}
+	return nil
+}
+
+// normalizeScopes - проверить права и убрать повторы.
+func normalizeScopes(scopes []domain.Scope) ([]domain.Scope, error) {
+	if len(scopes) == 0 {
+		return nil, ports.ErrInvalidScope
+	}
+	result := make([]domain.Scope, 0, len(scopes))
+	seen := make(map[domain.Scope]struct{}, len(scopes))
+	for _, scope := range scopes {
+		switch scope {
+		case domain.ScopeRead, domain.ScopeShorten, domain.ScopeDelete:
+		default:
+			return nil, fmt.Errorf("%w: %s", ports.ErrInvalidScope, scope)
+		}
+		if _, ok := seen[scope]; ok {
+			continue
+		}
+		seen[scope] = struct{}{}
+		result = append(result, scope)
+	}
+	return result, nil
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	repo := inmemory.NewAPIKeyRepository()
+	keys := NewAPIKeyService(repo)
+
+	_, _, err := keys.Issue(ctx, "user", "empty", nil)
+	require.ErrorIs(t, err, ports.ErrInvalidScope)
+	_, _, err = keys.Issue(ctx, "user", "admin", []domain.Scope{"admin"})
+	require.ErrorIs(t, err, ports.ErrInvalidScope)
+
+	key, token, err := keys.Issue(ctx, "user", "ci", []domain.Scope{domain.ScopeRead, domain.ScopeRead, domain.ScopeShorten})
+	require.NoError(t, err)
+	require.Equal(t, []domain.Scope{domain.ScopeRead, domain.ScopeShorten}, key.Scopes)
+	stored, err := repo.Get(ctx, key.ID)
+	require.NoError(t, err)
+	require.NotContains(t, token, stored.Hash)
+
+	found, err := keys.Authenticate(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, domain.UID("user"), found.UID)
+	require.True(t, found.HasScope(domain.ScopeShorten))
+	require.False(t, found.HasScope(domain.ScopeDelete))
+
+	for _, bad := range []string{"", "mu_", "mu_" + key.ID, "mu_" + key.ID + ".wrong", token[3:]} {
+		_, err = keys.Authenticate(ctx, bad)
+		require.ErrorIs(t, err, ports.ErrInvalidAPIKey, bad)
+	}
+
+	require.ErrorIs(t, keys.Revoke(ctx, "other", key.ID), ports.ErrNotFound)
+	require.NoError(t, keys.Revoke(ctx, "user", key.ID))
+	_, err = keys.Authenticate(ctx, token)
+	require.ErrorIs(t, err, ports.ErrInvalidAPIKey)
+	require.ErrorIs(t, keys.Revoke(ctx, "user", key.ID), ports.ErrNotFound)
+
+	list, err := keys.UserKeys(ctx, "user")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.NotNil(t, list[0].RevokedAt)
+}
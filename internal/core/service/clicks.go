@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// ClickService - сервис, который пишет переходы по ссылкам батчами и отдает статистику.
+type ClickService struct {
+	repo      ports.ClickRepository
+	logger    ports.Logger
+	batchSize int
+	clicks    chan *domain.Click
+	stopped   chan struct{}
+	mutex     sync.RWMutex
+	closed    bool
+}
+
+// NewClickService - новый сервис. queueSize - размер очереди переходов,
+// при ее переполнении новые переходы отбрасываются, чтобы не задерживать редирект.
+func NewClickService(repo ports.ClickRepository, logger ports.Logger, batchSize int, queueSize int) *ClickService {
+	return &ClickService{
+		repo:      repo,
+		logger:    logger,
+		batchSize: batchSize,
+		clicks:    make(chan *domain.Click, queueSize),
+		stopped:   make(chan struct{}),
+	}
+}
+
+var _ ports.ClickRecorder = (*ClickService)(nil)
+var _ ports.ClickStatsService = (*ClickService)(nil)
+
+// Run - запуск сервиса.
+func (cs *ClickService) Run() error {
+	go cs.dbWriter()
+	return nil
+}
+
+// Record - добавить переход в очередь на запись.
+func (cs *ClickService) Record(_ context.Context, click *domain.Click) {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+	if cs.closed {
+		return
+	}
+	select {
+	case cs.clicks <- click:
+	default:
+		cs.logger.Warnln("click queue is full, click dropped", "short_id", click.ShortID)
+	}
+}
+
+// LinkStats - статистика переходов по ссылке пользователя.
+func (cs *ClickService) LinkStats(ctx context.Context, uid domain.UID, shortID domain.ShortID) (*domain.LinkStats, error) {
+	stats, err := cs.repo.ClickStats(ctx, uid, shortID)
+	if err != nil {
+		return nil, fmt.Errorf("click service, link stats: %w", err)
+	}
+	return stats, nil
+}
+
+// Shutdown - дожидаемся записи переходов из очереди.
+func (cs *ClickService) Shutdown() error {
+	cs.mutex.Lock()
+	if !cs.closed {
+		cs.closed = true
+		close(cs.clicks)
+	}
+	cs.mutex.Unlock()
+	<-cs.stopped
+	return nil
+}
+
+func (cs *ClickService) dbWriter() {
+	defer close(cs.stopped)
+	batch := make([]*domain.Click, 0, cs.batchSize)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := cs.repo.SaveClicks(context.Background(), batch)
+		if err != nil {
+			cs.logger.Errorln("write clicks batch err: ", err, "dropped", len(batch))
+		}
+		for i := range batch {
+			batch[i] = nil
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case click, ok := <-cs.clicks:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, click)
+			if len(batch) == cs.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
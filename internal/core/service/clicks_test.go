@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClickService(t *testing.T) {
+	ctx := context.Background()
+	repo := inmemory.NewShortenerRepository()
+	_, err := repo.Add(ctx, "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	clicks := NewClickService(repo, zap.NewNop().Sugar(), 10, 10)
+	require.NoError(t, clicks.Run())
+
+	clickedAt := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	clicks.Record(ctx, &domain.Click{ShortID: "first", ClickedAt: clickedAt, IP: "1.1.1.1"})
+	clicks.Record(ctx, &domain.Click{ShortID: "first", ClickedAt: clickedAt.Add(2 * time.Hour), IP: "1.1.1.1"})
+	clicks.Record(ctx, &domain.Click{ShortID: "unknown", ClickedAt: clickedAt, IP: "2.2.2.2"})
+	require.NoError(t, clicks.Shutdown())
+	clicks.Record(ctx, &domain.Click{ShortID: "first", ClickedAt: clickedAt, IP: "3.3.3.3"})
+
+	stats, err := clicks.LinkStats(ctx, "alice", "first")
+	require.NoError(t, err)
+	require.Equal(t, &domain.LinkStats{
+		TotalClicks:    2,
+		UniqueVisitors: 1,
+		Daily: []domain.DailyClicks{
+			{Date: "2024-05-01", Clicks: 1},
+			{Date: "2024-05-02", Clicks: 1},
+		},
+	}, stats)
+	_, err = clicks.LinkStats(ctx, "bob", "first")
+	require.ErrorIs(t, err, ports.ErrNotFound)
+}
+
+func TestClickServiceDropsWhenQueueIsFull(t *testing.T) {
+	ctx := context.Background()
+	repo := inmemory.NewShortenerRepository()
+	_, err := repo.Add(ctx, "first", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	clicks := NewClickService(repo, zap.NewNop().Sugar(), 10, 1)
+	clicks.Record(ctx, &domain.Click{ShortID: "first", ClickedAt: time.Now()})
+	clicks.Record(ctx, &domain.Click{ShortID: "first", ClickedAt: time.Now()})
+	require.NoError(t, clicks.Run())
+	require.NoError(t, clicks.Shutdown())
+
+	stats, err := clicks.LinkStats(ctx, "alice", "first")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), stats.TotalClicks)
+}
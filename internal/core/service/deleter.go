@@ -4,33 +4,90 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/Svirex/microurl/internal/core/ports"
 )
 
+const (
+	// deletionJobIDSize - размер ID задачи удаления в байтах.
+	deletionJobIDSize = 8
+	// deletionJobRetention - сколько хранится состояние завершенной задачи удаления.
+	deletionJobRetention = 24 * time.Hour
+	// maxDeleteBackoff - предел паузы между попытками записать батч.
+	maxDeleteBackoff = time.Minute
+)
+
 // DeleterService - структура сервиса удаления ссылок.
 type DeleterService struct {
-	errorChan   chan error              // 16
-	repo        ports.DeleterRepository // 16
-	wg          sync.WaitGroup          // 8 + 4
-	batchSize   int                     // 8
-	logger      ports.Logger            // 8
-	mayShutdown chan struct{}           // 8
-	fanInChan   chan *domain.DeleteData // 8
+	errorChan   chan error                 // 16
+	repo        ports.DeleterRepository    // 16
+	deadLetters ports.DeadLetterRepository // 16
+	wg          sync.WaitGroup             // 8 + 4
+	batchSize   int                        // 8
+	maxAttempts int                        // 8
+	backoff     time.Duration              // 8
+	logger      ports.Logger               // 8
+	mayShutdown chan struct{}              // 8
+	fanInChan   chan *domain.DeleteData    // 8
 
+	jobs      map[string]*deletionJob
+	jobsMutex sync.Mutex
+
+	pending       atomic.Int64
+	failedBatches atomic.Int64
+	deadLettered  atomic.Int64
+	alive         atomic.Bool
+}
+
+// deletionJob - задача удаления, индексы ее ссылок и число ссылок, которые еще ожидают удаления.
+type deletionJob struct {
+	job       domain.DeletionJob
+	index     map[domain.ShortID]int
+	remaining int
+	failed    bool
+}
+
+// DeleterOption - дополнительный параметр сервиса удаления.
+type DeleterOption func(*DeleterService)
+
+// WithRetries - число попыток записать батч и пауза после первой неудачи, которая удваивается
+// с каждой попыткой, но не больше минуты. По умолчанию 5 попыток и 1 секунда.
+func WithRetries(maxAttempts int, backoff time.Duration) DeleterOption {
+	return func(ds *DeleterService) {
+		ds.maxAttempts = maxAttempts
+		ds.backoff = backoff
+	}
+}
+
+// WithDeadLetters - хранилище ссылок, батч которых не удалось записать за все попытки.
+// Без него такие ссылки только пишутся в лог.
+func WithDeadLetters(deadLetters ports.DeadLetterRepository) DeleterOption {
+	return func(ds *DeleterService) {
+		ds.deadLetters = deadLetters
+	}
 }
 
 // NewDeleter - новый сервис.
-func NewDeleter(repo ports.DeleterRepository, logger ports.Logger, batchSize int) (*DeleterService, error) {
+func NewDeleter(repo ports.DeleterRepository, logger ports.Logger, batchSize int, opts ...DeleterOption) (*DeleterService, error) {
 	service := &DeleterService{
 		repo:        repo,
 		logger:      logger,
 		batchSize:   batchSize,
+		maxAttempts: 5,
+		backoff:     time.Second,
 		errorChan:   make(chan error, batchSize),
 		fanInChan:   make(chan *domain.DeleteData, batchSize),
 		mayShutdown: make(chan struct{}),
+		jobs:        make(map[string]*deletionJob),
+	}
+	for _, opt := range opts {
+		opt(service)
+	}
+	if service.maxAttempts < 1 {
+		service.maxAttempts = 1
 	}
 	return service, nil
 }
@@ -42,16 +99,67 @@ func (ds *DeleterService) Run() error {
 	// запустить горутину, которая пишет в базу
 	// запустить горутину, которая логирует ошибки
 	//
+	ds.alive.Store(true)
 	go ds.dbWriter()
 	go ds.errorLogger()
 
 	return nil
 }
 
-// Process - добавить запись в обработку.
-func (ds *DeleterService) Process(_ context.Context, uid string, shortIDs []string) {
+// Process - добавить записи в обработку и вернуть ID задачи. Повторы идентификаторов отбрасываются.
+func (ds *DeleterService) Process(_ context.Context, uid string, shortIDs []string) (string, error) {
+	unique := make([]string, 0, len(shortIDs))
+	seen := make(map[string]struct{}, len(shortIDs))
+	for _, shortID := range shortIDs {
+		if _, ok := seen[shortID]; ok {
+			continue
+		}
+		seen[shortID] = struct{}{}
+		unique = append(unique, shortID)
+	}
+	id, err := randomHex(deletionJobIDSize)
+	if err != nil {
+		return "", fmt.Errorf("deleter service, process: %w", err)
+	}
+	ds.addJob(id, uid, unique)
+	ds.pending.Add(int64(len(unique)))
 	ds.wg.Add(1)
-	go ds.generator(uid, shortIDs)
+	go ds.generator(id, uid, unique)
+	return id, nil
+}
+
+// Job - состояние задачи удаления пользователя.
+func (ds *DeleterService) Job(_ context.Context, uid domain.UID, id string) (*domain.DeletionJob, error) {
+	ds.jobsMutex.Lock()
+	defer ds.jobsMutex.Unlock()
+	j, ok := ds.jobs[id]
+	if !ok || j.job.UID != uid {
+		return nil, fmt.Errorf("deleter service, job: %w", ports.ErrNotFound)
+	}
+	job := j.job
+	job.Items = make([]domain.DeletionItem, len(j.job.Items))
+	copy(job.Items, j.job.Items)
+	return &job, nil
+}
+
+// Alive - горутина записи удалений работает.
+func (ds *DeleterService) Alive() bool {
+	return ds.alive.Load()
+}
+
+// QueueDepth - число записей, которые ожидают удаления.
+func (ds *DeleterService) QueueDepth() int64 {
+	return ds.pending.Load()
+}
+
+// FailedBatches - число неудачных попыток записать батч.
+func (ds *DeleterService) FailedBatches() int64 {
+	return ds.failedBatches.Load()
+}
+
+// DeadLetters - число ссылок, которые не удалось удалить за все попытки.
+func (ds *DeleterService) DeadLetters() int64 {
+	return ds.deadLettered.Load()
 }
 
 // Shutdown - дожидаемся завершения обработки записей в очереди.
@@ -62,11 +170,81 @@ func (ds *DeleterService) Shutdown() error {
 	return nil
 }
 
-func (ds *DeleterService) generator(uid string, shortIDs []string) {
+// addJob - зарегистрировать задачу и убрать завершенные задачи старше срока хранения.
+func (ds *DeleterService) addJob(id string, uid string, shortIDs []string) {
+	now := time.Now().UTC()
+	j := &deletionJob{
+		job: domain.DeletionJob{
+			ID:        id,
+			UID:       domain.UID(uid),
+			Status:    domain.DeletionJobPending,
+			Items:     make([]domain.DeletionItem, 0, len(shortIDs)),
+			CreatedAt: now,
+		},
+		index:     make(map[domain.ShortID]int, len(shortIDs)),
+		remaining: len(shortIDs),
+	}
+	for i, shortID := range shortIDs {
+		j.job.Items = append(j.job.Items, domain.DeletionItem{
+			ShortID: domain.ShortID(shortID),
+			Status:  domain.DeleteStatusPending,
+		})
+		j.index[domain.ShortID(shortID)] = i
+	}
+	if j.remaining == 0 {
+		j.finish(now)
+	}
+	ds.jobsMutex.Lock()
+	defer ds.jobsMutex.Unlock()
+	for jobID, old := range ds.jobs {
+		if old.job.FinishedAt != nil && now.Sub(*old.job.FinishedAt) > deletionJobRetention {
+			delete(ds.jobs, jobID)
+		}
+	}
+	ds.jobs[id] = j
+}
+
+// resolve - перенести результаты записей батча в их задачи.
+func (ds *DeleterService) resolve(batch []*domain.DeleteData) {
+	now := time.Now().UTC()
+	ds.jobsMutex.Lock()
+	defer ds.jobsMutex.Unlock()
+	for _, v := range batch {
+		j, ok := ds.jobs[v.JobID]
+		if !ok {
+			continue
+		}
+		i, ok := j.index[domain.ShortID(v.ShortID)]
+		if !ok || j.job.Items[i].Status != domain.DeleteStatusPending {
+			continue
+		}
+		status := v.Status
+		if status == "" {
+			status = domain.DeleteStatusDeleted
+		}
+		j.job.Items[i].Status = status
+		j.failed = j.failed || status == domain.DeleteStatusFailed
+		j.remaining--
+		if j.remaining == 0 {
+			j.finish(now)
+		}
+	}
+}
+
+func (j *deletionJob) finish(now time.Time) {
+	j.job.Status = domain.DeletionJobDone
+	if j.failed {
+		j.job.Status = domain.DeletionJobFailed
+	}
+	j.job.FinishedAt = &now
+}
+
+func (ds *DeleterService) generator(jobID string, uid string, shortIDs []string) {
 	for _, v := range shortIDs {
 		ds.fanInChan <- &domain.DeleteData{
 			UID:     uid,
 			ShortID: v,
+			JobID:   jobID,
 		}
 	}
 	defer ds.wg.Done()
@@ -80,6 +258,7 @@ func (ds *DeleterService) errorLogger() {
 }
 
 func (ds *DeleterService) dbWriter() {
+	defer ds.alive.Store(false)
 	batch := make([]*domain.DeleteData, 0, ds.batchSize)
 	ticker := time.NewTicker(time.Second)
 
@@ -94,15 +273,20 @@ func (ds *DeleterService) dbWriter() {
 		if len(batch) == 0 {
 			return
 		}
-		for {
-			err := ds.writeBatch(batch)
-			if err != nil {
-				ds.errorChan <- fmt.Errorf("couldnt write batch: %w", err)
-				time.Sleep(5 * time.Second)
-			} else {
+		backoff := ds.backoff
+		var err error
+		for attempt := 1; attempt <= ds.maxAttempts; attempt++ {
+			err = ds.writeBatch(batch)
+			if err == nil {
+				ds.resolve(batch)
 				return
 			}
+			if attempt < ds.maxAttempts {
+				time.Sleep(backoff)
+				backoff = min(2*backoff, maxDeleteBackoff)
+			}
 		}
+		ds.deadLetter(batch, err)
 	}
 
 	for {
@@ -128,8 +312,35 @@ func (ds *DeleterService) dbWriter() {
 func (ds *DeleterService) writeBatch(batch []*domain.DeleteData) error {
 	err := ds.repo.Delete(context.Background(), batch)
 	if err != nil {
-		ds.errorChan <- err
+		ds.failedBatches.Add(1)
+		ds.errorChan <- fmt.Errorf("couldnt write batch: %w", err)
 		return err
 	}
+	ds.pending.Add(-int64(len(batch)))
 	return nil
 }
+
+// deadLetter - сохранить ссылки батча, который не удалось записать за все попытки, и завершить их с ошибкой.
+func (ds *DeleterService) deadLetter(batch []*domain.DeleteData, err error) {
+	now := time.Now().UTC()
+	letters := make([]domain.DeadLetter, 0, len(batch))
+	for _, v := range batch {
+		v.Status = domain.DeleteStatusFailed
+		letters = append(letters, domain.DeadLetter{
+			JobID:    v.JobID,
+			UID:      domain.UID(v.UID),
+			ShortID:  domain.ShortID(v.ShortID),
+			Error:    err.Error(),
+			Attempts: ds.maxAttempts,
+			FailedAt: now,
+		})
+	}
+	ds.pending.Add(-int64(len(batch)))
+	ds.deadLettered.Add(int64(len(batch)))
+	if ds.deadLetters == nil {
+		ds.errorChan <- fmt.Errorf("batch of %d urls dropped after %d attempts: %w", len(batch), ds.maxAttempts, err)
+	} else if saveErr := ds.deadLetters.Add(context.Background(), letters); saveErr != nil {
+		ds.errorChan <- fmt.Errorf("save %d dead letters: %w", len(letters), saveErr)
+	}
+	ds.resolve(batch)
+}
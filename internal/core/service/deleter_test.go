@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type failingDeleter struct{}
+
+func (failingDeleter) Delete(context.Context, []*domain.DeleteData) error {
+	return errors.New("db is down")
+}
+
+func (failingDeleter) Restore(context.Context, domain.UID, []domain.ShortID, time.Time) ([]domain.RestoreResult, error) {
+	return nil, errors.New("db is down")
+}
+
+func (failingDeleter) PurgeDeleted(context.Context, time.Time) (*domain.PurgeResult, error) {
+	return nil, errors.New("db is down")
+}
+
+func TestDeleterJob(t *testing.T) {
+	ctx := context.Background()
+	repo := inmemory.NewShortenerRepository()
+	_, err := repo.Add(ctx, "mine", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	_, err = repo.Add(ctx, "theirs", &domain.Record{UID: "bob", URL: "http://ya.ru"})
+	require.NoError(t, err)
+	deleter, err := NewDeleter(inmemory.NewDeleterRepository(repo), zap.NewNop().Sugar(), 10)
+	require.NoError(t, err)
+	require.NoError(t, deleter.Run())
+
+	id, err := deleter.Process(ctx, "alice", []string{"mine", "theirs", "mine", "missing"})
+	require.NoError(t, err)
+	job, err := deleter.Job(ctx, "alice", id)
+	require.NoError(t, err)
+	require.Equal(t, domain.DeletionJobPending, job.Status)
+	require.Len(t, job.Items, 3)
+	_, err = deleter.Job(ctx, "bob", id)
+	require.ErrorIs(t, err, ports.ErrNotFound)
+
+	require.NoError(t, deleter.Shutdown())
+	job, err = deleter.Job(ctx, "alice", id)
+	require.NoError(t, err)
+	require.Equal(t, domain.DeletionJobDone, job.Status)
+	require.NotNil(t, job.FinishedAt)
+	require.Equal(t, []domain.DeletionItem{
+		{ShortID: "mine", Status: domain.DeleteStatusDeleted},
+		{ShortID: "theirs", Status: domain.DeleteStatusNotOwned},
+		{ShortID: "missing", Status: domain.DeleteStatusNotFound},
+	}, job.Items)
+	_, err = repo.Get(ctx, "mine")
+	require.ErrorIs(t, err, ports.ErrDeleted)
+}
+
+func TestDeleterDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	deadLetters := inmemory.NewDeadLetterRepository()
+	deleter, err := NewDeleter(failingDeleter{}, zap.NewNop().Sugar(), 10,
+		WithRetries(2, time.Millisecond),
+		WithDeadLetters(deadLetters),
+	)
+	require.NoError(t, err)
+	require.NoError(t, deleter.Run())
+	id, err := deleter.Process(ctx, "alice", []string{"mine"})
+	require.NoError(t, err)
+	require.NoError(t, deleter.Shutdown())
+
+	job, err := deleter.Job(ctx, "alice", id)
+	require.NoError(t, err)
+	require.Equal(t, domain.DeletionJobFailed, job.Status)
+	require.Equal(t, domain.DeleteStatusFailed, job.Items[0].Status)
+	letters := deadLetters.Letters()
+	require.Len(t, letters, 1)
+	require.Equal(t, id, letters[0].JobID)
+	require.Equal(t, domain.ShortID("mine"), letters[0].ShortID)
+	require.Equal(t, 2, letters[0].Attempts)
+	require.Equal(t, int64(1), deleter.DeadLetters())
+	require.Equal(t, int64(2), deleter.FailedBatches())
+	require.Zero(t, deleter.QueueDepth())
+}
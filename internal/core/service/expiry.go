@@ -0,0 +1,31 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// resolveExpiry - вычислить момент истечения срока действия ссылки
+// по абсолютному времени или по TTL в секундах. Указывать можно только одно из двух.
+func resolveExpiry(expiresAt *time.Time, ttl int64, now time.Time) (*time.Time, error) {
+	if expiresAt != nil && ttl != 0 {
+		return nil, fmt.Errorf("both expires_at and ttl set: %w", ports.ErrInvalidExpiry)
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("negative ttl %d: %w", ttl, ports.ErrInvalidExpiry)
+	}
+	if ttl > 0 {
+		t := now.Add(time.Duration(ttl) * time.Second).UTC()
+		return &t, nil
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return nil, fmt.Errorf("expires_at %s in the past: %w", expiresAt, ports.ErrInvalidExpiry)
+		}
+		t := expiresAt.UTC()
+		return &t, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveExpiry(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	expiresAt, err := resolveExpiry(nil, 0, now)
+	require.NoError(t, err)
+	require.Nil(t, expiresAt)
+
+	expiresAt, err = resolveExpiry(nil, 60, now)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(time.Minute), *expiresAt)
+
+	expiresAt, err = resolveExpiry(&future, 0, now)
+	require.NoError(t, err)
+	require.Equal(t, future, *expiresAt)
+
+	_, err = resolveExpiry(&future, 60, now)
+	require.ErrorIs(t, err, ports.ErrInvalidExpiry)
+
+	_, err = resolveExpiry(nil, -1, now)
+	require.ErrorIs(t, err, ports.ErrInvalidExpiry)
+
+	_, err = resolveExpiry(&past, 0, now)
+	require.ErrorIs(t, err, ports.ErrInvalidExpiry)
+}
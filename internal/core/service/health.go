@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// HealthService - сервис, который собирает результаты проверок готовности.
+type HealthService struct {
+	checks       []ports.HealthChecker
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewHealthService - новый сервис, timeout - ограничение времени на все проверки.
+func NewHealthService(timeout time.Duration, checks ...ports.HealthChecker) *HealthService {
+	return &HealthService{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+var _ ports.HealthService = (*HealthService)(nil)
+
+// Ready - выполнить проверки параллельно. Во время остановки сервис всегда не готов.
+func (hs *HealthService) Ready(ctx context.Context) *domain.HealthReport {
+	if hs.shuttingDown.Load() {
+		return &domain.HealthReport{
+			Status: domain.HealthStatusShuttingDown,
+			Checks: make([]domain.CheckResult, 0),
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, hs.timeout)
+	defer cancel()
+	report := &domain.HealthReport{
+		Status: domain.HealthStatusOK,
+		Checks: make([]domain.CheckResult, len(hs.checks)),
+	}
+	var wg sync.WaitGroup
+	for i, check := range hs.checks {
+		wg.Add(1)
+		go func(i int, check ports.HealthChecker) {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Check(ctx)
+			result := domain.CheckResult{
+				Name:     check.Name(),
+				Status:   domain.HealthStatusOK,
+				Duration: time.Since(start).String(),
+			}
+			if err != nil {
+				result.Status = domain.HealthStatusFail
+				result.Error = err.Error()
+			}
+			report.Checks[i] = result
+		}(i, check)
+	}
+	wg.Wait()
+	for i := range report.Checks {
+		if report.Checks[i].Status != domain.HealthStatusOK {
+			report.Status = domain.HealthStatusFail
+		}
+	}
+	return report
+}
+
+// Shutdown - с этого момента сервис не готов принимать запросы.
+func (hs *HealthService) Shutdown() error {
+	hs.shuttingDown.Store(true)
+	return nil
+}
+
+// dbHealthCheck - проверка соединения с БД.
+type dbHealthCheck struct {
+	ping ports.DBCheck
+}
+
+// NewDBHealthCheck - проверка доступности БД.
+func NewDBHealthCheck(ping ports.DBCheck) ports.HealthChecker {
+	return &dbHealthCheck{ping: ping}
+}
+
+// Name - имя проверки.
+func (c *dbHealthCheck) Name() string {
+	return "database"
+}
+
+// Check - пингуем БД.
+func (c *dbHealthCheck) Check(ctx context.Context) error {
+	return c.ping.Ping(ctx)
+}
+
+// deleterHealthCheck - проверка сервиса удаления.
+type deleterHealthCheck struct {
+	deleter    *DeleterService
+	maxBacklog int64
+}
+
+// NewDeleterHealthCheck - проверка, что горутина записи удалений работает и очередь не больше maxBacklog.
+func NewDeleterHealthCheck(deleter *DeleterService, maxBacklog int64) ports.HealthChecker {
+	return &deleterHealthCheck{
+		deleter:    deleter,
+		maxBacklog: maxBacklog,
+	}
+}
+
+// Name - имя проверки.
+func (c *deleterHealthCheck) Name() string {
+	return "deleter"
+}
+
+// Check - проверяем состояние сервиса удаления.
+func (c *deleterHealthCheck) Check(_ context.Context) error {
+	if !c.deleter.Alive() {
+		return errors.New("deleter writer is not running")
+	}
+	if depth := c.deleter.QueueDepth(); depth > c.maxBacklog {
+		return fmt.Errorf("deleter backlog %d exceeds %d", depth, c.maxBacklog)
+	}
+	return nil
+}
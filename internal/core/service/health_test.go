@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type funcCheck struct {
+	name string
+	err  error
+}
+
+func (c *funcCheck) Name() string                  { return c.name }
+func (c *funcCheck) Check(_ context.Context) error { return c.err }
+
+func TestHealthReady(t *testing.T) {
+	health := NewHealthService(time.Second, &funcCheck{name: "good"}, &funcCheck{name: "bad", err: errors.New("broken")})
+	report := health.Ready(context.Background())
+	require.Equal(t, domain.HealthStatusFail, report.Status)
+	require.Len(t, report.Checks, 2)
+	require.Equal(t, "good", report.Checks[0].Name)
+	require.Equal(t, domain.HealthStatusOK, report.Checks[0].Status)
+	require.Equal(t, domain.HealthStatusFail, report.Checks[1].Status)
+	require.Equal(t, "broken", report.Checks[1].Error)
+
+	health = NewHealthService(time.Second, &funcCheck{name: "good"})
+	require.Equal(t, domain.HealthStatusOK, health.Ready(context.Background()).Status)
+	require.NoError(t, health.Shutdown())
+	require.Equal(t, domain.HealthStatusShuttingDown, health.Ready(context.Background()).Status)
+}
+
+func TestDeleterHealthCheck(t *testing.T) {
+	repo := inmemory.NewShortenerRepository()
+	deleter, err := NewDeleter(inmemory.NewDeleterRepository(repo), zap.NewNop().Sugar(), 10)
+	require.NoError(t, err)
+	check := NewDeleterHealthCheck(deleter, 0)
+	require.Error(t, check.Check(context.Background()))
+
+	require.NoError(t, deleter.Run())
+	require.NoError(t, check.Check(context.Background()))
+
+	require.NoError(t, deleter.Shutdown())
+	require.Eventually(t, func() bool { return check.Check(context.Background()) != nil }, time.Second, 10*time.Millisecond)
+}
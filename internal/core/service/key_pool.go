@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// keyPoolCheckInterval - период проверки заполненности пула помимо пополнения по сигналу.
+const keyPoolCheckInterval = 5 * time.Second
+
+// keyPoolAllocateRounds - сколько раз за одно пополнение добавлять ключи в хранилище,
+// если часть созданных ключей оказалась занята.
+const keyPoolAllocateRounds = 3
+
+// KeyPoolService - пул заранее подготовленных коротких идентификаторов.
+// Ключи берутся из хранилища, а если там их не хватает, то хранилище сначала пополняется ключами
+// генератора source: хранилище отбрасывает ключи, уже занятые записями.
+// Пул пополняется в фоне, когда в памяти остается меньше lowWatermark ключей.
+type KeyPoolService struct {
+	repo         ports.KeyPoolRepository
+	source       ports.StringGenerator
+	logger       ports.Logger
+	size         uint
+	batchSize    int
+	lowWatermark int
+	keys         chan domain.ShortID
+	refill       chan struct{}
+	done         chan struct{}
+	stopped      chan struct{}
+}
+
+// NewKeyPool - новый пул ключей длины size. За одно пополнение в память загружается до batchSize ключей.
+func NewKeyPool(
+	repo ports.KeyPoolRepository,
+	source ports.StringGenerator,
+	logger ports.Logger,
+	size uint,
+	batchSize int,
+	lowWatermark int,
+) *KeyPoolService {
+	return &KeyPoolService{
+		repo:         repo,
+		source:       source,
+		logger:       logger,
+		size:         size,
+		batchSize:    batchSize,
+		lowWatermark: lowWatermark,
+		keys:         make(chan domain.ShortID, batchSize+lowWatermark),
+		refill:       make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+}
+
+var _ ports.KeyPoolService = (*KeyPoolService)(nil)
+
+// Run - запуск сервиса.
+func (kp *KeyPoolService) Run() error {
+	go kp.refiller()
+	return nil
+}
+
+// Generate - выдать ключ из пула. Если пул пуст, то ключ создается генератором source,
+// чтобы не задерживать запрос.
+func (kp *KeyPoolService) Generate(ctx context.Context, size uint) (string, error) {
+	if size != kp.size {
+		return kp.source.Generate(ctx, size)
+	}
+	if len(kp.keys) <= kp.lowWatermark {
+		kp.triggerRefill()
+	}
+	select {
+	case key := <-kp.keys:
+		return string(key), nil
+	default:
+		kp.logger.Warnln("key pool is empty, generating key on demand")
+		return kp.source.Generate(ctx, size)
+	}
+}
+
+// Shutdown - останавливаем пополнение и возвращаем неиспользованные ключи в хранилище.
+func (kp *KeyPoolService) Shutdown() error {
+	close(kp.done)
+	<-kp.stopped
+	unused := make([]domain.ShortID, 0, len(kp.keys))
+drain:
+	for {
+		select {
+		case key := <-kp.keys:
+			unused = append(unused, key)
+		default:
+			break drain
+		}
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), keyPoolCheckInterval)
+	defer cancel()
+	err := kp.repo.PutKeys(ctx, unused)
+	if err != nil {
+		return fmt.Errorf("key pool, shutdown, return unused keys: %w", err)
+	}
+	kp.logger.Infoln("returned unused keys to pool", "count", len(unused))
+	return nil
+}
+
+func (kp *KeyPoolService) triggerRefill() {
+	select {
+	case kp.refill <- struct{}{}:
+	default:
+	}
+}
+
+func (kp *KeyPoolService) refiller() {
+	defer close(kp.stopped)
+	ticker := time.NewTicker(keyPoolCheckInterval)
+	defer ticker.Stop()
+	kp.fill()
+	for {
+		select {
+		case <-kp.done:
+			return
+		case <-kp.refill:
+			kp.fill()
+		case <-ticker.C:
+			if len(kp.keys) <= kp.lowWatermark {
+				kp.fill()
+			}
+		}
+	}
+}
+
+func (kp *KeyPoolService) fill() {
+	need := cap(kp.keys) - len(kp.keys)
+	if need <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), keyPoolCheckInterval)
+	defer cancel()
+	keys, err := kp.repo.TakeKeys(ctx, need)
+	if err != nil {
+		kp.logger.Errorln("key pool, take keys err: ", err)
+		return
+	}
+	for round := 0; round < keyPoolAllocateRounds && len(keys) < need; round++ {
+		err = kp.allocate(ctx, need-len(keys))
+		if err != nil {
+			kp.logger.Errorln("key pool, allocate keys err: ", err)
+			break
+		}
+		allocated, err := kp.repo.TakeKeys(ctx, need-len(keys))
+		if err != nil {
+			kp.logger.Errorln("key pool, take allocated keys err: ", err)
+			break
+		}
+		keys = append(keys, allocated...)
+	}
+	var extra []domain.ShortID
+	for i := range keys {
+		select {
+		case kp.keys <- keys[i]:
+		default:
+			extra = append(extra, keys[i])
+		}
+	}
+	if len(extra) > 0 {
+		err = kp.repo.PutKeys(ctx, extra)
+		if err != nil {
+			kp.logger.Errorln("key pool, put extra keys err: ", err)
+		}
+	}
+}
+
+// allocate - создать n ключей и положить их в хранилище, которое отбросит уже занятые.
+func (kp *KeyPoolService) allocate(ctx context.Context, n int) error {
+	keys := make([]domain.ShortID, 0, n)
+	for len(keys) < n {
+		key, err := kp.source.Generate(ctx, kp.size)
+		if err != nil {
+			return fmt.Errorf("generate key: %w", err)
+		}
+		keys = append(keys, domain.ShortID(key))
+	}
+	err := kp.repo.PutKeys(ctx, keys)
+	if err != nil {
+		return fmt.Errorf("put keys: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// uniqueGenerator - генератор уникальных строк вида key-N
+type uniqueGenerator struct {
+	next atomic.Int64
+}
+
+func (g *uniqueGenerator) Generate(_ context.Context, _ uint) (string, error) {
+	return fmt.Sprintf("key-%d", g.next.Add(1)), nil
+}
+
+func TestKeyPool(t *testing.T) {
+	records := inmemory.NewShortenerRepository()
+	_, err := records.Add(context.Background(), "key-1", &domain.Record{UID: "alice", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+	repo := inmemory.NewKeyPoolRepository(records)
+	require.NoError(t, repo.PutKeys(context.Background(), []domain.ShortID{"stored1", "stored2", "stored1", "key-1"}))
+	source := &uniqueGenerator{}
+	pool := NewKeyPool(repo, source, zap.NewNop().Sugar(), 8, 4, 2)
+	require.NoError(t, pool.Run())
+	require.Eventually(t, func() bool { return len(pool.keys) == 6 }, time.Second, 10*time.Millisecond)
+	pooled := make([]domain.ShortID, 0, 6)
+	for len(pool.keys) > 0 {
+		pooled = append(pooled, <-pool.keys)
+	}
+	require.Equal(t, []domain.ShortID{"stored1", "stored2", "key-2", "key-3", "key-4", "key-5"}, pooled)
+	for _, key := range pooled {
+		pool.keys <- key
+	}
+
+	first, err := pool.Generate(context.Background(), 8)
+	require.NoError(t, err)
+	require.Equal(t, "stored1", first)
+	second, err := pool.Generate(context.Background(), 8)
+	require.NoError(t, err)
+	require.Equal(t, "stored2", second)
+
+	require.NoError(t, pool.Shutdown())
+	returned, err := repo.TakeKeys(context.Background(), 100)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(returned), 4)
+	require.NotContains(t, returned, domain.ShortID("stored1"))
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// ReaperService - сервис, который периодически удаляет записи с истекшим сроком действия.
+type ReaperService struct {
+	repo     ports.ExpiredRepository
+	logger   ports.Logger
+	interval time.Duration
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewReaper - новый сервис.
+func NewReaper(repo ports.ExpiredRepository, logger ports.Logger, interval time.Duration) *ReaperService {
+	return &ReaperService{
+		repo:     repo,
+		logger:   logger,
+		interval: interval,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+var _ ports.ReaperService = (*ReaperService)(nil)
+
+// Run - запуск сервиса.
+func (rs *ReaperService) Run() error {
+	go rs.purger()
+	return nil
+}
+
+// Shutdown - остановка сервиса, дожидаемся завершения текущей очистки.
+func (rs *ReaperService) Shutdown() error {
+	close(rs.done)
+	<-rs.stopped
+	return nil
+}
+
+func (rs *ReaperService) purger() {
+	defer close(rs.stopped)
+	ticker := time.NewTicker(rs.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rs.done:
+			return
+		case <-ticker.C:
+			rs.purge()
+		}
+	}
+}
+
+func (rs *ReaperService) purge() {
+	ctx, cancel := context.WithTimeout(context.Background(), rs.interval)
+	defer cancel()
+	count, err := rs.repo.PurgeExpired(ctx, time.Now())
+	if err != nil {
+		rs.logger.Errorln("purge expired records err: ", err)
+		return
+	}
+	if count > 0 {
+		rs.logger.Infoln("purged expired records", "count", count)
+	}
+}
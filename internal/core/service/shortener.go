@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"sync/atomic"
+	"time"
 
 	"github.com/Svirex/microurl/internal/core/domain"
 	"github.com/Svirex/microurl/internal/core/ports"
@@ -15,61 +18,226 @@ type ShortenerService struct {
 	shortIDGenerator ports.StringGenerator
 	repository       ports.ShortenerRepository
 	shortIDSize      uint
+	maxAttempts      int
+	normalizer       *URLNormalizer
+	domainPolicy     ports.DomainPolicy
+	created          atomic.Int64
+	conflicted       atomic.Int64
+}
+
+// ShortenerOption - дополнительный параметр сервиса.
+type ShortenerOption func(*ShortenerService)
+
+// WithURLNormalizer - правила проверки и нормализации URL, по умолчанию http и https, фрагмент сохраняется.
+func WithURLNormalizer(normalizer *URLNormalizer) ShortenerOption {
+	return func(s *ShortenerService) {
+		s.normalizer = normalizer
+	}
+}
+
+// WithDomainPolicy - правила доступа к доменам, проверяются при сокращении и при переходе по ссылке.
+func WithDomainPolicy(policy ports.DomainPolicy) ShortenerOption {
+	return func(s *ShortenerService) {
+		s.domainPolicy = policy
+	}
 }
 
 // NewShortenerService - создание сервиса.
+// maxAttempts - число попыток сгенерировать короткий идентификатор, если сгенерированный уже занят.
 func NewShortenerService(
 	shortIDGenerator ports.StringGenerator,
 	repository ports.ShortenerRepository,
 	shortIDSize uint,
+	maxAttempts int,
 	baseURL string,
+	opts ...ShortenerOption,
 ) *ShortenerService {
-	return &ShortenerService{
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	s := &ShortenerService{
 		shortIDGenerator: shortIDGenerator,
 		repository:       repository,
 		shortIDSize:      shortIDSize,
+		maxAttempts:      maxAttempts,
 		baseURL:          baseURL,
+		normalizer:       NewURLNormalizer(nil, domain.FragmentKeep),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 var _ ports.ShortenerService = (*ShortenerService)(nil)
 
 // Add - обработать добавление записи.
-func (s *ShortenerService) Add(ctx context.Context, record *domain.Record) (domain.ShortURL, error) {
-	shortID := domain.ShortID(s.shortIDGenerator.Generate(ctx, s.shortIDSize))
-	id, err := s.repository.Add(ctx, shortID, record)
+func (s *ShortenerService) Add(ctx context.Context, record *domain.Record) (shortURL domain.ShortURL, err error) {
+	ctx, span := startSpan(ctx, "ShortenerService.Add")
+	defer func() { endSpan(span, err) }()
+	shortURL, err = s.add(ctx, record)
+	switch {
+	case err == nil:
+		s.created.Add(1)
+	case errors.Is(err, ports.ErrAlreadyExists), errors.Is(err, ports.ErrAliasAlreadyExists):
+		s.conflicted.Add(1)
+	}
+	return shortURL, err
+}
+
+// ShortensCreated - число ссылок, созданных через Add и Batch.
+func (s *ShortenerService) ShortensCreated() int64 {
+	return s.created.Load()
+}
+
+// ShortensConflicted - число запросов Add и элементов Batch, для которых ссылка или алиас уже существовали.
+func (s *ShortenerService) ShortensConflicted() int64 {
+	return s.conflicted.Load()
+}
+
+func (s *ShortenerService) add(ctx context.Context, record *domain.Record) (domain.ShortURL, error) {
+	url, err := s.normalizer.Normalize(record.URL)
 	if err != nil {
+		return domain.ShortURL(""), fmt.Errorf("shortener service, add: %w", err)
+	}
+	if err := s.checkDomain(url); err != nil {
+		return domain.ShortURL(""), fmt.Errorf("shortener service, add: %w", err)
+	}
+	record.URL = url
+	expiresAt, err := resolveExpiry(record.ExpiresAt, record.TTL, time.Now())
+	if err != nil {
+		return domain.ShortURL(""), fmt.Errorf("shortener service, add, resolve expiry: %w", err)
+	}
+	record.ExpiresAt = expiresAt
+	if record.CustomAlias != "" {
+		if err := ValidateAlias(record.CustomAlias); err != nil {
+			return domain.ShortURL(""), fmt.Errorf("shortener service, add, validate alias: %w", err)
+		}
+		id, err := s.repository.Add(ctx, record.CustomAlias, record)
+		if err != nil {
+			if errors.Is(err, ports.ErrAlreadyExists) {
+				return s.shortURL(id), err
+			}
+			if errors.Is(err, ports.ErrShortIDExists) {
+				return domain.ShortURL(""), fmt.Errorf("shortener service, add: %w", ports.ErrAliasAlreadyExists)
+			}
+			return domain.ShortURL(""), fmt.Errorf("shortener service, add: %w", err)
+		}
+		return s.shortURL(id), nil
+	}
+	for attempt := 1; ; attempt++ {
+		shortID, err := s.generateShortID(ctx)
+		if err != nil {
+			return domain.ShortURL(""), fmt.Errorf("shortener service, add: %w", err)
+		}
+		id, err := s.repository.Add(ctx, shortID, record)
+		if err == nil {
+			return s.shortURL(id), nil
+		}
 		if errors.Is(err, ports.ErrAlreadyExists) {
 			return s.shortURL(id), err
 		}
-		return domain.ShortURL(""), fmt.Errorf("shortener service, add: %w", err)
+		if !errors.Is(err, ports.ErrShortIDExists) || attempt == s.maxAttempts {
+			return domain.ShortURL(""), fmt.Errorf("shortener service, add, attempt %d: %w", attempt, err)
+		}
 	}
-	return s.shortURL(id), nil
 }
 
 // Get - обработать получение записи.
-func (s *ShortenerService) Get(ctx context.Context, shortID domain.ShortID) (domain.URL, error) {
-	return s.repository.Get(ctx, shortID)
+func (s *ShortenerService) Get(ctx context.Context, shortID domain.ShortID) (url domain.URL, err error) {
+	ctx, span := startSpan(ctx, "ShortenerService.Get")
+	defer func() { endSpan(span, err) }()
+	url, err = s.repository.Get(ctx, shortID)
+	if err != nil {
+		return url, err
+	}
+	if err := s.checkDomain(url); err != nil {
+		return domain.URL(""), fmt.Errorf("shortener service, get: %w", err)
+	}
+	return url, nil
 }
 
 // Batch - обработать добавление нескольких записей.
-func (s *ShortenerService) Batch(ctx context.Context, uid domain.UID, data []domain.BatchRecord) ([]domain.BatchRecord, error) {
-	for i := range data {
-		data[i].ShortID = domain.ShortID(s.shortIDGenerator.Generate(ctx, s.shortIDSize))
+func (s *ShortenerService) Batch(ctx context.Context, uid domain.UID, data []domain.BatchRecord) (result []domain.BatchRecord, err error) {
+	ctx, span := startSpan(ctx, "ShortenerService.Batch")
+	defer func() { endSpan(span, err) }()
+	result, err = s.batch(ctx, uid, data)
+	if errors.Is(err, ports.ErrAliasAlreadyExists) {
+		s.conflicted.Add(1)
 	}
-	data, err := s.repository.Batch(ctx, uid, data)
-	if err != nil {
-		return nil, fmt.Errorf("shortener service, batch: %w", err)
+	for i := range result {
+		switch result[i].Status {
+		case domain.BatchStatusCreated:
+			s.created.Add(1)
+		case domain.BatchStatusExisting:
+			s.conflicted.Add(1)
+		}
 	}
+	return result, err
+}
+
+func (s *ShortenerService) batch(ctx context.Context, uid domain.UID, data []domain.BatchRecord) ([]domain.BatchRecord, error) {
+	now := time.Now()
+	aliases := make(map[domain.ShortID]struct{})
 	for i := range data {
-		data[i].ShortURL = domain.URL(s.shortURL(data[i].ShortID))
+		url, err := s.normalizer.Normalize(data[i].URL)
+		if err != nil {
+			return nil, fmt.Errorf("shortener service, batch, item %q: %w", data[i].CorrID, err)
+		}
+		if err := s.checkDomain(url); err != nil {
+			return nil, fmt.Errorf("shortener service, batch, item %q: %w", data[i].CorrID, err)
+		}
+		data[i].URL = url
+		expiresAt, err := resolveExpiry(data[i].ExpiresAt, data[i].TTL, now)
+		if err != nil {
+			return nil, fmt.Errorf("shortener service, batch, resolve expiry: %w", err)
+		}
+		data[i].ExpiresAt = expiresAt
+		alias := data[i].CustomAlias
+		if alias == "" {
+			shortID, err := s.generateShortID(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("shortener service, batch: %w", err)
+			}
+			data[i].ShortID = shortID
+			continue
+		}
+		if err := ValidateAlias(alias); err != nil {
+			return nil, fmt.Errorf("shortener service, batch, validate alias: %w", err)
+		}
+		if _, exist := aliases[alias]; exist {
+			return nil, fmt.Errorf("shortener service, batch, duplicate alias %q: %w", alias, ports.ErrAliasAlreadyExists)
+		}
+		aliases[alias] = struct{}{}
+		data[i].ShortID = alias
+	}
+	for attempt := 1; ; attempt++ {
+		result, err := s.repository.Batch(ctx, uid, data)
+		if err == nil {
+			for i := range result {
+				result[i].ShortURL = domain.URL(s.shortURL(result[i].ShortID))
+			}
+			return result, nil
+		}
+		var existsErr *ports.ShortIDExistsError
+		if !errors.As(err, &existsErr) || attempt == s.maxAttempts {
+			return nil, fmt.Errorf("shortener service, batch, attempt %d: %w", attempt, err)
+		}
+		if _, isAlias := aliases[existsErr.ShortID]; isAlias {
+			return nil, fmt.Errorf("shortener service, batch, alias %q: %w", existsErr.ShortID, ports.ErrAliasAlreadyExists)
+		}
+		err = s.regenerateShortID(ctx, data, existsErr.ShortID)
+		if err != nil {
+			return nil, fmt.Errorf("shortener service, batch: %w", err)
+		}
 	}
-	return data, nil
 }
 
 // UserURLs - получить все урлы пользователя.
-func (s *ShortenerService) UserURLs(ctx context.Context, uid domain.UID) ([]domain.URLData, error) {
-	data, err := s.repository.UserURLs(ctx, uid)
+func (s *ShortenerService) UserURLs(ctx context.Context, uid domain.UID) (data []domain.URLData, err error) {
+	ctx, span := startSpan(ctx, "ShortenerService.UserURLs")
+	defer func() { endSpan(span, err) }()
+	data, err = s.repository.UserURLs(ctx, uid)
 	if err != nil {
 		return nil, fmt.Errorf("shortener service, user url: %w", err)
 	}
@@ -79,11 +247,104 @@ func (s *ShortenerService) UserURLs(ctx context.Context, uid domain.UID) ([]doma
 	return data, nil
 }
 
+// Update - изменить ссылку пользователя: новый URL проверяется так же, как при сокращении.
+func (s *ShortenerService) Update(ctx context.Context, uid domain.UID, shortID domain.ShortID, update *domain.URLUpdate) (data *domain.URLData, err error) {
+	ctx, span := startSpan(ctx, "ShortenerService.Update")
+	defer func() { endSpan(span, err) }()
+	if update.URL != nil {
+		url, err := s.normalizer.Normalize(*update.URL)
+		if err != nil {
+			return nil, fmt.Errorf("shortener service, update: %w", err)
+		}
+		if err := s.checkDomain(url); err != nil {
+			return nil, fmt.Errorf("shortener service, update: %w", err)
+		}
+		update.URL = &url
+	}
+	update.UpdatedAt = time.Now().UTC()
+	if update.ClearExpiry && (update.ExpiresAt != nil || update.TTL != 0) {
+		return nil, fmt.Errorf("shortener service, update, clear and set expiry: %w", ports.ErrInvalidExpiry)
+	}
+	expiresAt, err := resolveExpiry(update.ExpiresAt, update.TTL, update.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("shortener service, update, resolve expiry: %w", err)
+	}
+	update.ExpiresAt = expiresAt
+	update.TTL = 0
+	data, err = s.repository.Update(ctx, uid, shortID, update)
+	if err != nil {
+		return nil, fmt.Errorf("shortener service, update: %w", err)
+	}
+	data.ShortURL = domain.URL(s.shortURL(data.ShortID))
+	return data, nil
+}
+
+// URLRevisions - история изменений ссылки пользователя.
+func (s *ShortenerService) URLRevisions(ctx context.Context, uid domain.UID, shortID domain.ShortID) (revisions []domain.URLRevision, err error) {
+	ctx, span := startSpan(ctx, "ShortenerService.URLRevisions")
+	defer func() { endSpan(span, err) }()
+	revisions, err = s.repository.URLRevisions(ctx, uid, shortID)
+	if err != nil {
+		return nil, fmt.Errorf("shortener service, url revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// Stats - получить статистику сервиса.
+func (s *ShortenerService) Stats(ctx context.Context) (stats *domain.Stats, err error) {
+	ctx, span := startSpan(ctx, "ShortenerService.Stats")
+	defer func() { endSpan(span, err) }()
+	stats, err = s.repository.Stats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("shortener service, stats: %w", err)
+	}
+	return stats, nil
+}
+
 // Shutdown - завершить работу сервиса.
 func (s *ShortenerService) Shutdown() error {
 	return nil
 }
 
+func (s *ShortenerService) generateShortID(ctx context.Context) (domain.ShortID, error) {
+	shortID, err := s.shortIDGenerator.Generate(ctx, s.shortIDSize)
+	if err != nil {
+		return domain.ShortID(""), fmt.Errorf("generate short id: %w", err)
+	}
+	return domain.ShortID(shortID), nil
+}
+
+// regenerateShortID - заменить занятый сгенерированный идентификатор у всех записей батча.
+func (s *ShortenerService) regenerateShortID(ctx context.Context, data []domain.BatchRecord, taken domain.ShortID) error {
+	for i := range data {
+		if data[i].CustomAlias != "" || data[i].ShortID != taken {
+			continue
+		}
+		shortID, err := s.generateShortID(ctx)
+		if err != nil {
+			return err
+		}
+		data[i].ShortID = shortID
+	}
+	return nil
+}
+
+// checkDomain - проверить хост ссылки по правилам доступа к доменам.
+// Если хост не удается определить, то ссылка не проходит проверку.
+func (s *ShortenerService) checkDomain(rawURL domain.URL) error {
+	if s.domainPolicy == nil {
+		return nil
+	}
+	u, err := url.Parse(string(rawURL))
+	if err != nil {
+		return &ports.URLValidationError{URL: string(rawURL), Reason: "cannot parse url to check domain"}
+	}
+	if u.Hostname() == "" {
+		return &ports.URLValidationError{URL: string(rawURL), Reason: "no host to check domain"}
+	}
+	return s.domainPolicy.Check(u.Hostname())
+}
+
 func (s *ShortenerService) shortURL(shortID domain.ShortID) domain.ShortURL {
 	return domain.ShortURL(fmt.Sprintf("%s/%s", s.baseURL, string(shortID)))
 }
@@ -7,13 +7,15 @@ import (
 	"github.com/Svirex/microurl/internal/adapters/generator"
 	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
 	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
 )
 
 func setupBenchmarkTest() (*ShortenerService, func()) {
 	repo := inmemory.NewShortenerRepository()
-	gen := generator.NewStringGenerator(255)
-	service := NewShortenerService(gen, repo, 8, "http://localhost:8090")
+	gen := generator.NewStringGenerator()
+	service := NewShortenerService(gen, repo, 8, 5, "http://localhost:8090")
 	// fmt.Println("[TEST] ", name)
 
 	// tear down later
@@ -36,3 +38,72 @@ func BenchmarkAddGood(b *testing.B) {
 		service.Add(context.Background(), data)
 	}
 }
+
+// sequenceGenerator - генератор, который возвращает заданные строки по очереди
+type sequenceGenerator struct {
+	values []string
+	next   int
+}
+
+func (g *sequenceGenerator) Generate(_ context.Context, _ uint) (string, error) {
+	value := g.values[g.next%len(g.values)]
+	g.next++
+	return value, nil
+}
+
+func TestAddRetriesOnCollision(t *testing.T) {
+	repo := inmemory.NewShortenerRepository()
+	_, err := repo.Add(context.Background(), "taken", &domain.Record{UID: "uid", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+
+	gen := &sequenceGenerator{values: []string{"taken", "taken", "free"}}
+	service := NewShortenerService(gen, repo, 8, 3, "http://localhost:8090")
+	shortURL, err := service.Add(context.Background(), &domain.Record{UID: "uid", URL: "http://ya.ru"})
+	require.NoError(t, err)
+	require.Equal(t, domain.ShortURL("http://localhost:8090/free"), shortURL)
+
+	gen = &sequenceGenerator{values: []string{"taken"}}
+	service = NewShortenerService(gen, repo, 8, 3, "http://localhost:8090")
+	_, err = service.Add(context.Background(), &domain.Record{UID: "uid", URL: "http://go.dev"})
+	require.ErrorIs(t, err, ports.ErrShortIDExists)
+	require.Equal(t, 3, gen.next)
+}
+
+func TestBatchRetriesOnCollision(t *testing.T) {
+	repo := inmemory.NewShortenerRepository()
+	_, err := repo.Add(context.Background(), "taken", &domain.Record{UID: "uid", URL: "http://svirex.ru"})
+	require.NoError(t, err)
+
+	gen := &sequenceGenerator{values: []string{"first", "taken", "second"}}
+	service := NewShortenerService(gen, repo, 8, 3, "http://localhost:8090")
+	result, err := service.Batch(context.Background(), "uid", []domain.BatchRecord{
+		{CorrID: "1", URL: "http://ya.ru"},
+		{CorrID: "2", URL: "http://go.dev"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://localhost:8090/first"), result[0].ShortURL)
+	require.Equal(t, domain.URL("http://localhost:8090/second"), result[1].ShortURL)
+}
+
+func TestBatchCountsItems(t *testing.T) {
+	repo := inmemory.NewShortenerRepository()
+	service := NewShortenerService(generator.NewStringGenerator(), repo, 8, 3, "http://localhost:8090")
+	_, err := service.Add(context.Background(), &domain.Record{UID: "uid", URL: "http://svirex.ru", CustomAlias: "promo"})
+	require.NoError(t, err)
+
+	_, err = service.Batch(context.Background(), "uid", []domain.BatchRecord{
+		{CorrID: "1", URL: "http://svirex.ru"},
+		{CorrID: "2", URL: "http://ya.ru"},
+		{CorrID: "3", URL: "http://go.dev"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), service.ShortensCreated())
+	require.Equal(t, int64(1), service.ShortensConflicted())
+
+	_, err = service.Batch(context.Background(), "uid", []domain.BatchRecord{
+		{CorrID: "1", URL: "http://example.com", CustomAlias: "promo"},
+	})
+	require.ErrorIs(t, err, ports.ErrAliasAlreadyExists)
+	require.Equal(t, int64(3), service.ShortensCreated())
+	require.Equal(t, int64(2), service.ShortensConflicted())
+}
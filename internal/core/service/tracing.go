@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName - имя трассировщика методов сервисов.
+const tracerName = "github.com/Svirex/microurl/internal/core/service"
+
+// startSpan - начать спан метода сервиса. Трассировщик берется при каждом вызове,
+// чтобы учитывать провайдера, установленного после инициализации пакета.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name)
+}
+
+// endSpan - завершить спан, отметив ошибку, если она есть.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+)
+
+// TrashService - сервис, который отменяет удаление ссылок в течение срока хранения
+// и периодически окончательно удаляет ссылки, удаленные раньше.
+type TrashService struct {
+	repo          ports.DeleterRepository
+	logger        ports.Logger
+	grace         time.Duration
+	interval      time.Duration
+	done          chan struct{}
+	stopped       chan struct{}
+	restored      atomic.Int64
+	purgedRecords atomic.Int64
+	purgedOwners  atomic.Int64
+}
+
+// NewTrashService - новый сервис, grace - срок, в течение которого удаление можно отменить,
+// interval - период очистки.
+func NewTrashService(repo ports.DeleterRepository, logger ports.Logger, grace, interval time.Duration) *TrashService {
+	return &TrashService{
+		repo:     repo,
+		logger:   logger,
+		grace:    grace,
+		interval: interval,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+var _ ports.TrashService = (*TrashService)(nil)
+
+// Restore - отменить удаление ссылок пользователем, если они удалены не раньше срока хранения.
+// Повторы идентификаторов отбрасываются.
+func (ts *TrashService) Restore(ctx context.Context, uid domain.UID, shortIDs []domain.ShortID) ([]domain.RestoreResult, error) {
+	unique := make([]domain.ShortID, 0, len(shortIDs))
+	seen := make(map[domain.ShortID]struct{}, len(shortIDs))
+	for _, shortID := range shortIDs {
+		if _, ok := seen[shortID]; ok {
+			continue
+		}
+		seen[shortID] = struct{}{}
+		unique = append(unique, shortID)
+	}
+	results, err := ts.repo.Restore(ctx, uid, unique, time.Now().Add(-ts.grace))
+	if err != nil {
+		return nil, fmt.Errorf("trash service, restore: %w", err)
+	}
+	for _, result := range results {
+		if result.Status == domain.RestoreStatusRestored {
+			ts.restored.Add(1)
+		}
+	}
+	return results, nil
+}
+
+// Restored - число восстановленных ссылок.
+func (ts *TrashService) Restored() int64 {
+	return ts.restored.Load()
+}
+
+// PurgedRecords - число окончательно удаленных записей.
+func (ts *TrashService) PurgedRecords() int64 {
+	return ts.purgedRecords.Load()
+}
+
+// PurgedOwners - число окончательно удаленных строк владельцев.
+func (ts *TrashService) PurgedOwners() int64 {
+	return ts.purgedOwners.Load()
+}
+
+// Run - запуск сервиса.
+func (ts *TrashService) Run() error {
+	go ts.purger()
+	return nil
+}
+
+// Shutdown - остановка сервиса, дожидаемся завершения текущей очистки.
+func (ts *TrashService) Shutdown() error {
+	close(ts.done)
+	<-ts.stopped
+	return nil
+}
+
+func (ts *TrashService) purger() {
+	defer close(ts.stopped)
+	ticker := time.NewTicker(ts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ts.done:
+			return
+		case <-ticker.C:
+			ts.purge()
+		}
+	}
+}
+
+func (ts *TrashService) purge() {
+	ctx, cancel := context.WithTimeout(context.Background(), ts.interval)
+	defer cancel()
+	result, err := ts.repo.PurgeDeleted(ctx, time.Now().Add(-ts.grace))
+	if err != nil {
+		ts.logger.Errorln("purge deleted records err: ", err)
+		return
+	}
+	ts.purgedRecords.Add(result.Records)
+	ts.purgedOwners.Add(result.Owners)
+	if result.Records > 0 || result.Owners > 0 {
+		ts.logger.Infoln("purged deleted records", "records", result.Records, "owners", result.Owners)
+	}
+}
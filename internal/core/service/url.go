@@ -0,0 +1,99 @@
+package service
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"golang.org/x/net/idna"
+)
+
+// defaultPorts - порты, которые не указываются в нормализованном URL.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// URLNormalizer - проверка и приведение URL к каноническому виду перед сокращением.
+type URLNormalizer struct {
+	schemes  map[string]struct{}
+	fragment domain.URLFragmentPolicy
+}
+
+// NewURLNormalizer - новый нормализатор. Пустой schemes означает domain.DefaultURLSchemes,
+// неизвестная политика фрагмента - domain.FragmentKeep.
+func NewURLNormalizer(schemes []string, fragment domain.URLFragmentPolicy) *URLNormalizer {
+	if len(schemes) == 0 {
+		schemes = domain.DefaultURLSchemes
+	}
+	n := &URLNormalizer{
+		schemes:  make(map[string]struct{}, len(schemes)),
+		fragment: fragment,
+	}
+	for _, scheme := range schemes {
+		n.schemes[strings.ToLower(scheme)] = struct{}{}
+	}
+	if fragment != domain.FragmentStrip && fragment != domain.FragmentReject {
+		n.fragment = domain.FragmentKeep
+	}
+	return n
+}
+
+// Normalize - проверить URL и вернуть нормализованный: схема и хост в нижнем регистре,
+// IDN в punycode, без порта по умолчанию, фрагмент по политике.
+// Если URL некорректен, то вернуть *ports.URLValidationError.
+func (n *URLNormalizer) Normalize(raw domain.URL) (domain.URL, error) {
+	source := strings.TrimSpace(string(raw))
+	invalid := func(reason string) (domain.URL, error) {
+		return domain.URL(""), &ports.URLValidationError{URL: source, Reason: reason}
+	}
+	if source == "" {
+		return invalid("empty url")
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return invalid("malformed url")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "" {
+		return invalid("scheme required")
+	}
+	if _, ok := n.schemes[u.Scheme]; !ok {
+		return invalid("scheme " + u.Scheme + " is not allowed")
+	}
+	if u.Opaque != "" || u.Hostname() == "" {
+		return invalid("host required")
+	}
+	host := strings.TrimSuffix(u.Hostname(), ".")
+	if net.ParseIP(host) == nil {
+		host, err = idna.Lookup.ToASCII(host)
+		if err != nil {
+			return invalid("invalid host")
+		}
+	}
+	host = strings.ToLower(host)
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+	if u.Fragment != "" || u.RawFragment != "" {
+		switch n.fragment {
+		case domain.FragmentReject:
+			return invalid("fragment is not allowed")
+		case domain.FragmentStrip:
+			u.Fragment = ""
+			u.RawFragment = ""
+		}
+	}
+	return domain.URL(u.String()), nil
+}
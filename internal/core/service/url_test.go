@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Svirex/microurl/internal/adapters/generator"
+	"github.com/Svirex/microurl/internal/adapters/repository/inmemory"
+	"github.com/Svirex/microurl/internal/core/domain"
+	"github.com/Svirex/microurl/internal/core/ports"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLNormalizer(t *testing.T) {
+	tests := []struct {
+		url      domain.URL
+		fragment domain.URLFragmentPolicy
+		want     domain.URL
+	}{
+		{url: "HTTP://Svirex.RU/Path?q=1", want: "http://svirex.ru/Path?q=1"},
+		{url: "  https://ya.ru:443/  ", want: "https://ya.ru/"},
+		{url: "http://ya.ru:80", want: "http://ya.ru"},
+		{url: "http://ya.ru:8080/a", want: "http://ya.ru:8080/a"},
+		{url: "https://ya.ru:80/", want: "https://ya.ru:80/"},
+		{url: "http://пример.рф/путь", want: "http://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C"},
+		{url: "http://[::1]:80/", want: "http://[::1]/"},
+		{url: "http://ya.ru/#top", want: "http://ya.ru/#top"},
+		{url: "http://ya.ru/#top", fragment: domain.FragmentStrip, want: "http://ya.ru/"},
+		{url: "http://ya.ru/#top", fragment: domain.FragmentReject},
+		{url: "javascript:alert(1)"},
+		{url: "ftp://ya.ru/file"},
+		{url: "svirex.ru"},
+		{url: "http://"},
+		{url: "http:///path"},
+		{url: "not a url"},
+		{url: ""},
+	}
+	for _, test := range tests {
+		normalizer := NewURLNormalizer(nil, test.fragment)
+		got, err := normalizer.Normalize(test.url)
+		if test.want == "" {
+			var validationErr *ports.URLValidationError
+			require.ErrorAs(t, err, &validationErr, test.url)
+			require.ErrorIs(t, err, ports.ErrInvalidURL, test.url)
+			continue
+		}
+		require.NoError(t, err, test.url)
+		require.Equal(t, test.want, got, test.url)
+	}
+}
+
+func TestAddDedupesNormalizedURL(t *testing.T) {
+	service := NewShortenerService(generator.NewStringGenerator(), inmemory.NewShortenerRepository(), 8, 5, "http://localhost:8090")
+	first, err := service.Add(context.Background(), &domain.Record{UID: "uid", URL: "http://svirex.ru/path"})
+	require.NoError(t, err)
+	second, err := service.Add(context.Background(), &domain.Record{UID: "uid", URL: "HTTP://SVIREX.RU:80/path"})
+	require.ErrorIs(t, err, ports.ErrAlreadyExists)
+	require.Equal(t, first, second)
+
+	_, err = service.Batch(context.Background(), "uid", []domain.BatchRecord{{CorrID: "1", URL: "javascript:alert(1)"}})
+	require.ErrorIs(t, err, ports.ErrInvalidURL)
+}
+
+// hostPolicy - запрещает хосты из списка.
+type hostPolicy map[string]bool
+
+func (p hostPolicy) Check(host string) error {
+	if p[host] {
+		return ports.ErrForbiddenDomain
+	}
+	return nil
+}
+
+func TestDomainPolicy(t *testing.T) {
+	repo := inmemory.NewShortenerRepository()
+	_, err := repo.Add(context.Background(), "stored", &domain.Record{UID: "uid", URL: "http://evil.com/login"})
+	require.NoError(t, err)
+	policy := hostPolicy{"evil.com": true}
+	service := NewShortenerService(generator.NewStringGenerator(), repo, 8, 5, "http://localhost:8090", WithDomainPolicy(policy))
+
+	_, err = service.Add(context.Background(), &domain.Record{UID: "uid", URL: "http://EVIL.com/other"})
+	require.ErrorIs(t, err, ports.ErrForbiddenDomain)
+	_, err = service.Batch(context.Background(), "uid", []domain.BatchRecord{
+		{CorrID: "1", URL: "http://svirex.ru"},
+		{CorrID: "2", URL: "http://evil.com"},
+	})
+	require.ErrorIs(t, err, ports.ErrForbiddenDomain)
+
+	_, err = service.Get(context.Background(), "stored")
+	require.ErrorIs(t, err, ports.ErrForbiddenDomain)
+	delete(policy, "evil.com")
+	url, err := service.Get(context.Background(), "stored")
+	require.NoError(t, err)
+	require.Equal(t, domain.URL("http://evil.com/login"), url)
+
+	_, err = repo.Add(context.Background(), "broken", &domain.Record{UID: "uid", URL: "http://%zz"})
+	require.NoError(t, err)
+	_, err = service.Get(context.Background(), "broken")
+	require.ErrorIs(t, err, ports.ErrInvalidURL)
+	_, err = repo.Add(context.Background(), "hostless", &domain.Record{UID: "uid", URL: "mailto:evil@evil.com"})
+	require.NoError(t, err)
+	_, err = service.Get(context.Background(), "hostless")
+	require.ErrorIs(t, err, ports.ErrInvalidURL)
+}
@@ -0,0 +1,2 @@
+ALTER TABLE public.records
+DROP CONSTRAINT records_short_id_key;
\ No newline at end of file
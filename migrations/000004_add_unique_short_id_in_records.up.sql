@@ -0,0 +1,2 @@
+ALTER TABLE public.records
+ADD CONSTRAINT records_short_id_key UNIQUE (short_id);
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS records_expires_at_idx;
+
+ALTER TABLE public.records
+DROP COLUMN expires_at;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE public.records
+ADD expires_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS records_expires_at_idx
+ON public.records (expires_at)
+WHERE expires_at IS NOT NULL;
\ No newline at end of file
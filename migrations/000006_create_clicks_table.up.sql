@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS
+public.clicks (
+    id BIGSERIAL PRIMARY KEY,
+    short_id VARCHAR(32) NOT NULL,
+    clicked_at TIMESTAMPTZ NOT NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS clicks_short_id_clicked_at_idx
+ON public.clicks (short_id, clicked_at);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP SEQUENCE IF EXISTS short_id_seq;
\ No newline at end of file
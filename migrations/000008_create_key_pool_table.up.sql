@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS
+public.key_pool (
+    short_id VARCHAR(32) PRIMARY KEY
+);
\ No newline at end of file
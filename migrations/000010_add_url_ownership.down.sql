@@ -0,0 +1,10 @@
+ALTER TABLE public.users
+DROP CONSTRAINT IF EXISTS users_uid_record_id_key;
+
+ALTER TABLE public.users
+DROP COLUMN IF EXISTS is_deleted;
+
+DROP INDEX IF EXISTS records_url_idx;
+
+ALTER TABLE public.records
+ADD CONSTRAINT records_url_key UNIQUE (url);
\ No newline at end of file
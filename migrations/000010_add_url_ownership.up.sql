@@ -0,0 +1,19 @@
+ALTER TABLE public.records
+DROP CONSTRAINT IF EXISTS records_url_key;
+
+CREATE INDEX IF NOT EXISTS records_url_idx
+ON public.records (url);
+
+ALTER TABLE public.users
+ADD is_deleted BOOLEAN NOT NULL DEFAULT false;
+
+DELETE FROM public.users a
+USING public.users b
+WHERE a.id > b.id AND a.uid = b.uid AND a.record_id = b.record_id;
+
+ALTER TABLE public.users
+ADD CONSTRAINT users_uid_record_id_key UNIQUE (uid, record_id);
+
+UPDATE public.users SET is_deleted = true
+FROM public.records
+WHERE users.record_id = records.id AND records.is_deleted;
\ No newline at end of file
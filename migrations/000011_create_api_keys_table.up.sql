@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS
+public.api_keys (
+    id VARCHAR(32) PRIMARY KEY,
+    uid UUID NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    hash TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_uid_idx ON public.api_keys (uid);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS
+public.url_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    record_id INTEGER NOT NULL REFERENCES records(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NULL,
+    changed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS url_revisions_record_id_idx
+ON public.url_revisions (record_id, id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS records_deleted_at_idx;
+DROP INDEX IF EXISTS users_deleted_at_idx;
+
+ALTER TABLE public.records
+DROP COLUMN deleted_at;
+
+ALTER TABLE public.users
+DROP COLUMN deleted_at;
\ No newline at end of file
@@ -0,0 +1,16 @@
+ALTER TABLE public.users
+ADD deleted_at TIMESTAMPTZ NULL;
+
+ALTER TABLE public.records
+ADD deleted_at TIMESTAMPTZ NULL;
+
+UPDATE public.users SET deleted_at = now() WHERE is_deleted;
+UPDATE public.records SET deleted_at = now() WHERE is_deleted;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx
+ON public.users (deleted_at)
+WHERE is_deleted;
+
+CREATE INDEX IF NOT EXISTS records_deleted_at_idx
+ON public.records (deleted_at)
+WHERE is_deleted;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS deletion_dead_letters;
\ No newline at end of file
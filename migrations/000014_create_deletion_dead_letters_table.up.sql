@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS
+public.deletion_dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    job_id TEXT NOT NULL,
+    uid TEXT NOT NULL,
+    short_id TEXT NOT NULL,
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    failed_at TIMESTAMPTZ NOT NULL
+);
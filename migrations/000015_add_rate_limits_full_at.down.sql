@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS rate_limits_full_at_idx;
+
+ALTER TABLE public.rate_limits
+DROP COLUMN full_at;
\ No newline at end of file
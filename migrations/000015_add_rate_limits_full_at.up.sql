@@ -0,0 +1,5 @@
+ALTER TABLE public.rate_limits
+ADD full_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS rate_limits_full_at_idx
+ON public.rate_limits (full_at);
@@ -0,0 +1,2 @@
+ALTER TABLE public.records
+DROP COLUMN creator_uid;
\ No newline at end of file
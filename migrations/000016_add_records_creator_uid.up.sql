@@ -0,0 +1,9 @@
+ALTER TABLE public.records
+ADD creator_uid UUID NULL;
+
+UPDATE public.records SET creator_uid = (
+    SELECT users.uid FROM public.users
+    WHERE users.record_id = records.id
+    ORDER BY users.id
+    LIMIT 1
+);
@@ -130,7 +130,7 @@ func MigrateDown() {
 
 // Truncate - очистить таблицы в БД.
 func Truncate() error {
-	_, err := dbpool.Exec(context.Background(), "TRUNCATE TABLE users, records RESTART IDENTITY;")
+	_, err := dbpool.Exec(context.Background(), "TRUNCATE TABLE clicks, users, records RESTART IDENTITY;")
 	if err != nil {
 		logger.Error("couldn't truncate tables ", err)
 		return err